@@ -0,0 +1,281 @@
+// Package app assembles configuration, logger, database, repositories and services of one environment.
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/idj1997/book-rent-core/cache"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/logging"
+	"github.com/idj1997/book-rent-core/metrics"
+	"github.com/idj1997/book-rent-core/notify"
+	"github.com/idj1997/book-rent-core/outbox"
+	"github.com/idj1997/book-rent-core/repository"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// App holds open database together with repositories and services using it,
+// Close releases database connections
+type App struct {
+	Config *config.Config
+	DB     *gorm.DB
+	// Repos are repositories outside transaction, books and users are cached when cache size is set
+	Repos domain.Repositories
+	Tx    domain.Transactor
+
+	Books     *service.BookService
+	Users     *service.UserService
+	Rents     *service.RentDetailsService
+	Catalog   *service.CatalogService
+	Inventory *service.InventoryService
+	Branches  *service.BranchService
+	Reports   *service.ReportService
+	Audit     *service.AuditService
+	Reminders *service.ReminderService
+	Outbox    *outbox.Dispatcher
+	Health    *config.HealthChecker
+	// Metrics holds service, query and stats metrics, New serves them on configured address
+	Metrics *prometheus.Registry
+
+	notifier      domain.Notifier
+	caches        *cache.Tenants
+	logs          io.Closer
+	metricsServer *http.Server
+	tracer        *sdktrace.TracerProvider
+	policies      *runner
+	reminders     *runner
+	dispatcher    *runner
+}
+
+// New loads config of env from path, configures logger and tracer, opens database and builds
+// services, nothing is left open when it fails
+func New(env string, path string) (*App, error) {
+	cfg, err := config.Load(env, path)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := logging.Configure(log.StandardLogger(), cfg.Logging)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := config.OpenPostgresDB(cfg.Database)
+	if err != nil {
+		_ = logs.Close()
+		return nil, err
+	}
+
+	app, err := Build(cfg, db)
+	if err == nil {
+		app.logs = logs
+		err = app.serveMetrics()
+	}
+	if err != nil {
+		_ = config.ClosePostgresDB(db)
+		_ = logs.Close()
+		return nil, err
+	}
+	app.tracer, err = tracing.NewTracerProvider(cfg.Tracing)
+	if err != nil {
+		_ = app.Close()
+		return nil, err
+	}
+	if app.tracer != nil {
+		otel.SetTracerProvider(app.tracer)
+	}
+	return app, nil
+}
+
+// Build assembles repositories and services of cfg on already opened database
+func Build(cfg *config.Config, db *gorm.DB) (*App, error) {
+	notifier, err := notify.NewNotifier(cfg.Reminders)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating notifier: %w", err)
+	}
+
+	// one cache of every tenant outlives requests, so its entries and stats are shared by them
+	var caches *cache.Tenants
+	if cfg.Cache.Size > 0 {
+		caches = cache.NewTenants(cache.NewBackend(cfg.Cache))
+	}
+	registry, err := instrument(cfg, db, caches)
+	if err != nil {
+		return nil, err
+	}
+
+	app := &App{
+		Config:   cfg,
+		DB:       db,
+		Health:   config.NewHealthChecker(db),
+		Metrics:  registry,
+		notifier: notifier,
+		caches:   caches}
+	app.assemble(nil)
+	// dispatcher delivers events of every tenant
+	app.Outbox = outbox.NewDispatcher(repository.NewGormOutboxRepository(repository.AllTenants(db)), cfg.Outbox)
+	return app, nil
+}
+
+// WithContext returns copy of app whose repositories and services trace calls under span of ctx
+// and log with fields of ctx, so one request is traced as single tree. Only original app is closed.
+func (a *App) WithContext(ctx context.Context) *App {
+	contextApp := *a
+	contextApp.assemble(ctx)
+	return &contextApp
+}
+
+// assemble builds repositories and services, bound to ctx unless it is nil
+func (a *App) assemble(ctx context.Context) {
+	db := a.DB
+	if ctx != nil {
+		db = db.WithContext(ctx)
+	}
+
+	repos := domain.Repositories{
+		Books:     repository.NewGormBookRepository(db),
+		Users:     repository.NewGormUserRepository(db),
+		Rents:     &repository.GormRentDetailsRepository{Db: db},
+		Audit:     repository.NewGormAuditRepository(db),
+		Outbox:    repository.NewGormOutboxRepository(db),
+		Movements: repository.NewGormStockMovementRepository(db),
+		Branches:  repository.NewGormBranchRepository(db),
+		Transfers: repository.NewGormTransferRepository(db)}
+	var tx domain.Transactor = repository.NewGormTransactor(db)
+	var reports domain.ReportRepository = repository.NewGormReportRepository(db)
+	var reminders domain.ReminderRepository = repository.NewGormReminderRepository(db)
+
+	if a.caches != nil {
+		tenantCache := a.caches.ForContext(ctx)
+		repos.Books = cache.NewBookRepository(repos.Books, tenantCache)
+		repos.Users = cache.NewUserRepository(repos.Users, tenantCache)
+		tx = cache.NewTransactor(tx, tenantCache)
+	}
+
+	var logger *log.Entry
+	if ctx != nil {
+		repos = tracing.WrapRepositories(ctx, repos)
+		tx = tracing.NewTransactor(ctx, tx)
+		reports = tracing.NewReportRepository(ctx, reports)
+		reminders = tracing.NewReminderRepository(ctx, reminders)
+		logger = logging.FromContext(ctx)
+	}
+
+	a.Repos = repos
+	a.Tx = tx
+	a.Books = service.NewBookService(repos.Books, tx).WithContext(ctx).WithLogger(logger)
+	a.Users = &service.UserService{Repo: repos.Users, Tx: tx, Log: logger, Ctx: ctx}
+	a.Rents = &service.RentDetailsService{
+		RentRepo: repos.Rents,
+		BookRepo: repos.Books,
+		Tx:       tx,
+		Log:      logger,
+		Ctx:      ctx}
+	a.Catalog = service.NewCatalogService(repos.Books, tx).WithContext(ctx)
+	a.Inventory = service.NewInventoryService(repos.Movements, tx).WithContext(ctx)
+	a.Branches = service.NewBranchService(repos.Branches, repos.Transfers, tx).WithContext(ctx)
+	a.Reports = &service.ReportService{Repo: reports, Ctx: ctx}
+	a.Audit = &service.AuditService{Repo: repos.Audit, Ctx: ctx}
+	a.Reminders = &service.ReminderService{
+		RentRepo:     repos.Rents,
+		ReminderRepo: reminders,
+		Notifier:     a.notifier,
+		DaysAhead:    a.Config.Reminders.DaysAhead,
+		Log:          logger,
+		Ctx:          ctx}
+}
+
+// instrument times and traces queries of db, times calls of all services and collects stats of db
+// and caches on scrape
+func instrument(cfg *config.Config, db *gorm.DB, caches *cache.Tenants) (*prometheus.Registry, error) {
+	registry := metrics.NewRegistry()
+	if plugin, ok := db.Config.Plugins[metrics.QueryMetricsPluginName]; ok {
+		err := registry.Register(plugin.(*metrics.QueryMetrics).Durations)
+		if err != nil {
+			return nil, fmt.Errorf("error while registering query metrics: %w", err)
+		}
+	} else {
+		queries, err := metrics.NewQueryMetrics(registry)
+		if err == nil {
+			err = db.Use(queries)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error while registering query metrics: %w", err)
+		}
+	}
+
+	if _, ok := db.Config.Plugins[tracing.SQLPluginName]; !ok {
+		err := db.Use(tracing.SQLEvents{})
+		if err != nil {
+			return nil, fmt.Errorf("error while registering sql tracing: %w", err)
+		}
+	}
+
+	services, err := metrics.NewServiceMetrics(registry)
+	if err != nil {
+		return nil, fmt.Errorf("error while registering service metrics: %w", err)
+	}
+	service.SetObserver(services)
+	err = registry.Register(&metrics.StatsCollector{
+		Repo:              repository.NewGormStatsRepository(db),
+		LowStockThreshold: cfg.Metrics.LowStockThreshold,
+		Caches:            caches})
+	if err != nil {
+		return nil, fmt.Errorf("error while registering stats metrics: %w", err)
+	}
+	return registry, nil
+}
+
+// serveMetrics starts metrics endpoint with liveness and readiness probes when address is configured
+func (a *App) serveMetrics() error {
+	server := metrics.NewServer(a.Config.Metrics, a.Metrics, a.Health)
+	if server == nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("error while listening for metrics: %w", err)
+	}
+
+	a.metricsServer = server
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Errorf("error while serving metrics: %v", err)
+		}
+	}()
+	log.Printf("Serving metrics on %s%s and probes on %s and %s",
+		listener.Addr(), metrics.Path, metrics.LivenessPath, metrics.ReadinessPath)
+	return nil
+}
+
+// Close stops policies, outbox dispatcher and metrics endpoint, flushes spans and closes database and log file opened by New
+func (a *App) Close() error {
+	a.stopBackground()
+	if a.metricsServer != nil {
+		_ = a.metricsServer.Close()
+	}
+	if a.tracer != nil {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		_ = a.tracer.Shutdown(context.Background())
+	}
+	err := config.ClosePostgresDB(a.DB)
+	if a.logs != nil {
+		closeErr := a.logs.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
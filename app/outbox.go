@@ -0,0 +1,18 @@
+package app
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// StartOutbox dispatches pending outbox events every outbox.interval until Close, nothing is
+// started when dispatcher already runs
+func (a *App) StartOutbox() {
+	if a.dispatcher != nil {
+		return
+	}
+	interval := a.Config.Outbox.Interval
+	a.dispatcher = startRunner(func(stop <-chan struct{}) {
+		a.Outbox.Run(interval, stop)
+	})
+	log.Printf("Dispatching outbox events every %s", interval)
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+// StartPolicies applies policies every policies.interval until Close, nothing is started
+// when interval is zero or policies already run
+func (a *App) StartPolicies() {
+	interval := a.Config.Policies.Interval
+	if interval <= 0 || a.policies != nil {
+		return
+	}
+
+	a.policies = startTicker(interval, func() {
+		_ = a.ApplyPolicies()
+	})
+	log.Printf("Applying policies every %s", interval)
+}
+
+// ApplyPolicies expires rents past deadline, declares lost expired rents overdue longer than
+// policies.lostAfter and purges books and users deleted longer than policies.purgeRetention ago of
+// every tenant, zero duration disables policy. Failure of one tenant is logged and does not stop others, first error is returned.
+func (a *App) ApplyPolicies() error {
+	return a.forEachTenant("applying policies", a.applyTenantPolicies)
+}
+
+// forEachTenant calls fn with App bound to every tenant, failure of one tenant is logged and
+// does not stop others, first error is returned
+func (a *App) forEachTenant(job string, fn func(tenant string, tenantApp *App) error) error {
+	tenants, err := repository.NewGormStatsRepository(a.DB).Tenants()
+	if err != domain.NilRepoErrPtr {
+		log.Errorf("error while listing tenants for %s: %v", job, err)
+		return err
+	}
+
+	var firstErr error
+	for _, tenant := range tenants {
+		err := fn(tenant, a.WithContext(domain.WithTenant(context.Background(), tenant)))
+		if err != nil {
+			log.WithField("tenant", tenant).Errorf("error while %s: %v", job, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (a *App) applyTenantPolicies(tenant string, tenantApp *App) error {
+	// rents are expired first, so rents overdue longer than lostAfter are declared lost in the same pass
+	err := tenantApp.Rents.UpdateToExpired()
+	if err != nil {
+		return err
+	}
+	if lostAfter := a.Config.Policies.LostAfter; lostAfter > 0 {
+		err = tenantApp.Rents.UpdateToLost(lostAfter)
+		if err != nil {
+			return err
+		}
+	}
+
+	retention := a.Config.Policies.PurgeRetention
+	if retention <= 0 {
+		return nil
+	}
+	books, err := tenantApp.Books.PurgeDeleted(retention)
+	if err != nil {
+		return err
+	}
+	users, err := tenantApp.Users.PurgeDeleted(retention)
+	if err != nil {
+		return err
+	}
+	if books > 0 || users > 0 {
+		log.WithField("tenant", tenant).Infof("purged %d books and %d users", books, users)
+	}
+	return nil
+}
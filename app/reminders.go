@@ -0,0 +1,31 @@
+package app
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// StartReminders sends reminders every reminders.interval until Close, nothing is started
+// when interval is zero or reminders already run
+func (a *App) StartReminders() {
+	interval := a.Config.Reminders.Interval
+	if interval <= 0 || a.reminders != nil {
+		return
+	}
+
+	a.reminders = startTicker(interval, func() {
+		_ = a.SendReminders()
+	})
+	log.Printf("Sending reminders every %s", interval)
+}
+
+// SendReminders sends due reminders and overdue notices of every tenant. Failure of one tenant
+// is logged and does not stop others, first error is returned.
+func (a *App) SendReminders() error {
+	return a.forEachTenant("sending reminders", func(tenant string, tenantApp *App) error {
+		sent, err := tenantApp.Reminders.SendReminders()
+		if sent > 0 {
+			log.WithField("tenant", tenant).Infof("sent %d reminders", sent)
+		}
+		return err
+	})
+}
@@ -0,0 +1,53 @@
+package app
+
+import "time"
+
+// stopBackground stops policies, reminders and outbox dispatcher and waits for their running pass to finish
+func (a *App) stopBackground() {
+	a.policies.close()
+	a.reminders.close()
+	a.dispatcher.close()
+	a.policies, a.reminders, a.dispatcher = nil, nil, nil
+}
+
+// runner runs function in background until stopped
+type runner struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startRunner runs fn in background, fn returns once stop is closed
+func startRunner(fn func(stop <-chan struct{})) *runner {
+	r := &runner{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		fn(r.stop)
+	}()
+	return r
+}
+
+// startTicker runs fn in background right away and then every interval until runner is closed
+func startTicker(interval time.Duration, fn func()) *runner {
+	return startRunner(func(stop <-chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			fn()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// close stops runner and waits for fn to return, nil runner is not running
+func (r *runner) close() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
@@ -0,0 +1,54 @@
+// Package auth authenticates callers of gRPC server, GraphQL endpoint and JSON API by bearer
+// token of one of configured actors, which binds actor and its tenant to call context.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+// Header carries "Bearer <token>" of calling actor
+const Header = "Authorization"
+
+// ActorField is log field of authenticated actor
+const ActorField = "actor"
+
+// TenantField is log field of tenant of authenticated actor
+const TenantField = "tenant"
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+type actorKey struct{}
+
+// ActorFromContext returns actor authenticated by Authenticate, empty when there is none
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Authenticate returns ctx carrying actor whose token is presented in authorization header value
+// and scoped to its tenant
+func Authenticate(ctx context.Context, authorization string, actors map[string]config.ActorConfig) (context.Context, error) {
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return nil, ErrMissingToken
+	}
+	token := []byte(strings.TrimPrefix(authorization, "Bearer "))
+
+	for actor, cfg := range actors {
+		if cfg.Token != "" && subtle.ConstantTimeCompare(token, []byte(cfg.Token)) == 1 {
+			ctx = domain.WithTenant(context.WithValue(ctx, actorKey{}, actor), cfg.Tenant)
+			return logging.WithFields(ctx, log.Fields{ActorField: actor, TenantField: domain.TenantFromContext(ctx)}), nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// Backend stores cached values by key, implementations must be safe for concurrent use.
+// Values are stored as given, shared backends are expected to copy or serialize them.
+type Backend interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+	Delete(key string)
+}
+
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// LRU is local backend holding at most capacity values, each for ttl after it was set,
+// least recently used value is evicted first when full
+type LRU struct {
+	capacity int
+	ttl      time.Duration
+	// Now returns current time, defaults to time.Now
+	Now func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// NewLRU creates LRU backend, ttl of 0 keeps values until evicted
+func NewLRU(capacity int, ttl time.Duration) *LRU {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRU{
+		capacity: capacity,
+		ttl:      ttl,
+		Now:      time.Now,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element)}
+}
+
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if c.ttl > 0 && !c.Now().Before(e.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+func (c *LRU) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry)
+		e.value, e.expiresAt = value, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	if c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len returns number of stored values, expired ones included until they are evicted
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+}
+
+type prefixed struct {
+	backend Backend
+	prefix  string
+}
+
+// Prefixed returns view of backend storing keys under prefix, repositories of different
+// tenants sharing one backend must each use their own prefix
+func Prefixed(backend Backend, prefix string) Backend {
+	return &prefixed{backend: backend, prefix: prefix + ":"}
+}
+
+// ForTenant returns Prefixed view of backend for tenant of ctx, so repositories of ctx only
+// see values cached for that tenant
+func ForTenant(ctx context.Context, backend Backend) Backend {
+	return Prefixed(backend, "tenant:"+domain.TenantFromContext(ctx))
+}
+
+func (p *prefixed) Get(key string) (interface{}, bool) {
+	return p.backend.Get(p.prefix + key)
+}
+
+func (p *prefixed) Set(key string, value interface{}) {
+	p.backend.Set(p.prefix+key, value)
+}
+
+func (p *prefixed) Delete(key string) {
+	p.backend.Delete(p.prefix + key)
+}
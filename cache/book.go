@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// BookRepository caches books found by ID, other reads go to inner repository.
+// Writes through it evict changed book, writes in transactions are evicted by Transactor.
+type BookRepository struct {
+	domain.BookRepository
+	cache *Cache
+}
+
+func NewBookRepository(inner domain.BookRepository, cache *Cache) *BookRepository {
+	return &BookRepository{BookRepository: inner, cache: cache}
+}
+
+// WithContext returns repository whose inner repository runs under span of ctx
+func (r *BookRepository) WithContext(ctx context.Context) domain.BookRepository {
+	return NewBookRepository(domain.BookRepositoryWithContext(ctx, r.BookRepository), r.cache)
+}
+
+// Stats returns hits and misses of GetByID of all repositories sharing cache
+func (r *BookRepository) Stats() Stats {
+	return r.cache.BookStats()
+}
+
+// GetByID returns copy of cached book, so callers may modify it
+func (r *BookRepository) GetByID(id int) (*domain.Book, error) {
+	key := bookKey(id)
+	if value, ok := r.cache.get(key); ok {
+		r.cache.books.hit()
+		book := value.(domain.Book)
+		return &book, domain.NilRepoErrPtr
+	}
+
+	r.cache.books.miss()
+	generation := r.cache.loading()
+	book, err := r.BookRepository.GetByID(id)
+	if err == domain.NilRepoErrPtr {
+		r.cache.set(generation, key, *book)
+	}
+	return book, err
+}
+
+func (r *BookRepository) Update(book *domain.Book, updates map[string]interface{}) error {
+	defer r.cache.delete(bookKey(int(book.ID)))
+	return r.BookRepository.Update(book, updates)
+}
+
+func (r *BookRepository) Delete(id int) error {
+	defer r.cache.delete(bookKey(id))
+	return r.BookRepository.Delete(id)
+}
+
+func (r *BookRepository) Restore(id int) error {
+	defer r.cache.delete(bookKey(id))
+	return r.BookRepository.Restore(id)
+}
+
+func bookKey(id int) string {
+	return "book:" + strconv.Itoa(id)
+}
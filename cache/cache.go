@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// Cache is cache of one tenant shared by repositories of all its requests. Every eviction bumps
+// its generation, so value loaded while concurrent write evicted it is dropped instead of stored.
+type Cache struct {
+	backend Backend
+	books   counter
+	users   counter
+
+	mu         sync.Mutex
+	generation uint64
+}
+
+func NewCache(backend Backend) *Cache {
+	return &Cache{backend: backend}
+}
+
+// BookStats returns hits and misses of cached books
+func (c *Cache) BookStats() Stats {
+	return c.books.stats()
+}
+
+// UserStats returns hits and misses of cached users
+func (c *Cache) UserStats() Stats {
+	return c.users.stats()
+}
+
+func (c *Cache) get(key string) (interface{}, bool) {
+	return c.backend.Get(key)
+}
+
+// loading returns generation of value about to be loaded, it is passed to set once loaded
+func (c *Cache) loading() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// set stores value loaded at generation unless anything was evicted since
+func (c *Cache) set(generation uint64, key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation == c.generation {
+		c.backend.Set(key, value)
+	}
+}
+
+func (c *Cache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.backend.Delete(key)
+}
+
+// Tenants keeps one Cache of every tenant on shared backend
+type Tenants struct {
+	backend Backend
+
+	mu     sync.Mutex
+	caches map[string]*Cache
+}
+
+func NewTenants(backend Backend) *Tenants {
+	return &Tenants{backend: backend, caches: make(map[string]*Cache)}
+}
+
+// ForContext returns Cache of tenant of ctx, it is created on first use
+func (t *Tenants) ForContext(ctx context.Context) *Cache {
+	tenant := domain.TenantFromContext(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.caches[tenant]
+	if !ok {
+		c = NewCache(ForTenant(ctx, t.backend))
+		t.caches[tenant] = c
+	}
+	return c
+}
+
+// Caches returns Cache of every tenant used so far by tenant
+func (t *Tenants) Caches() map[string]*Cache {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	caches := make(map[string]*Cache, len(t.caches))
+	for tenant, c := range t.caches {
+		caches[tenant] = c
+	}
+	return caches
+}
@@ -0,0 +1,8 @@
+package cache
+
+import "github.com/idj1997/book-rent-core/config"
+
+// NewBackend creates local LRU backend sized by cache config
+func NewBackend(cfg config.CacheConfig) Backend {
+	return NewLRU(cfg.Size, cfg.TTL)
+}
@@ -0,0 +1,25 @@
+package cache
+
+import "sync/atomic"
+
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type counter struct {
+	hits   uint64
+	misses uint64
+}
+
+func (c *counter) hit() {
+	atomic.AddUint64(&c.hits, 1)
+}
+
+func (c *counter) miss() {
+	atomic.AddUint64(&c.misses, 1)
+}
+
+func (c *counter) stats() Stats {
+	return Stats{Hits: atomic.LoadUint64(&c.hits), Misses: atomic.LoadUint64(&c.misses)}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// Transactor evicts books and users written in transaction once it finishes, so values read
+// concurrently before commit do not outlive it. Reads inside transaction bypass cache.
+type Transactor struct {
+	inner domain.Transactor
+	cache *Cache
+}
+
+func NewTransactor(inner domain.Transactor, cache *Cache) *Transactor {
+	return &Transactor{inner: inner, cache: cache}
+}
+
+// WithContext returns transactor whose inner transactor runs under span of ctx
+func (t *Transactor) WithContext(ctx context.Context) domain.Transactor {
+	return NewTransactor(domain.TransactorWithContext(ctx, t.inner), t.cache)
+}
+
+func (t *Transactor) Transaction(fn func(repos domain.Repositories) error) error {
+	written := &writtenKeys{}
+	defer written.evict(t.cache)
+
+	return t.inner.Transaction(func(repos domain.Repositories) error {
+		repos.Books = &evictingBookRepository{BookRepository: repos.Books, written: written}
+		repos.Users = &evictingUserRepository{UserRepository: repos.Users, written: written}
+		return fn(repos)
+	})
+}
+
+type writtenKeys struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (w *writtenKeys) add(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.keys = append(w.keys, key)
+}
+
+func (w *writtenKeys) evict(cache *Cache) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range w.keys {
+		cache.delete(key)
+	}
+}
+
+type evictingBookRepository struct {
+	domain.BookRepository
+	written *writtenKeys
+}
+
+func (r *evictingBookRepository) WithContext(ctx context.Context) domain.BookRepository {
+	return &evictingBookRepository{BookRepository: domain.BookRepositoryWithContext(ctx, r.BookRepository), written: r.written}
+}
+
+func (r *evictingBookRepository) Update(book *domain.Book, updates map[string]interface{}) error {
+	r.written.add(bookKey(int(book.ID)))
+	return r.BookRepository.Update(book, updates)
+}
+
+func (r *evictingBookRepository) Delete(id int) error {
+	r.written.add(bookKey(id))
+	return r.BookRepository.Delete(id)
+}
+
+func (r *evictingBookRepository) Restore(id int) error {
+	r.written.add(bookKey(id))
+	return r.BookRepository.Restore(id)
+}
+
+type evictingUserRepository struct {
+	domain.UserRepository
+	written *writtenKeys
+}
+
+func (r *evictingUserRepository) WithContext(ctx context.Context) domain.UserRepository {
+	return &evictingUserRepository{UserRepository: domain.UserRepositoryWithContext(ctx, r.UserRepository), written: r.written}
+}
+
+func (r *evictingUserRepository) Update(user *domain.User, updates map[string]interface{}) error {
+	r.written.add(userKey(int(user.ID)))
+	return r.UserRepository.Update(user, updates)
+}
+
+func (r *evictingUserRepository) Delete(id int) error {
+	r.written.add(userKey(id))
+	return r.UserRepository.Delete(id)
+}
+
+func (r *evictingUserRepository) Restore(id int) error {
+	r.written.add(userKey(id))
+	return r.UserRepository.Restore(id)
+}
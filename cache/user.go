@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// UserRepository caches users found by ID or email, other reads go to inner repository.
+// Email maps to user ID, so evicting user by ID also invalidates lookups by any of its emails.
+type UserRepository struct {
+	domain.UserRepository
+	cache *Cache
+}
+
+func NewUserRepository(inner domain.UserRepository, cache *Cache) *UserRepository {
+	return &UserRepository{UserRepository: inner, cache: cache}
+}
+
+// WithContext returns repository whose inner repository runs under span of ctx
+func (r *UserRepository) WithContext(ctx context.Context) domain.UserRepository {
+	return NewUserRepository(domain.UserRepositoryWithContext(ctx, r.UserRepository), r.cache)
+}
+
+// Stats returns hits and misses of GetByID and GetByEmail of all repositories sharing cache
+func (r *UserRepository) Stats() Stats {
+	return r.cache.UserStats()
+}
+
+// GetByID returns copy of cached user, so callers may modify it
+func (r *UserRepository) GetByID(id int) (*domain.User, error) {
+	if user, ok := r.cached(id); ok {
+		r.cache.users.hit()
+		return user, domain.NilRepoErrPtr
+	}
+
+	r.cache.users.miss()
+	generation := r.cache.loading()
+	user, err := r.UserRepository.GetByID(id)
+	if err == domain.NilRepoErrPtr {
+		r.store(generation, user)
+	}
+	return user, err
+}
+
+func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
+	if id, ok := r.cache.get(emailKey(email)); ok {
+		if user, ok := r.cached(id.(int)); ok && user.Email == email {
+			r.cache.users.hit()
+			return user, domain.NilRepoErrPtr
+		}
+	}
+
+	r.cache.users.miss()
+	generation := r.cache.loading()
+	user, err := r.UserRepository.GetByEmail(email)
+	if err == domain.NilRepoErrPtr {
+		r.store(generation, user)
+	}
+	return user, err
+}
+
+func (r *UserRepository) Update(user *domain.User, updates map[string]interface{}) error {
+	defer r.cache.delete(userKey(int(user.ID)))
+	return r.UserRepository.Update(user, updates)
+}
+
+func (r *UserRepository) Delete(id int) error {
+	defer r.cache.delete(userKey(id))
+	return r.UserRepository.Delete(id)
+}
+
+func (r *UserRepository) Restore(id int) error {
+	defer r.cache.delete(userKey(id))
+	return r.UserRepository.Restore(id)
+}
+
+func (r *UserRepository) cached(id int) (*domain.User, bool) {
+	value, ok := r.cache.get(userKey(id))
+	if !ok {
+		return nil, false
+	}
+	user := value.(domain.User)
+	return &user, true
+}
+
+// store caches user loaded at generation
+func (r *UserRepository) store(generation uint64, user *domain.User) {
+	r.cache.set(generation, userKey(int(user.ID)), *user)
+	r.cache.set(generation, emailKey(user.Email), int(user.ID))
+}
+
+func userKey(id int) string {
+	return "user:" + strconv.Itoa(id)
+}
+
+func emailKey(email string) string {
+	return "user:email:" + email
+}
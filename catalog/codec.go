@@ -0,0 +1,159 @@
+// Package catalog reads and writes book catalogs in CSV and JSON
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// Columns is header of CSV catalog, columns are matched by name in any order
+var Columns = []string{"title", "content", "stock", "price", "isbn"}
+
+// Row is decoded catalog record, Err is set when record could not be parsed
+type Row struct {
+	Row   int
+	Entry domain.CatalogEntry
+	Err   error
+}
+
+// Decode reads all records of catalog, malformed records are returned with Err set
+// while error is returned only when input as a whole cannot be read
+func Decode(r io.Reader, format domain.CatalogFormat) ([]Row, error) {
+	switch format {
+	case domain.CSVFormat:
+		return decodeCSV(r)
+	case domain.JSONFormat:
+		return decodeJSON(r)
+	}
+	return nil, fmt.Errorf("unsupported catalog format %q", format)
+}
+
+// Encode writes entries as catalog in format
+func Encode(w io.Writer, format domain.CatalogFormat, entries []domain.CatalogEntry) error {
+	switch format {
+	case domain.CSVFormat:
+		return encodeCSV(w, entries)
+	case domain.JSONFormat:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+	return fmt.Errorf("unsupported catalog format %q", format)
+}
+
+// FormatOf guesses catalog format from file name extension
+func FormatOf(filename string) domain.CatalogFormat {
+	lower := strings.ToLower(filename)
+	if strings.HasSuffix(lower, ".json") {
+		return domain.JSONFormat
+	}
+	return domain.CSVFormat
+}
+
+func decodeCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // row length is checked per record
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error while reading catalog header: %v", err)
+	}
+	index := make(map[string]int)
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"title", "content"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("catalog header is missing %q column", required)
+		}
+	}
+
+	rows := make([]Row, 0)
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			if _, malformed := err.(*csv.ParseError); !malformed {
+				return nil, err
+			}
+			rows = append(rows, Row{Row: n, Err: err})
+			continue
+		}
+		if len(record) != len(header) {
+			rows = append(rows, Row{Row: n, Err: fmt.Errorf("expected %d fields, got %d", len(header), len(record))})
+			continue
+		}
+
+		field := func(column string) string {
+			if i, ok := index[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := Row{Row: n, Entry: domain.CatalogEntry{
+			Title:   field("title"),
+			Content: field("content"),
+			ISBN:    field("isbn")}}
+		row.Entry.Stock, row.Err = parseInt("stock", field("stock"))
+		if row.Err == nil {
+			row.Entry.Price, row.Err = parseInt("price", field("price"))
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseInt(column string, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", column, value)
+	}
+	return n, nil
+}
+
+func decodeJSON(r io.Reader) ([]Row, error) {
+	var records []json.RawMessage
+	err := json.NewDecoder(r).Decode(&records)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading catalog: %v", err)
+	}
+
+	rows := make([]Row, len(records))
+	for i, record := range records {
+		rows[i].Row = i + 1
+		rows[i].Err = json.Unmarshal(record, &rows[i].Entry)
+	}
+	return rows, nil
+}
+
+func encodeCSV(w io.Writer, entries []domain.CatalogEntry) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(Columns)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err = writer.Write([]string{
+			entry.Title,
+			entry.Content,
+			strconv.Itoa(entry.Stock),
+			strconv.Itoa(entry.Price),
+			entry.ISBN})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
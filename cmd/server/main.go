@@ -0,0 +1,105 @@
+// Command server serves book, user and rent services over gRPC on address set by grpc.address,
+// over GraphQL on graphQL.address and as JSON API described by /openapi.json on http.address.
+// Callers authenticate with bearer token of one of auth.actors, whose tenant they work with.
+//
+//	server [-env dev] [-config config.yml]
+//
+// Policies are applied every policies.interval, reminders are sent every reminders.interval and
+// outbox events are dispatched every outbox.interval while serving. SIGINT and SIGTERM stop the servers
+// after running calls finish.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/idj1997/book-rent-core/app"
+	"github.com/idj1997/book-rent-core/graphqlapi"
+	"github.com/idj1997/book-rent-core/grpcapi"
+	"github.com/idj1997/book-rent-core/restapi"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	env := flag.String("env", "dev", "config environment")
+	configPath := flag.String("config", "config.yml", "config file")
+	flag.Parse()
+
+	err := run(*env, *configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "server: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(env string, configPath string) error {
+	application, err := app.New(env, configPath)
+	if err != nil {
+		return err
+	}
+	defer application.Close()
+	application.StartPolicies()
+	application.StartReminders()
+	application.StartOutbox()
+
+	grpcServer := grpcapi.NewServer(application.Config.GRPC, application.Config.Auth, grpcapi.AppScope(application))
+	httpServers := map[string]*http.Server{
+		"GraphQL":  graphqlapi.NewServer(application.Config.GraphQL, application.Config.Auth, graphqlapi.AppScope(application)),
+		"JSON API": restapi.NewServer(application.Config.HTTP, application.Config.Auth, restapi.AppScope(application))}
+	for name, server := range httpServers {
+		if server == nil {
+			delete(httpServers, name)
+		}
+	}
+	if grpcServer == nil && len(httpServers) == 0 {
+		return errors.New("none of grpc.address, graphQL.address and http.address is configured")
+	}
+
+	errs := make(chan error, len(httpServers)+1)
+	if grpcServer != nil {
+		listener, err := net.Listen("tcp", application.Config.GRPC.Address)
+		if err != nil {
+			return fmt.Errorf("error while listening for grpc: %w", err)
+		}
+		log.Printf("Serving gRPC on %s", listener.Addr())
+		go func() {
+			errs <- grpcServer.Serve(listener)
+		}()
+	}
+	for name, server := range httpServers {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			return fmt.Errorf("error while listening for %s: %w", name, err)
+		}
+		log.Printf("Serving %s on %s", name, listener.Addr())
+		go func(server *http.Server) {
+			errs <- server.Serve(listener)
+		}(server)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-signals:
+	case err = <-errs:
+	}
+
+	log.Print("Stopping servers")
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	for _, server := range httpServers {
+		_ = server.Shutdown(context.Background())
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
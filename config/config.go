@@ -2,14 +2,103 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
-	"strconv"
+	"strings"
+	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/go-playground/validator"
 	"github.com/spf13/viper"
 )
 
-var ENV string
+// EnvPrefix starts names of environment variables overriding config values,
+// BOOKRENT_DATABASE_PASSWORD overrides database.password of loaded environment
+// and BOOKRENT_DATABASE_PASSWORD_FILE reads it from secret file
+const EnvPrefix = "BOOKRENT_"
+
+// Config is typed configuration of one environment, several may be loaded in one process
+type Config struct {
+	Env       string `mapstructure:"-"`
+	Logging   LoggingConfig
+	Database  DatabaseConfig
+	Policies  PoliciesConfig
+	Outbox    OutboxConfig
+	Reminders RemindersConfig
+	Cache     CacheConfig
+	Metrics   MetricsConfig
+	Tracing   TracingConfig
+	Auth      AuthConfig
+	GRPC      GRPCConfig
+	GraphQL   GraphQLConfig
+	HTTP      HTTPConfig
+}
+
+type LoggingConfig struct {
+	// Level is logrus level name, info when empty
+	Level string `validate:"omitempty,oneof=trace debug info warn warning error fatal panic"`
+	// Format is json or text, json when empty
+	Format string `validate:"omitempty,oneof=json text"`
+	// OutputType is console, file or both
+	OutputType   string `validate:"oneof=console file both"`
+	FilePath     string
+	ReportCaller bool
+	Rotation     RotationConfig
+}
+
+// RotationConfig rotates log file by size and age, zero values disable rotation and pruning
+type RotationConfig struct {
+	// MaxSizeMB is size in megabytes file is rotated at
+	MaxSizeMB int `validate:"gte=0"`
+	// Interval is age file is rotated at
+	Interval time.Duration `validate:"gte=0"`
+	// MaxAge removes rotated files older than it
+	MaxAge time.Duration `validate:"gte=0"`
+	// MaxBackups is number of rotated files kept
+	MaxBackups int `validate:"gte=0"`
+}
+
+// ConnectionConfig holds connection settings of one postgres database
+type ConnectionConfig struct {
+	Host     string `validate:"required"`
+	Port     int    `validate:"gt=0,lte=65535"`
+	User     string `validate:"required"`
+	Password string
+	DBName   string `validate:"required"`
+	SSLMode  string `validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+}
+
+type DatabaseConfig struct {
+	ConnectionConfig `mapstructure:",squash"`
+	// Replicas serve reads, settings missing in replica entry are taken from primary
+	Replicas []ConnectionConfig `validate:"dive"`
+	Log      SQLLogConfig
+	Pool     PoolConfig
+	Startup  StartupConfig
+	Populate PopulateConfig
+}
+
+// SQLLogConfig configures gorm logger writing through logrus
+type SQLLogConfig struct {
+	// Level is silent, error, warn or info, warn when empty, info logs every statement
+	Level string `validate:"omitempty,oneof=silent error warn info"`
+	// SlowThreshold is duration statements are logged as slow at, 0 disables slow statement detection
+	SlowThreshold time.Duration `validate:"gte=0"`
+	// RedactColumns lists columns whose values are hidden in logged statements, password when empty
+	RedactColumns []string
+}
+
+type PoolConfig struct {
+	MaxOpenConns    int           `validate:"gte=0"`
+	MaxIdleConns    int           `validate:"gte=0"`
+	ConnMaxLifetime time.Duration `validate:"gte=0"`
+}
+
+type StartupConfig struct {
+	// Attempts is number of pings before startup gives up
+	Attempts int `validate:"gte=0"`
+	// Backoff is delay after first failed ping, doubled after every next one
+	Backoff time.Duration `validate:"gte=0"`
+}
 
 type PopulateConfig struct {
 	Migrate bool
@@ -17,63 +106,213 @@ type PopulateConfig struct {
 	File    string
 }
 
-func InitConfig(env string, path string) {
-	ENV = env
+type PoliciesConfig struct {
+	// Interval is how often App applies policies once started, 0 disables them
+	Interval time.Duration `validate:"gte=0"`
+	// PurgeRetention is how long deleted book or user is kept before it is purged
+	PurgeRetention time.Duration `validate:"gte=0"`
+	// LostAfter is how long expired rent stays overdue before it is declared lost
+	LostAfter time.Duration `validate:"gte=0"`
+}
+
+type OutboxConfig struct {
+	Interval    time.Duration `validate:"gt=0"`
+	BatchSize   int           `validate:"gt=0"`
+	MaxAttempts int           `validate:"gt=0"`
+	Sinks       OutboxSinksConfig
+}
+
+// OutboxSinksConfig enables file and http sinks, empty value disables sink
+type OutboxSinksConfig struct {
+	File string
+	HTTP string
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int `validate:"gte=0,lte=65535"`
+	Username string
+	Password string
+	From     string
+}
+
+type RemindersConfig struct {
+	// Interval is how often App sends reminders once started, 0 disables them
+	Interval  time.Duration `validate:"gte=0"`
+	DaysAhead []int         `validate:"dive,gte=0"`
+	// Notifier is one of smtp, file or log
+	Notifier string `validate:"oneof=smtp file log"`
+	FilePath string
+	SMTP     SMTPConfig
+}
+
+type CacheConfig struct {
+	// Size is maximum number of cached books and users, 0 disables cache
+	Size int           `validate:"gte=0"`
+	TTL  time.Duration `validate:"gte=0"`
+}
+
+type MetricsConfig struct {
+	// Address is listen address of /metrics endpoint and /healthz and /readyz probes, empty disables them
+	Address string
+	// LowStockThreshold is stock at or below which book counts as low on stock
+	LowStockThreshold int `validate:"gte=0"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp, none when empty
+	Exporter string `validate:"omitempty,oneof=none stdout otlp"`
+	// Endpoint is OTLP/HTTP traces url of collector, e.g. http://localhost:4318/v1/traces
+	Endpoint    string
+	ServiceName string
+	// BatchSize is number of spans exported at once
+	BatchSize int `validate:"gte=0"`
+	// FlushInterval is longest time ended span waits for export
+	FlushInterval time.Duration `validate:"gte=0"`
+}
+
+// AuthConfig holds actors callers of gRPC server, GraphQL endpoint and JSON API authenticate as
+type AuthConfig struct {
+	// Actors maps actor to its credentials, BOOKRENT_AUTH_ACTORS_<ACTOR>_TOKEN_FILE reads token
+	// from secret file
+	Actors map[string]ActorConfig `validate:"dive"`
+}
+
+type ActorConfig struct {
+	// Token is bearer token actor authenticates with
+	Token string `validate:"required"`
+	// Tenant is tenant whose records actor works with, default tenant when empty
+	Tenant string
+}
 
-	// load config file
-	viper.SetConfigFile(path)
-	err := viper.ReadInConfig()
+type GRPCConfig struct {
+	// Address is listen address of gRPC server, empty disables server
+	Address string
+}
+
+type GraphQLConfig struct {
+	// Address is listen address of /graphql endpoint, empty disables endpoint
+	Address string
+}
+
+type HTTPConfig struct {
+	// Address is listen address of JSON API described by restapi.Spec, empty disables API
+	Address string
+}
+
+// Load reads settings of env from config file at path, applies environment variable
+// and secret file overrides and validates result
+func Load(env string, path string) (*Config, error) {
+	file := viper.New()
+	file.SetConfigFile(path)
+	err := file.ReadInConfig()
 	if err != nil {
-		log.Fatalf("error while reading config file: %v", err)
+		return nil, fmt.Errorf("error while reading config file: %w", err)
 	}
 
-	ConfigureLogger()
+	settings := file.Sub(env)
+	if settings == nil {
+		return nil, fmt.Errorf("environment %q not found in config file %s", env, path)
+	}
+	err = applyEnvOverrides(settings, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{Env: env}
+	err = settings.Unmarshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding config: %w", err)
+	}
+	cfg.Database.inheritReplicaSettings()
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
-func ConfigureLogger() {
-	log.SetFormatter(&log.JSONFormatter{})
-	log.SetReportCaller(true)
+// Validate checks value ranges and settings required by selected options
+func (c *Config) Validate() error {
+	err := validator.New().Struct(c)
+	if err != nil {
+		return fmt.Errorf("invalid config of %s environment: %w", c.Env, err)
+	}
+
+	switch {
+	case c.Logging.OutputType != "console" && c.Logging.FilePath == "":
+		return fmt.Errorf("invalid config of %s environment: logging.filePath is required by %s output", c.Env, c.Logging.OutputType)
+	case c.Database.Populate.Init && c.Database.Populate.File == "":
+		return fmt.Errorf("invalid config of %s environment: database.populate.file is required by init", c.Env)
+	case c.Reminders.Notifier == "file" && c.Reminders.FilePath == "":
+		return fmt.Errorf("invalid config of %s environment: reminders.filePath is required by file notifier", c.Env)
+	case c.Reminders.Notifier == "smtp" && (c.Reminders.SMTP.Host == "" || c.Reminders.SMTP.From == ""):
+		return fmt.Errorf("invalid config of %s environment: reminders.smtp host and from are required by smtp notifier", c.Env)
+	case (c.GRPC.Address != "" || c.GraphQL.Address != "" || c.HTTP.Address != "") && len(c.Auth.Actors) == 0:
+		return fmt.Errorf("invalid config of %s environment: auth.actors are required by grpc, graphQL and http servers", c.Env)
+	case c.Tracing.Exporter == "otlp" && c.Tracing.Endpoint == "":
+		return fmt.Errorf("invalid config of %s environment: tracing.endpoint is required by otlp exporter", c.Env)
+	}
+	return nil
+}
 
-	partialPath := fmt.Sprintf("%s.logging.", ENV)
-	logOutputType := fmt.Sprintf("%s", viper.Get(partialPath + "outputType"))
-	logFilePath := fmt.Sprintf("%s", viper.Get( partialPath + "filePath"))
+// applyEnvOverrides sets values of environment variables starting with EnvPrefix,
+// variable ending with _FILE names secret file holding value unless its key is a section
+func applyEnvOverrides(settings *viper.Viper, environ []string) error {
+	for _, variable := range environ {
+		if !strings.HasPrefix(variable, EnvPrefix) {
+			continue
+		}
+		pair := strings.SplitN(strings.TrimPrefix(variable, EnvPrefix), "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.ReplaceAll(pair[0], "_", "."))
+		value := pair[1]
 
-	if logOutputType == "console" {
-		// pass
-	} else if logOutputType == "file" {
-		logFile, err := os.OpenFile(logFilePath, os.O_RDWR, os.ModeAppend)
-		if err != nil {
-			log.Fatalf("Error while opening log file: %v", err)
+		if secretKey := strings.TrimSuffix(key, ".file"); secretKey != key && !isSection(settings, secretKey) {
+			secret, err := ioutil.ReadFile(value)
+			if err != nil {
+				return fmt.Errorf("error while reading secret of %s: %w", pair[0], err)
+			}
+			key, value = secretKey, strings.TrimRight(string(secret), "\r\n")
 		}
-		log.SetOutput(logFile)
-	} else {
-		log.Fatalf("Invalid logging.outputType")
+		settings.Set(key, value)
 	}
+	return nil
+}
+
+func isSection(settings *viper.Viper, key string) bool {
+	_, ok := settings.Get(key).(map[string]interface{})
+	return ok
 }
 
-func GetByKey(key string) string {
-	return fmt.Sprintf("%s", viper.Get(key))
+func (c *DatabaseConfig) inheritReplicaSettings() {
+	for i := range c.Replicas {
+		replica := &c.Replicas[i]
+		if replica.Host == "" {
+			replica.Host = c.Host
+		}
+		if replica.Port == 0 {
+			replica.Port = c.Port
+		}
+		if replica.User == "" {
+			replica.User = c.User
+		}
+		if replica.Password == "" {
+			replica.Password = c.Password
+		}
+		if replica.DBName == "" {
+			replica.DBName = c.DBName
+		}
+		if replica.SSLMode == "" {
+			replica.SSLMode = c.SSLMode
+		}
+	}
 }
 
-func GetPostgresDSN() string {
-	partialPath := fmt.Sprintf("%s.database.", ENV)
+func (c ConnectionConfig) DSN() string {
 	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
-		viper.Get(partialPath+"host"),
-		viper.Get(partialPath+"user"),
-		viper.Get(partialPath+"password"),
-		viper.Get(partialPath+"dbname"),
-		viper.Get(partialPath+"port"),
-		viper.Get(partialPath+"sslmode"))
-}
-
-func GetPopulateConfig() PopulateConfig {
-	partialPath := fmt.Sprintf("%s.database.populate.", ENV)
-	migrate, _ := strconv.ParseBool(fmt.Sprint(viper.Get(partialPath + "migrate")))
-	init, _ := strconv.ParseBool(fmt.Sprint(viper.Get(partialPath + "init")))
-	file := fmt.Sprint(viper.Get(partialPath + "file"))
-
-	return PopulateConfig{
-		Migrate: migrate,
-		Init:    init,
-		File:    file}
+		c.Host, c.User, c.Password, c.DBName, c.Port, c.SSLMode)
 }
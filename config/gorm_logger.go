@@ -0,0 +1,218 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// DefaultRedactColumns are columns whose values are hidden when SQLLogConfig lists none
+var DefaultRedactColumns = []string{"password"}
+
+// Redacted replaces values of redacted columns in logged statements
+const Redacted = "'[REDACTED]'"
+
+var sqlLogLevels = map[string]logger.LogLevel{
+	"":       logger.Warn,
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+// GormLogger writes gorm messages and statements through logrus with sql, rows, duration_ms and
+// caller fields. Statements are logged at info level, slow ones at warn and failed ones at error,
+// record not found is not treated as failure.
+type GormLogger struct {
+	Logger        *log.Logger
+	Level         logger.LogLevel
+	SlowThreshold time.Duration
+	redactor      *redactor
+}
+
+// NewGormLogger creates gorm logger writing to l with level and slow threshold of cfg
+func NewGormLogger(l *log.Logger, cfg SQLLogConfig) *GormLogger {
+	columns := cfg.RedactColumns
+	if len(columns) == 0 {
+		columns = DefaultRedactColumns
+	}
+	return &GormLogger{
+		Logger:        l,
+		Level:         sqlLogLevels[cfg.Level],
+		SlowThreshold: cfg.SlowThreshold,
+		redactor:      newRedactor(columns)}
+}
+
+func (g *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	levelLogger := *g
+	levelLogger.Level = level
+	return &levelLogger
+}
+
+func (g *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if g.Level >= logger.Info {
+		g.entry(ctx, utils.FileWithLineNum()).Infof(msg, data...)
+	}
+}
+
+func (g *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if g.Level >= logger.Warn {
+		g.entry(ctx, utils.FileWithLineNum()).Warnf(msg, data...)
+	}
+}
+
+func (g *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if g.Level >= logger.Error {
+		g.entry(ctx, utils.FileWithLineNum()).Errorf(msg, data...)
+	}
+}
+
+func (g *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.Level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := g.SlowThreshold != 0 && elapsed > g.SlowThreshold
+
+	switch {
+	case failed && g.Level >= logger.Error:
+		g.statement(ctx, utils.FileWithLineNum(), elapsed, fc).WithError(err).Error("sql statement failed")
+	case slow && g.Level >= logger.Warn:
+		g.statement(ctx, utils.FileWithLineNum(), elapsed, fc).
+			WithField("slow_threshold_ms", milliseconds(g.SlowThreshold)).
+			Warn("slow sql statement")
+	case g.Level >= logger.Info:
+		g.statement(ctx, utils.FileWithLineNum(), elapsed, fc).Info("sql statement")
+	}
+}
+
+func (g *GormLogger) entry(ctx context.Context, caller string) *log.Entry {
+	entry := log.NewEntry(g.Logger).WithField("caller", caller)
+	if ctx != nil {
+		entry = entry.WithContext(ctx)
+	}
+	return entry
+}
+
+func (g *GormLogger) statement(ctx context.Context, caller string, elapsed time.Duration, fc func() (string, int64)) *log.Entry {
+	sql, rows := fc()
+	fields := log.Fields{"sql": g.redactor.redact(sql), "duration_ms": milliseconds(elapsed)}
+	if rows != -1 {
+		fields["rows"] = rows
+	}
+	return g.entry(ctx, caller).WithFields(fields)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
+
+// sqlValue matches quoted literal with backslash or doubled quote escapes, or bare token
+const sqlValue = `(?:'(?:[^'\\]|\\.|'')*'|[^\s,)]+)`
+
+// redactor hides values of columns in statements with parameters already filled in
+type redactor struct {
+	columns    map[string]bool
+	comparison *regexp.Regexp
+	insert     *regexp.Regexp
+}
+
+func newRedactor(columns []string) *redactor {
+	r := &redactor{columns: make(map[string]bool, len(columns))}
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		r.columns[strings.ToLower(column)] = true
+		quoted = append(quoted, regexp.QuoteMeta(column))
+	}
+	r.comparison = regexp.MustCompile(fmt.Sprintf(`(?i)("?(?:%s)"?\s*(?:=|<>|!=)\s*)%s`, strings.Join(quoted, "|"), sqlValue))
+	r.insert = regexp.MustCompile(`(?i)^\s*INSERT\s+INTO\s+\S+\s*\(([^)]*)\)\s*VALUES\s*`)
+	return r
+}
+
+// redact hides values compared with or assigned to redacted columns and inserted into them
+func (r *redactor) redact(sql string) string {
+	sql = r.comparison.ReplaceAllString(sql, "${1}"+Redacted)
+
+	match := r.insert.FindStringSubmatchIndex(sql)
+	if match == nil {
+		return sql
+	}
+	positions := make(map[int]bool)
+	for i, column := range strings.Split(sql[match[2]:match[3]], ",") {
+		if r.columns[strings.ToLower(strings.Trim(strings.TrimSpace(column), `"`))] {
+			positions[i] = true
+		}
+	}
+	if len(positions) == 0 {
+		return sql
+	}
+	return sql[:match[1]] + redactTuples(sql[match[1]:], positions)
+}
+
+// redactTuples replaces values at positions of every tuple in values list and keeps the rest
+// of statement, like RETURNING clause, as it is
+func redactTuples(values string, positions map[int]bool) string {
+	var out strings.Builder
+	depth, position, quoted := 0, 0, false
+	for i := 0; i < len(values); i++ {
+		c := values[i]
+		hidden := depth >= 1 && positions[position]
+		switch {
+		case quoted:
+			if c == '\\' && i+1 < len(values) {
+				if !hidden {
+					out.WriteByte(c)
+					out.WriteByte(values[i+1])
+				}
+				i++
+				continue
+			}
+			if c == '\'' {
+				if i+1 < len(values) && values[i+1] == '\'' {
+					if !hidden {
+						out.WriteString("''")
+					}
+					i++
+					continue
+				}
+				quoted = false
+			}
+		case c == '\'':
+			quoted = true
+		case c == '(':
+			depth++
+			if depth == 1 {
+				position = 0
+				out.WriteByte(c)
+				if positions[position] {
+					out.WriteString(Redacted)
+				}
+				continue
+			}
+		case c == ')':
+			depth--
+		case c == ',' && depth == 1:
+			position++
+			out.WriteByte(c)
+			if positions[position] {
+				out.WriteString(Redacted)
+			}
+			continue
+		case depth == 0 && c != ',' && c != ' ':
+			return out.String() + values[i:]
+		}
+		if !hidden || (c == ')' && depth == 0) {
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HealthStatus string
+
+const (
+	HealthUp   HealthStatus = "up"
+	HealthDown HealthStatus = "down"
+)
+
+// HealthReport describes database as seen by liveness and readiness probes
+type HealthReport struct {
+	Status HealthStatus `json:"status"`
+	// LatencyMs is round trip of ping in milliseconds
+	LatencyMs     float64     `json:"latencyMs"`
+	Pool          sql.DBStats `json:"pool"`
+	SchemaVersion int         `json:"schemaVersion"`
+	// ExpectedSchemaVersion is version migrated by this build, readiness requires it
+	ExpectedSchemaVersion int    `json:"expectedSchemaVersion"`
+	Error                 string `json:"error,omitempty"`
+}
+
+// HealthChecker probes primary database for liveness and readiness
+type HealthChecker struct {
+	DB *gorm.DB
+	// Timeout bounds ping and schema version query, defaults to two seconds
+	Timeout time.Duration
+}
+
+func NewHealthChecker(db *gorm.DB) *HealthChecker {
+	return &HealthChecker{DB: db, Timeout: 2 * time.Second}
+}
+
+// Liveness reports database up when it answers ping
+func (h *HealthChecker) Liveness(ctx context.Context) HealthReport {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	return h.ping(ctx)
+}
+
+// Readiness reports database up when it answers ping and its schema is migrated to SchemaVersion
+func (h *HealthChecker) Readiness(ctx context.Context) HealthReport {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+
+	report := h.ping(ctx)
+	if report.Status == HealthDown {
+		return report
+	}
+
+	version, err := MigrationVersion(h.DB.WithContext(ctx))
+	report.SchemaVersion = version
+	if err != nil {
+		report.Status, report.Error = HealthDown, err.Error()
+	} else if version < SchemaVersion {
+		report.Status, report.Error = HealthDown, "database schema is not migrated"
+	}
+	return report
+}
+
+// LivenessHandler serves liveness report as json, 503 when database is down
+func (h *HealthChecker) LivenessHandler() http.Handler {
+	return reportHandler(h.Liveness)
+}
+
+// ReadinessHandler serves readiness report as json, 503 when database is not ready
+func (h *HealthChecker) ReadinessHandler() http.Handler {
+	return reportHandler(h.Readiness)
+}
+
+func (h *HealthChecker) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (h *HealthChecker) ping(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthUp, ExpectedSchemaVersion: SchemaVersion}
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		report.Status, report.Error = HealthDown, err.Error()
+		return report
+	}
+
+	start := time.Now()
+	err = sqlDB.PingContext(ctx)
+	report.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	report.Pool = sqlDB.Stats()
+	if err != nil {
+		report.Status, report.Error = HealthDown, err.Error()
+	}
+	return report
+}
+
+func reportHandler(check func(ctx context.Context) HealthReport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := check(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != HealthUp {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
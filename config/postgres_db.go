@@ -2,103 +2,410 @@ package config
 
 import (
 	"bufio"
+	"database/sql"
+	"fmt"
 	"github.com/idj1997/book-rent-core/domain"
-	golog "log"
+	"github.com/idj1997/book-rent-core/repository"
 	"os"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/clause"
 )
 
-func OpenPostgresDB() *gorm.DB {
-	DSN := GetPostgresDSN()
-	config := GetGormConfig()
-	db, err := gorm.Open(postgres.Open(DSN), config)
+// SchemaVersion is version of database schema migrated by this build,
+// bump it whenever migrated models change
+const SchemaVersion = 2
+
+// SchemaMigration records schema version migrated into database
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
+
+// Pinger is database checked for connectivity
+type Pinger interface {
+	Ping() error
+}
+
+// OpenPostgresDB opens, migrates and populates primary database and routes reads to replicas,
+// connection is closed again when any step fails
+func OpenPostgresDB(cfg DatabaseConfig) (*gorm.DB, error) {
+	db, err := openPostgres(cfg.ConnectionConfig, cfg)
 	if err != nil {
-		log.Fatalf("Error while opening DB connection: %v\n", err)
-	} else {
-		log.Println("Connection opened to DB")
+		return nil, fmt.Errorf("error while opening DB connection: %w", err)
 	}
+	log.Println("Connection opened to DB")
 
-	MigratePostgresDB(db)
-	InitPostgresDB(db)
-	return db
+	err = preparePostgresDB(db, cfg)
+	if err != nil {
+		_ = ClosePostgresDB(db)
+		return nil, err
+	}
+	return db, nil
 }
 
-func GetGormConfig() *gorm.Config {
-	config := gorm.Config{}
-	if ENV == "test" {
-		config.Logger = TestLogger()
+func preparePostgresDB(db *gorm.DB, cfg DatabaseConfig) error {
+	err := repository.RegisterTenantScope(db)
+	if err != nil {
+		return fmt.Errorf("error while registering tenant scope: %w", err)
+	}
+
+	err = MigratePostgresDB(db, cfg.Populate)
+	if err != nil {
+		return err
 	}
-	return &config
+	err = InitPostgresDB(db, cfg.Populate)
+	if err != nil {
+		return err
+	}
+	err = BackfillBranches(db)
+	if err != nil {
+		return err
+	}
+	err = BackfillStockLedger(db)
+	if err != nil {
+		return err
+	}
+	err = BackfillTenants(db)
+	if err != nil {
+		return err
+	}
+	return OpenReplicas(db, cfg)
 }
 
-func TestLogger() logger.Interface {
-	newLogger := logger.New(
-		golog.New(os.Stdout, "\r\n", golog.LstdFlags), // io writer
-		logger.Config{
-			SlowThreshold: time.Second * 0, // Slow SQL threshold
-			LogLevel:      logger.Info,     // Log level
-			Colorful:      true,            // Disable color
-		})
-	return newLogger
+// OpenReplicas connects read replicas of config and routes reads of db to them,
+// must follow migrations so schema checks run against primary
+func OpenReplicas(db *gorm.DB, cfg DatabaseConfig) error {
+	if len(cfg.Replicas) == 0 {
+		return nil
+	}
+
+	replicas := &repository.Replicas{}
+	for _, replicaConfig := range cfg.Replicas {
+		replica, err := openPostgres(replicaConfig, cfg)
+		if err == nil {
+			var pool *sql.DB
+			pool, err = replica.DB()
+			replicas.Pools = append(replicas.Pools, pool)
+		}
+		if err != nil {
+			_ = replicas.Close()
+			return fmt.Errorf("error while opening replica connection to %s: %w", replicaConfig.Host, err)
+		}
+	}
+
+	err := db.Use(replicas)
+	if err != nil {
+		_ = replicas.Close()
+		return fmt.Errorf("error while registering replicas: %w", err)
+	}
+	log.Printf("Routing reads to %d replica(s)", len(replicas.Pools))
+	return nil
 }
 
-func ClosePostgresDB(db *gorm.DB) {
+// openPostgres opens database of connection with pool configured by cfg, waiting until it answers ping
+func openPostgres(connection ConnectionConfig, cfg DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(connection.DSN()), GetGormConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Error getting DB from gormDB in closing DB: %v\n", err)
+		return nil, err
 	}
 
-	err = sqlDB.Close()
+	ConfigurePool(sqlDB, cfg.Pool)
+	err = WaitForDB(sqlDB, cfg.Startup)
 	if err != nil {
-		log.Fatalf("Error closing DB: %v\n", err)
+		_ = sqlDB.Close()
+		return nil, err
 	}
+	return db, nil
+}
 
-	log.Println("Closed DB")
+// ConfigurePool applies pool settings, zero values keep database/sql defaults
+func ConfigurePool(sqlDB *sql.DB, pool PoolConfig) {
+	if pool.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
 }
 
-func MigratePostgresDB(db *gorm.DB) {
-	populateConfig := GetPopulateConfig()
-	if populateConfig.Migrate {
+// WaitForDB pings database until it answers, backing off between failed attempts,
+// returns last ping error once attempts are exhausted
+func WaitForDB(db Pinger, startup StartupConfig) error {
+	attempts := startup.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
 
-		if populateConfig.Init {
-			err := db.Migrator().DropTable(&domain.Book{}, &domain.User{}, &domain.RentDetails{})
-			if err != nil {
-				log.Fatalf("Error while dropping tables: %v", err)
-			}
+	delay := startup.Backoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = db.Ping()
+		if err == nil {
+			return nil
 		}
+		if attempt < attempts {
+			log.Warnf("Database not reachable, attempt %d of %d, retrying in %v: %v", attempt, attempts, delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+func GetGormConfig(cfg DatabaseConfig) *gorm.Config {
+	// connectivity is checked by WaitForDB
+	return &gorm.Config{DisableAutomaticPing: true, Logger: NewGormLogger(log.StandardLogger(), cfg.Log)}
+}
 
-		err := db.AutoMigrate(&domain.Book{}, &domain.User{}, &domain.RentDetails{})
+// ClosePostgresDB closes replicas and primary database, attempting both when either fails
+func ClosePostgresDB(db *gorm.DB) error {
+	var errs closeErrors
+	if plugin, ok := db.Config.Plugins[repository.ReplicasPluginName]; ok {
+		err := plugin.(*repository.Replicas).Close()
 		if err != nil {
-			log.Fatalf("Error while migrating DB: %v\n", err)
-		} else {
-			log.Printf("Migrated DB \n")
+			errs = append(errs, fmt.Errorf("error closing replicas: %w", err))
 		}
 	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error getting DB from gormDB in closing DB: %w", err))
+	} else if err = sqlDB.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("error closing DB: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	log.Println("Closed DB")
+	return nil
+}
+
+// closeErrors joins errors of closing replicas and primary database
+type closeErrors []error
+
+func (e closeErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns first error, so errors.Is matches failure of replicas or, without them, of primary
+func (e closeErrors) Unwrap() error {
+	return e[0]
 }
 
-func InitPostgresDB(db *gorm.DB) {
-	populateConfig := GetPopulateConfig()
+// models returns all migrated domain models
+func models() []interface{} {
+	return []interface{}{
+		&domain.Book{},
+		&domain.User{},
+		&domain.RentDetails{},
+		&domain.AuditRecord{},
+		&domain.OutboxEvent{},
+		&domain.SentReminder{},
+		&domain.StockMovement{},
+		&domain.Branch{},
+		&domain.BranchStock{},
+		&domain.Transfer{},
+		&SchemaMigration{}}
+}
+
+func MigratePostgresDB(db *gorm.DB, populateConfig PopulateConfig) error {
+	if !populateConfig.Migrate {
+		return nil
+	}
+
 	if populateConfig.Init {
-		statements := LoadStatementsFromFile(populateConfig.File)
-		for _, statement := range statements {
-			err := db.Exec(statement).Error
-			if err != nil {
-				log.Fatalf("Error while inserting rows in DB: %v\n", err)
-			}
+		err := db.Migrator().DropTable(models()...)
+		if err != nil {
+			return fmt.Errorf("error while dropping tables: %w", err)
+		}
+	}
+
+	err := CreatePostgresExtensions(db)
+	if err != nil {
+		return fmt.Errorf("error while creating extensions: %w", err)
+	}
+
+	err = db.AutoMigrate(models()...)
+	if err != nil {
+		return fmt.Errorf("error while migrating DB: %w", err)
+	}
+	log.Printf("Migrated DB \n")
+
+	err = DropLegacyIndexes(db)
+	if err != nil {
+		return fmt.Errorf("error while dropping legacy indexes: %w", err)
+	}
+
+	err = db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&SchemaMigration{Version: SchemaVersion, AppliedAt: time.Now()}).Error
+	if err != nil {
+		return fmt.Errorf("error while recording schema version: %w", err)
+	}
+	return nil
+}
+
+// CreatePostgresExtensions installs extensions required by repository queries
+func CreatePostgresExtensions(db *gorm.DB) error {
+	for _, extension := range []string{"pg_trgm", "unaccent"} {
+		err := db.Exec("CREATE EXTENSION IF NOT EXISTS " + extension).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropLegacyIndexes removes unique indexes replaced by per tenant ones
+func DropLegacyIndexes(db *gorm.DB) error {
+	for _, index := range []string{"idx_users_email", "idx_books_isbn", "idx_branches_name", "idx_branches_default"} {
+		err := db.Exec("DROP INDEX IF EXISTS " + index).Error
+		if err != nil {
+			return err
 		}
-		log.Printf("Populated DB with %v", populateConfig.File)
 	}
+	return nil
 }
 
-func LoadStatementsFromFile(filename string) []string {
+func InitPostgresDB(db *gorm.DB, populateConfig PopulateConfig) error {
+	if !populateConfig.Init {
+		return nil
+	}
+
+	statements, err := LoadStatementsFromFile(populateConfig.File)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		err = db.Exec(statement).Error
+		if err != nil {
+			return fmt.Errorf("error while inserting rows in DB: %w", err)
+		}
+	}
+	log.Printf("Populated DB with %v", populateConfig.File)
+	return nil
+}
+
+// BackfillBranches creates default branch of every tenant and assigns to it stock and rents
+// recorded before branches existed
+func BackfillBranches(db *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO branches (name, is_default, created_at, updated_at, tenant_id)
+		SELECT 'main', true, now(), now(), tenants.tenant_id
+		FROM (SELECT DISTINCT tenant_id FROM books) AS tenants
+		WHERE NOT EXISTS (SELECT 1 FROM branches
+			WHERE branches.tenant_id = tenants.tenant_id AND is_default AND deleted_at IS NULL)`,
+		`INSERT INTO branch_stocks (book_id, branch_id, stock, tenant_id)
+		SELECT books.id, branches.id, books.stock, books.tenant_id
+		FROM books, branches
+		WHERE branches.is_default AND branches.deleted_at IS NULL AND branches.tenant_id = books.tenant_id
+		AND NOT EXISTS (SELECT 1 FROM branch_stocks WHERE branch_stocks.book_id = books.id)`,
+		`UPDATE stock_movements SET branch_id = branches.id
+		FROM branches
+		WHERE branches.is_default AND branches.deleted_at IS NULL AND branches.tenant_id = stock_movements.tenant_id
+		AND (stock_movements.branch_id IS NULL OR stock_movements.branch_id = 0)`,
+		`UPDATE rent_details SET branch_id = branches.id
+		FROM branches
+		WHERE branches.is_default AND branches.deleted_at IS NULL AND branches.tenant_id = rent_details.tenant_id
+		AND (rent_details.branch_id IS NULL OR rent_details.branch_id = 0)`}
+	for _, statement := range statements {
+		err := db.Exec(statement).Error
+		if err != nil {
+			return fmt.Errorf("error while backfilling branches: %w", err)
+		}
+	}
+	return nil
+}
+
+// BackfillStockLedger records opening balance movement for books stocked before
+// stock ledger existed, so stock of every book equals sum of its movements
+func BackfillStockLedger(db *gorm.DB) error {
+	result := db.Exec(`
+		INSERT INTO stock_movements (book_id, branch_id, kind, quantity, reason, actor, created_at, tenant_id)
+		SELECT books.id, branch_stocks.branch_id, ?, branch_stocks.stock, 'opening balance', 'system', now(), books.tenant_id
+		FROM books
+		JOIN branch_stocks ON branch_stocks.book_id = books.id
+		WHERE branch_stocks.stock <> 0
+		AND NOT EXISTS (SELECT 1 FROM stock_movements WHERE stock_movements.book_id = books.id)`,
+		domain.MovementCorrection)
+	if result.Error != nil {
+		return fmt.Errorf("error while backfilling stock ledger: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Recorded opening stock balance of %d books", result.RowsAffected)
+	}
+	return nil
+}
+
+// BackfillTenants assigns ledger, branch stock, transfers and audit records stored before they
+// were owned by tenant to tenant of their book, user or rent, then moves them from default branch
+// shared before branches were owned by tenant to default branch of their tenant
+func BackfillTenants(db *gorm.DB) error {
+	statements := []string{
+		`UPDATE stock_movements SET tenant_id = books.tenant_id
+		FROM books WHERE books.id = stock_movements.book_id AND stock_movements.tenant_id <> books.tenant_id`,
+		`UPDATE branch_stocks SET tenant_id = books.tenant_id
+		FROM books WHERE books.id = branch_stocks.book_id AND branch_stocks.tenant_id <> books.tenant_id`,
+		`UPDATE transfers SET tenant_id = books.tenant_id
+		FROM books WHERE books.id = transfers.book_id AND transfers.tenant_id <> books.tenant_id`,
+		`UPDATE audit_records SET tenant_id = books.tenant_id
+		FROM books WHERE audit_records.entity = 'Book' AND books.id = audit_records.entity_id
+		AND audit_records.tenant_id <> books.tenant_id`,
+		`UPDATE audit_records SET tenant_id = users.tenant_id
+		FROM users WHERE audit_records.entity = 'User' AND users.id = audit_records.entity_id
+		AND audit_records.tenant_id <> users.tenant_id`,
+		`UPDATE audit_records SET tenant_id = rent_details.tenant_id
+		FROM rent_details WHERE audit_records.entity = 'RentDetails' AND rent_details.id = audit_records.entity_id
+		AND audit_records.tenant_id <> rent_details.tenant_id`}
+	branchColumns := [][2]string{
+		{"branch_stocks", "branch_id"},
+		{"stock_movements", "branch_id"},
+		{"rent_details", "branch_id"},
+		{"rent_details", "return_branch_id"},
+		{"transfers", "from_branch_id"},
+		{"transfers", "to_branch_id"}}
+	for _, column := range branchColumns {
+		statements = append(statements, fmt.Sprintf(`UPDATE %[1]s SET %[2]s = own.id
+		FROM branches AS shared, branches AS own
+		WHERE shared.id = %[1]s.%[2]s AND shared.is_default AND shared.tenant_id <> %[1]s.tenant_id
+		AND own.tenant_id = %[1]s.tenant_id AND own.is_default AND own.deleted_at IS NULL`, column[0], column[1]))
+	}
+	for _, statement := range statements {
+		err := db.Exec(statement).Error
+		if err != nil {
+			return fmt.Errorf("error while backfilling tenants: %w", err)
+		}
+	}
+	return nil
+}
+
+// MigrationVersion returns latest schema version migrated into database, 0 when none was recorded
+func MigrationVersion(db *gorm.DB) (int, error) {
+	var version int
+	err := db.Model(&SchemaMigration{}).Select("coalesce(max(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+func LoadStatementsFromFile(filename string) ([]string, error) {
 	f, err := os.Open(filename)
 	if err != nil {
-		log.Fatalf("Error while loading file %v: %v\n", filename, err)
+		return nil, fmt.Errorf("error while loading file %v: %w", filename, err)
 	}
 	defer func() {
 		_ = f.Close()
@@ -109,5 +416,8 @@ func LoadStatementsFromFile(filename string) []string {
 	for s.Scan() {
 		statements = append(statements, s.Text())
 	}
-	return statements
+	if err = s.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading file %v: %w", filename, err)
+	}
+	return statements, nil
 }
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "CREATE"
+	AuditUpdate  AuditAction = "UPDATE"
+	AuditDelete  AuditAction = "DELETE"
+	AuditRestore AuditAction = "RESTORE"
+)
+
+// FieldChange holds value of a single field before and after change
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges maps column names to their changes, stored as json
+type AuditChanges map[string]FieldChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	}
+	return errors.New("unsupported audit changes value")
+}
+
+type AuditRecord struct {
+	ID        uint         `gorm:"primarykey"`
+	Actor     string       `gorm:"not null;index"`
+	Entity    string       `gorm:"not null;index:idx_audit_records_entity"`
+	EntityID  uint         `gorm:"index:idx_audit_records_entity"`
+	Action    AuditAction  `gorm:"not null"`
+	Changes   AuditChanges `gorm:"type:jsonb"`
+	CreatedAt time.Time    `gorm:"index"`
+	TenantID  string       `gorm:"not null;default:'default';index"` // tenant of audited entity
+}
+
+// AuditFilter narrows audit records, zero valued fields are ignored
+type AuditFilter struct {
+	Entity   string
+	EntityID uint
+	Actor    string
+	From     time.Time
+	To       time.Time
+}
+
+type AuditRepository interface {
+	Create(record *AuditRecord) error
+	Find(filter AuditFilter) ([]AuditRecord, error)
+}
+
+type AuditService interface {
+	Find(filter AuditFilter) ([]AuditRecord, error)
+}
@@ -1,26 +1,52 @@
 package domain
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Book struct {
 	gorm.Model
-	Title   string `validate:"required"`
-	Content string `validate:"required"`
-	Stock   int
+	Title    string `validate:"required"`
+	Content  string `validate:"required"`
+	Stock    int    `validate:"gte=0"`
+	Price    int    `gorm:"not null;default:0" validate:"gte=0"` // replacement price of copy, in cents
+	ISBN     string `gorm:"uniqueIndex:idx_books_tenant_isbn,priority:2,where:isbn <> '' AND deleted_at IS NULL"`
+	TenantID string `gorm:"not null;default:'default';uniqueIndex:idx_books_tenant_isbn,priority:1"` // tenant owning book
 }
 
 type BookRepository interface {
 	GetByID(id int) (*Book, error)
+	// GetByIDForUpdate locks row of book until transaction ends, so its stock is not changed
+	// concurrently, it must be called inside transaction
+	GetByIDForUpdate(id int) (*Book, error)
+	// GetByIDs returns books with given IDs in no particular order, missing IDs are skipped
+	GetByIDs(ids []int) ([]Book, error)
 	GetByTitle(title string) ([]Book, error)
+	GetAll() ([]Book, error)
+	// GetByNaturalKey finds book by ISBN when set, otherwise by exact title
+	GetByNaturalKey(isbn string, title string) (*Book, error)
 	Create(book *Book) (uint, error)
 	Update(book *Book, updates map[string]interface{}) error
 	Delete(id int) error
+	GetDeleted() ([]Book, error)
+	Restore(id int) error
+	// Purge permanently removes books deleted before deletedBefore and returns their IDs, books
+	// with rent history are kept
+	Purge(deletedBefore time.Time) ([]uint, error)
 }
 
 type BookService interface {
 	GetByID(id int) (*Book, error)
+	GetByIDs(ids []int) ([]Book, error)
 	GetByTitle(title string) ([]Book, error)
 	Create(book *Book) (int, error)
+	// UpdateStock corrects stock of book held at default branch only, rejected once stock is split across branches
 	UpdateStock(bookID int, newStock int) (*Book, error)
 	Delete(id int) error
+	ForceDelete(id int, closeAs RentDetailsStatus) error
+	GetDeleted() ([]Book, error)
+	Restore(id int) error
+	PurgeDeleted(retention time.Duration) (int, error)
 }
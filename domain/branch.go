@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Branch is a library location holding its own stock of books,
+// operations which name no branch use the default one of tenant
+type Branch struct {
+	gorm.Model
+	Name      string `gorm:"uniqueIndex:idx_branches_tenant_name,priority:2,where:deleted_at IS NULL" validate:"required"`
+	Address   string
+	IsDefault bool   `gorm:"not null;default:false;uniqueIndex:idx_branches_tenant_default,priority:2,where:is_default AND deleted_at IS NULL"`
+	TenantID  string `gorm:"not null;default:'default';uniqueIndex:idx_branches_tenant_name,priority:1;uniqueIndex:idx_branches_tenant_default,priority:1"` // tenant owning branch, name and default branch are unique per tenant
+}
+
+// BranchStock is stock of book at branch, Book.Stock is the sum over all branches
+type BranchStock struct {
+	BookID   uint `gorm:"primaryKey;autoIncrement:false"`
+	BranchID uint `gorm:"primaryKey;autoIncrement:false"`
+	Stock    int  `gorm:"not null;default:0"`
+	Book     Book
+	Branch   Branch
+	TenantID string `gorm:"not null;default:'default';index"` // tenant of stocked book and branch
+}
+
+type TransferStatus string
+
+const (
+	TransferRequested TransferStatus = "REQUESTED"
+	TransferShipped   TransferStatus = "SHIPPED"
+	TransferReceived  TransferStatus = "RECEIVED"
+	TransferCancelled TransferStatus = "CANCELLED"
+)
+
+// Transfer moves copies between branches, stock leaves source branch when shipped
+// and arrives at target branch when received
+type Transfer struct {
+	gorm.Model
+	BookID       uint           `gorm:"not null;index"`
+	FromBranchID uint           `gorm:"not null;index"`
+	ToBranchID   uint           `gorm:"not null;index"`
+	Quantity     int            `gorm:"not null"`
+	Status       TransferStatus `gorm:"not null;default:REQUESTED"`
+	RequestedBy  string
+	ShippedAt    time.Time
+	ReceivedAt   time.Time
+	Book         Book
+	FromBranch   Branch
+	ToBranch     Branch
+	TenantID     string `gorm:"not null;default:'default';index"` // tenant of transferred book
+}
+
+type BranchRepository interface {
+	GetByID(id int) (*Branch, error)
+	GetAll() ([]Branch, error)
+	// GetDefault returns default branch of tenant, it is created on first use
+	GetDefault() (*Branch, error)
+	Create(branch *Branch) error
+	// GetStock returns NotFound when book was never stocked at branch
+	GetStock(bookID int, branchID int) (*BranchStock, error)
+	SaveStock(stock *BranchStock) error
+	GetAvailability(bookID int) ([]BranchStock, error)
+	GetAvailableBooks(branchID int) ([]BranchStock, error)
+}
+
+type TransferRepository interface {
+	GetByID(id int) (*Transfer, error)
+	// GetByIDForUpdate locks row of transfer until transaction ends, so its status is not changed
+	// concurrently, it must be called inside transaction
+	GetByIDForUpdate(id int) (*Transfer, error)
+	GetByBranch(branchID int) ([]Transfer, error)
+	Create(transfer *Transfer) error
+	Update(transfer *Transfer, updates map[string]interface{}) error
+}
+
+type BranchService interface {
+	GetAll() ([]Branch, error)
+	Create(branch *Branch) error
+	// GetAvailability lists stock of book at every branch holding it
+	GetAvailability(bookID int) ([]BranchStock, error)
+	// GetAvailableBooks lists books in stock at branch
+	GetAvailableBooks(branchID int) ([]BranchStock, error)
+	RequestTransfer(bookID int, fromBranchID int, toBranchID int, quantity int) (*Transfer, error)
+	ShipTransfer(transferID int) error
+	ReceiveTransfer(transferID int) error
+	CancelTransfer(transferID int) error
+	GetTransfers(branchID int) ([]Transfer, error)
+}
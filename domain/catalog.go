@@ -0,0 +1,56 @@
+package domain
+
+import "io"
+
+type CatalogFormat string
+
+const (
+	CSVFormat  CatalogFormat = "csv"
+	JSONFormat CatalogFormat = "json"
+)
+
+// CatalogEntry is a single book of imported or exported catalog
+type CatalogEntry struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Stock   int    `json:"stock"`
+	Price   int    `json:"price"`
+	ISBN    string `json:"isbn,omitempty"`
+}
+
+type ImportMode string
+
+const (
+	// ImportAtomic imports all rows in one transaction, nothing is stored when any row fails
+	ImportAtomic ImportMode = "atomic"
+	// ImportPartial imports every row on its own, failed rows are reported and skipped
+	ImportPartial ImportMode = "partial"
+)
+
+type ImportOptions struct {
+	Format CatalogFormat
+	Mode   ImportMode
+	// DryRun validates and classifies rows without storing anything
+	DryRun bool
+}
+
+// RowError reports failed import row, Row is 1-based position of record in input
+type RowError struct {
+	Row     int    `json:"row"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+type ImportReport struct {
+	DryRun    bool       `json:"dryRun"`
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Failed    int        `json:"failed"`
+	Errors    []RowError `json:"errors"`
+}
+
+type CatalogService interface {
+	Import(r io.Reader, options ImportOptions) (*ImportReport, error)
+	Export(w io.Writer, format CatalogFormat) (int, error)
+}
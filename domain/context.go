@@ -0,0 +1,106 @@
+package domain
+
+import "context"
+
+// Repositories and transactors having WithContext method returning their own interface can be
+// bound to context, their copy bound to ctx traces calls and runs queries under span of ctx.
+// Functions below bind value to ctx, value which cannot be bound, like mock, is returned as is.
+
+func BookRepositoryWithContext(ctx context.Context, repo BookRepository) BookRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) BookRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func UserRepositoryWithContext(ctx context.Context, repo UserRepository) UserRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) UserRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func RentDetailsRepositoryWithContext(ctx context.Context, repo RentDetailsRepository) RentDetailsRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) RentDetailsRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func AuditRepositoryWithContext(ctx context.Context, repo AuditRepository) AuditRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) AuditRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func OutboxRepositoryWithContext(ctx context.Context, repo OutboxRepository) OutboxRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) OutboxRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func StockMovementRepositoryWithContext(ctx context.Context, repo StockMovementRepository) StockMovementRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) StockMovementRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func BranchRepositoryWithContext(ctx context.Context, repo BranchRepository) BranchRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) BranchRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func TransferRepositoryWithContext(ctx context.Context, repo TransferRepository) TransferRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) TransferRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func ReportRepositoryWithContext(ctx context.Context, repo ReportRepository) ReportRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) ReportRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func ReminderRepositoryWithContext(ctx context.Context, repo ReminderRepository) ReminderRepository {
+	if contextual, ok := repo.(interface {
+		WithContext(ctx context.Context) ReminderRepository
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return repo
+}
+
+func TransactorWithContext(ctx context.Context, tx Transactor) Transactor {
+	if contextual, ok := tx.(interface {
+		WithContext(ctx context.Context) Transactor
+	}); ok {
+		return contextual.WithContext(ctx)
+	}
+	return tx
+}
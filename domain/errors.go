@@ -8,6 +8,7 @@ const (
 	InvalidField         RepoErrorType = 2
 	UniqueConstraint     RepoErrorType = 3
 	ForeignKeyConstraint RepoErrorType = 4
+	InvalidTransition    RepoErrorType = 5
 )
 
 type RepoError struct {
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	BookCreated  EventType = "BookCreated"
+	StockChanged EventType = "StockChanged"
+	BookRented   EventType = "BookRented"
+	BookReturned EventType = "BookReturned"
+	RentExpired  EventType = "RentExpired"
+	RentLost     EventType = "RentLost"
+	RentDamaged  EventType = "RentDamaged"
+	UserCreated  EventType = "UserCreated"
+)
+
+type BookCreatedPayload struct {
+	BookID uint   `json:"bookId"`
+	Title  string `json:"title"`
+	Stock  int    `json:"stock"`
+}
+
+type StockChangedPayload struct {
+	BookID   uint         `json:"bookId"`
+	BranchID uint         `json:"branchId"`
+	OldStock int          `json:"oldStock"`
+	NewStock int          `json:"newStock"`
+	Movement MovementKind `json:"movement"`
+	Reason   string       `json:"reason,omitempty"`
+}
+
+type BookRentedPayload struct {
+	RentID         uint      `json:"rentId"`
+	BookID         int       `json:"bookId"`
+	UserID         int       `json:"userId"`
+	ReturnDeadline time.Time `json:"returnDeadline"`
+}
+
+type BookReturnedPayload struct {
+	RentID     uint      `json:"rentId"`
+	BookID     int       `json:"bookId"`
+	UserID     int       `json:"userId"`
+	ReturnedAt time.Time `json:"returnedAt"`
+}
+
+type RentExpiredPayload struct {
+	RentID         uint      `json:"rentId"`
+	BookID         int       `json:"bookId"`
+	UserID         int       `json:"userId"`
+	ReturnDeadline time.Time `json:"returnDeadline"`
+}
+
+// RentClosedPayload is published for rents closed as lost or damaged
+type RentClosedPayload struct {
+	RentID    uint      `json:"rentId"`
+	BookID    int       `json:"bookId"`
+	UserID    int       `json:"userId"`
+	Fee       int       `json:"fee"`
+	Restocked bool      `json:"restocked"`
+	ClosedAt  time.Time `json:"closedAt"`
+}
+
+type UserCreatedPayload struct {
+	UserID uint   `json:"userId"`
+	Email  string `json:"email"`
+}
+
+// OutboxEvent is a domain event stored in the same transaction as the state change
+// and delivered at least once by dispatcher, ID lets consumers drop duplicates
+type OutboxEvent struct {
+	ID             uint            `gorm:"primarykey" json:"id"`
+	Type           EventType       `gorm:"not null;index" json:"type"`
+	Payload        json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	Attempts       int             `gorm:"not null;default:0" json:"-"`
+	NextAttemptAt  time.Time       `gorm:"index" json:"-"`
+	LastError      string          `json:"-"`
+	DispatchedAt   *time.Time      `gorm:"index" json:"-"`
+	DeadLetteredAt *time.Time      `gorm:"index" json:"-"`
+	TenantID       string          `gorm:"not null;default:'default';index" json:"tenantId"` // tenant whose change raised event
+}
+
+type OutboxRepository interface {
+	Create(event *OutboxEvent) error
+	GetPending(now time.Time, limit int) ([]OutboxEvent, error)
+	GetDeadLettered() ([]OutboxEvent, error)
+	MarkDispatched(id uint, at time.Time) error
+	MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkDeadLettered(id uint, at time.Time, lastError string) error
+	Requeue(id uint) error
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+type ReminderKind string
+
+const (
+	DueReminder   ReminderKind = "DUE"
+	OverdueNotice ReminderKind = "OVERDUE"
+)
+
+type Notification struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Send(notification Notification) error
+}
+
+// SentReminder records a reminder sent for rent, unique per rent, kind and days before deadline
+type SentReminder struct {
+	ID         uint         `gorm:"primarykey"`
+	RentID     uint         `gorm:"not null;uniqueIndex:idx_sent_reminders_rent"`
+	Kind       ReminderKind `gorm:"not null;uniqueIndex:idx_sent_reminders_rent"`
+	DaysBefore int          `gorm:"not null;uniqueIndex:idx_sent_reminders_rent"`
+	Email      string
+	SentAt     time.Time
+}
+
+type ReminderRepository interface {
+	Create(reminder *SentReminder) error
+	Delete(id uint) error
+	GetByRent(rentID uint) ([]SentReminder, error)
+}
+
+type ReminderService interface {
+	SendReminders() (int, error)
+}
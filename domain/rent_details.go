@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,6 +13,8 @@ const (
 	RENTED   RentDetailsStatus = 0
 	RETURNED RentDetailsStatus = 1
 	EXPIRED  RentDetailsStatus = 2
+	LOST     RentDetailsStatus = 3
+	DAMAGED  RentDetailsStatus = 4
 )
 
 type RentDetails struct {
@@ -21,6 +24,14 @@ type RentDetails struct {
 	Status         RentDetailsStatus `gorm:"default:0"`
 	ReturnedAt     time.Time
 	ReturnDeadline time.Time
+	ExpiredAt      time.Time
+	LostAt         time.Time
+	DamagedAt      time.Time
+	Fee            int `gorm:"not null;default:0"` // replacement fee charged for lost or damaged book, in cents
+	Note           string
+	BranchID       uint   // branch book was rented from, 0 for default branch
+	ReturnBranchID uint   // branch book was returned to
+	TenantID       string `gorm:"not null;default:'default';index"` // tenant of rented book and user
 	User           User
 	Book           Book
 }
@@ -33,15 +44,33 @@ type RentDetailsRepository interface {
 	GetByUser(userID int) ([]RentDetails, error)
 	GetByBook(bookID int) ([]RentDetails, error)
 	GetByStatus(status RentDetailsStatus) ([]RentDetails, error)
-	RentDetailsIterator(chan RentDetails)
+	GetByStatusAndDeadline(status RentDetailsStatus, from time.Time, to time.Time) ([]RentDetails, error)
+	// RentDetailsIterator sends every rent not returned yet to stream and closes it, it stops early
+	// when ctx is done or reading rents fails and returns why
+	RentDetailsIterator(ctx context.Context, stream chan<- RentDetails) error
 }
 
 type RentDetailsService interface {
 	GetByID(id int) (*RentDetails, error)
 	RentBook(rent *RentDetails) error
-	ReturnBook(rentDetailsID int) error
+	// ReturnBook returns book to branch, 0 returns it to branch it was rented from
+	ReturnBook(rentDetailsID int, branchID int) error
 	GetByUser(userID int) ([]RentDetails, error)
 	GetByBook(bookID int) ([]RentDetails, error)
 	GetByStatus(status RentDetailsStatus) ([]RentDetails, error)
+	StreamActiveRents(fn func(rent RentDetails) error) error
 	UpdateToExpired() error
+	DeclareLost(rentDetailsID int, outcome RentOutcome) error
+	DeclareDamaged(rentDetailsID int, outcome RentOutcome) error
+	UpdateToLost(overdueFor time.Duration) error
+}
+
+// RentOutcome describes how lost or damaged rent is settled
+type RentOutcome struct {
+	// ChargeFee charges replacement price of book to rent
+	ChargeFee bool
+	Note      string
+	// Restock puts damaged copy back on stock when it is still usable,
+	// lost copies are never restocked
+	Restock bool
 }
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// rentTransitions lists allowed target statuses for every status,
+// RETURNED, LOST and DAMAGED are final
+var rentTransitions = map[RentDetailsStatus][]RentDetailsStatus{
+	RENTED:  {RETURNED, EXPIRED, LOST, DAMAGED},
+	EXPIRED: {RETURNED, LOST, DAMAGED},
+}
+
+func (s RentDetailsStatus) String() string {
+	switch s {
+	case RENTED:
+		return "RENTED"
+	case RETURNED:
+		return "RETURNED"
+	case EXPIRED:
+		return "EXPIRED"
+	case LOST:
+		return "LOST"
+	case DAMAGED:
+		return "DAMAGED"
+	}
+	return fmt.Sprintf("RentDetailsStatus(%d)", int(s))
+}
+
+// CanTransitionTo reports whether lifecycle allows moving from s to status
+func (s RentDetailsStatus) CanTransitionTo(status RentDetailsStatus) bool {
+	for _, allowed := range rentTransitions[s] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned for transitions not allowed by rent lifecycle or its guards
+type TransitionError struct {
+	From   RentDetailsStatus
+	To     RentDetailsStatus
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("invalid rent transition %s -> %s: %s", e.From, e.To, e.Reason)
+}
+
+// Transition validates moving rent to status at time now and returns updates to persist,
+// rent itself is left unchanged
+func (rent *RentDetails) Transition(to RentDetailsStatus, now time.Time) (map[string]interface{}, error) {
+	if !rent.Status.CanTransitionTo(to) {
+		return nil, &TransitionError{From: rent.Status, To: to, Reason: "not allowed"}
+	}
+
+	updates := make(map[string]interface{})
+	updates["status"] = to
+	switch to {
+	case RETURNED:
+		updates["returned_at"] = now
+	case EXPIRED:
+		if !rent.ReturnDeadline.Before(now) {
+			return nil, &TransitionError{From: rent.Status, To: to, Reason: "return deadline not passed"}
+		}
+		updates["expired_at"] = now
+	case LOST:
+		updates["lost_at"] = now
+	case DAMAGED:
+		updates["damaged_at"] = now
+	}
+	return updates, nil
+}
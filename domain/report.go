@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// ReportGrouping is period into which report rows are bucketed
+type ReportGrouping string
+
+const (
+	GroupByDay   ReportGrouping = "day"
+	GroupByWeek  ReportGrouping = "week"
+	GroupByMonth ReportGrouping = "month"
+)
+
+// ReportQuery selects rents started in [From, To) bucketed by GroupBy,
+// Limit caps rows per period where report is ranked
+type ReportQuery struct {
+	From    time.Time
+	To      time.Time
+	GroupBy ReportGrouping
+	Limit   int
+}
+
+type BookRentCount struct {
+	Period time.Time `json:"period"`
+	BookID uint      `json:"bookId"`
+	Title  string    `json:"title"`
+	Rents  int64     `json:"rents"`
+}
+
+type OverdueRate struct {
+	Period  time.Time `json:"period"`
+	Rents   int64     `json:"rents"`
+	Overdue int64     `json:"overdue"`
+	Rate    float64   `json:"rate"`
+}
+
+// LoanDuration is bucketed by return date, unlike other reports which use rent start
+type LoanDuration struct {
+	Period      time.Time `json:"period"`
+	Returned    int64     `json:"returned"`
+	AverageDays float64   `json:"averageDays"`
+}
+
+type ActiveBorrowers struct {
+	Period    time.Time `json:"period"`
+	Borrowers int64     `json:"borrowers"`
+	Rents     int64     `json:"rents"`
+}
+
+type ReportRepository interface {
+	MostRentedBooks(query ReportQuery) ([]BookRentCount, error)
+	OverdueRates(query ReportQuery) ([]OverdueRate, error)
+	LoanDurations(query ReportQuery) ([]LoanDuration, error)
+	ActiveBorrowers(query ReportQuery) ([]ActiveBorrowers, error)
+}
+
+type ReportService interface {
+	MostRentedBooks(query ReportQuery) ([]BookRentCount, error)
+	OverdueRates(query ReportQuery) ([]OverdueRate, error)
+	LoanDurations(query ReportQuery) ([]LoanDuration, error)
+	ActiveBorrowers(query ReportQuery) ([]ActiveBorrowers, error)
+}
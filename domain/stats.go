@@ -0,0 +1,23 @@
+package domain
+
+// RentStatusCount is number of rents of tenant in status
+type RentStatusCount struct {
+	TenantID string
+	Status   RentDetailsStatus
+	Rents    int64
+}
+
+// LowStockCount is number of books of tenant at branch whose stock is at or below threshold
+type LowStockCount struct {
+	TenantID string
+	BranchID uint
+	Books    int64
+}
+
+// StatsRepository reads current state of all tenants for runtime metrics
+type StatsRepository interface {
+	RentsByStatus(statuses []RentDetailsStatus) ([]RentStatusCount, error)
+	LowStock(threshold int) ([]LowStockCount, error)
+	// Tenants lists tenants owning any book, user or rent
+	Tenants() ([]string, error)
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+type MovementKind string
+
+const (
+	MovementPurchase   MovementKind = "PURCHASE"
+	MovementWriteOff   MovementKind = "WRITE_OFF"
+	MovementCorrection MovementKind = "CORRECTION"
+	MovementRentOut    MovementKind = "RENT_OUT"
+	MovementReturn     MovementKind = "RETURN"
+	// MovementTransferOut and MovementTransferIn are recorded by branch transfers only
+	MovementTransferOut MovementKind = "TRANSFER_OUT"
+	MovementTransferIn  MovementKind = "TRANSFER_IN"
+)
+
+// StockMovement is ledger entry of book stock, Book.Stock always equals sum of its movements
+type StockMovement struct {
+	ID         uint         `gorm:"primarykey" json:"id"`
+	BookID     uint         `gorm:"not null;index" json:"bookId"`
+	BranchID   uint         `gorm:"index" json:"branchId"`
+	Kind       MovementKind `gorm:"not null" json:"kind"`
+	Quantity   int          `gorm:"not null" json:"quantity"` // signed change of stock
+	Reason     string       `json:"reason"`
+	Actor      string       `gorm:"not null" json:"actor"`
+	RentID     uint         `json:"rentId,omitempty"`     // rent of rent-out and return movements
+	TransferID uint         `json:"transferId,omitempty"` // transfer of transfer movements
+	CreatedAt  time.Time    `gorm:"index" json:"createdAt"`
+	TenantID   string       `gorm:"not null;default:'default';index" json:"-"` // tenant of moved book
+}
+
+type StockMovementRepository interface {
+	Create(movement *StockMovement) error
+	GetByBook(bookID int) ([]StockMovement, error)
+}
+
+type InventoryService interface {
+	// RecordMovement applies manual movement at branch, 0 for default branch, quantity is signed:
+	// positive for purchase, negative for write-off and either for correction
+	RecordMovement(bookID int, branchID int, kind MovementKind, quantity int, reason string) (*StockMovement, error)
+	GetMovements(bookID int) ([]StockMovement, error)
+}
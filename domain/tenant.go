@@ -0,0 +1,26 @@
+package domain
+
+import "context"
+
+// DefaultTenant owns records stored without tenant in context
+const DefaultTenant = "default"
+
+type tenantKey struct{}
+
+// WithTenant returns context scoping repository statements to tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns tenant of context, DefaultTenant when none is set
+func TenantFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+			return tenant
+		}
+	}
+	return DefaultTenant
+}
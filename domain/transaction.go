@@ -0,0 +1,18 @@
+package domain
+
+// Repositories groups repositories bound to the same transaction
+type Repositories struct {
+	Books     BookRepository
+	Users     UserRepository
+	Rents     RentDetailsRepository
+	Audit     AuditRepository
+	Outbox    OutboxRepository
+	Movements StockMovementRepository
+	Branches  BranchRepository
+	Transfers TransferRepository
+}
+
+type Transactor interface {
+	// Transaction runs fn in a single transaction, rolled back when fn returns error
+	Transaction(fn func(repos Repositories) error) error
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type UserType int
 
@@ -13,24 +17,39 @@ type User struct {
 	gorm.Model
 	Firstname string
 	Lastname  string
-	Email     string `gorm:"unique"`
-	Password  string `gorm:"not null"`
+	Email     string `gorm:"uniqueIndex:idx_users_tenant_email,priority:2,where:deleted_at IS NULL"`
+	Password  string `gorm:"not null" audit:"-"`
 	Type      UserType
+	TenantID  string `gorm:"not null;default:'default';uniqueIndex:idx_users_tenant_email,priority:1"` // tenant owning user, email is unique per tenant
 }
 
 type UserRepository interface {
 	GetByID(id int) (*User, error)
+	// GetByIDs returns users with given IDs in no particular order, missing IDs are skipped
+	GetByIDs(ids []int) ([]User, error)
 	GetByEmail(email string) (*User, error)
 	GetByFirstnameAndLastname(firstname string, lastname string) ([]User, error)
+	Search(query string) ([]User, error)
 	Create(user *User) error
 	Update(user *User, updates map[string]interface{}) error
 	Delete(id int) error
+	GetDeleted() ([]User, error)
+	Restore(id int) error
+	// Purge permanently removes users deleted before deletedBefore and returns their IDs, users
+	// with rent history are anonymised instead and returned as well
+	Purge(deletedBefore time.Time) ([]uint, error)
 }
 
 type UserService interface {
 	GetByID(id int) (*User, error)
+	GetByIDs(ids []int) ([]User, error)
 	GetByEmail(email string) (*User, error)
 	GetByFirstnameAndLastname(firstname string, lastname string) ([]User, error)
+	Search(query string) ([]User, error)
 	Create(user *User) error
 	Delete(id int) error
+	ForceDelete(id int, closeAs RentDetailsStatus) error
+	GetDeleted() ([]User, error)
+	Restore(id int) error
+	PurgeDeleted(retention time.Duration) (int, error)
 }
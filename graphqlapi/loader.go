@@ -0,0 +1,140 @@
+package graphqlapi
+
+import (
+	"sync"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+)
+
+// loader fetches values by ID once per request. IDs enqueued by list resolvers are fetched
+// together with first ID loaded afterwards, so nested fields of a list cost one query.
+type loader struct {
+	mu      sync.Mutex
+	fetch   func(ids []int) (map[int]interface{}, error)
+	pending []int
+	values  map[int]interface{}
+	errs    map[int]error
+}
+
+func newLoader(fetch func(ids []int) (map[int]interface{}, error)) *loader {
+	return &loader{fetch: fetch, values: make(map[int]interface{}), errs: make(map[int]error)}
+}
+
+// prime stores value already fetched with its parent
+func (l *loader) prime(id int, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.values[id] = value
+}
+
+// enqueue adds ids to next batch
+func (l *loader) enqueue(ids ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, ids...)
+}
+
+// load returns value of id, fetching it together with all enqueued ids not fetched yet,
+// missing id is NotFound
+func (l *loader) load(id int) (interface{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if value, ok := l.values[id]; ok {
+		return value, nil
+	}
+	if err, ok := l.errs[id]; ok {
+		return nil, err
+	}
+
+	batch := make([]int, 0, len(l.pending)+1)
+	seen := make(map[int]bool, len(l.pending)+1)
+	for _, pending := range append(l.pending, id) {
+		_, loaded := l.values[pending]
+		_, failed := l.errs[pending]
+		if !seen[pending] && !loaded && !failed {
+			seen[pending] = true
+			batch = append(batch, pending)
+		}
+	}
+	l.pending = nil
+
+	values, err := l.fetch(batch)
+	for _, fetched := range batch {
+		if value, ok := values[fetched]; ok && err == nil {
+			l.values[fetched] = value
+		} else if err != nil {
+			l.errs[fetched] = err
+		} else {
+			l.errs[fetched] = &service.ServiceError{Type: service.NotFound}
+		}
+	}
+	if err, ok := l.errs[id]; ok {
+		return nil, err
+	}
+	return l.values[id], nil
+}
+
+// loaders batch book and user lookups of one request
+type loaders struct {
+	books *loader
+	users *loader
+}
+
+func newLoaders(services Services) *loaders {
+	return &loaders{
+		books: newLoader(func(ids []int) (map[int]interface{}, error) {
+			books, err := services.Books.GetByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[int]interface{}, len(books))
+			for _, book := range books {
+				values[int(book.ID)] = book
+			}
+			return values, nil
+		}),
+		users: newLoader(func(ids []int) (map[int]interface{}, error) {
+			users, err := services.Users.GetByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[int]interface{}, len(users))
+			for _, user := range users {
+				values[int(user.ID)] = user
+			}
+			return values, nil
+		})}
+}
+
+func (l *loaders) book(id int) (domain.Book, error) {
+	value, err := l.books.load(id)
+	if err != nil {
+		return domain.Book{}, err
+	}
+	return value.(domain.Book), nil
+}
+
+func (l *loaders) user(id int) (domain.User, error) {
+	value, err := l.users.load(id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return value.(domain.User), nil
+}
+
+// enqueueRents prepares batches of users and books of rents, using associations loaded with them
+func (l *loaders) enqueueRents(rents []domain.RentDetails) {
+	for _, rent := range rents {
+		if rent.Book.ID != 0 {
+			l.books.prime(rent.BookID, rent.Book)
+		} else {
+			l.books.enqueue(rent.BookID)
+		}
+		if rent.User.ID != 0 {
+			l.users.prime(rent.UserID, rent.User)
+		} else {
+			l.users.enqueue(rent.UserID)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package graphqlapi
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+)
+
+// Error is GraphQL error whose extensions carry code, upper case name of service error type
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// toError converts service error to Error, other errors become INTERNAL without leaking their message
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+	serviceErr, ok := err.(*service.ServiceError)
+	if !ok {
+		return &Error{Code: "INTERNAL", Message: "internal error"}
+	}
+	message := serviceErr.Message
+	if message == "" {
+		message = serviceErr.Type.String()
+	}
+	return &Error{Code: strings.ToUpper(serviceErr.Type.String()), Message: message}
+}
+
+func parseID(id graphql.ID) (int, error) {
+	value, err := strconv.Atoi(string(id))
+	if err != nil || value <= 0 {
+		return 0, &Error{Code: strings.ToUpper(service.InvalidArguments.String()), Message: "invalid id " + string(id)}
+	}
+	return value, nil
+}
+
+// parseOptionalID returns 0 for omitted id
+func parseOptionalID(id *graphql.ID) (int, error) {
+	if id == nil {
+		return 0, nil
+	}
+	return parseID(*id)
+}
+
+func toID(id uint) graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(id), 10))
+}
+
+// resolver resolves Query and Mutation using services and loaders of request context
+type resolver struct{}
+
+func (r *resolver) Book(ctx context.Context, args struct{ ID graphql.ID }) (*bookResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	book, err := requestOf(ctx).loaders.book(id)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &bookResolver{book: book}, nil
+}
+
+func (r *resolver) Books(ctx context.Context, args struct{ Title string }) ([]*bookResolver, error) {
+	books, err := requestOf(ctx).services.Books.GetByTitle(args.Title)
+	if err != nil {
+		return nil, toError(err)
+	}
+	resolvers := make([]*bookResolver, 0, len(books))
+	for _, book := range books {
+		resolvers = append(resolvers, &bookResolver{book: book})
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := requestOf(ctx).loaders.user(id)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &userResolver{user: user}, nil
+}
+
+func (r *resolver) Users(ctx context.Context, args struct{ Query string }) ([]*userResolver, error) {
+	users, err := requestOf(ctx).services.Users.Search(args.Query)
+	if err != nil {
+		return nil, toError(err)
+	}
+	resolvers := make([]*userResolver, 0, len(users))
+	for _, user := range users {
+		resolvers = append(resolvers, &userResolver{user: user})
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) Rent(ctx context.Context, args struct{ ID graphql.ID }) (*rentResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	request := requestOf(ctx)
+	rent, err := request.services.Rents.GetByID(id)
+	if err != nil {
+		return nil, toError(err)
+	}
+	request.loaders.enqueueRents([]domain.RentDetails{*rent})
+	return &rentResolver{rent: *rent}, nil
+}
+
+func (r *resolver) RentBook(ctx context.Context, args struct {
+	UserID   graphql.ID
+	BookID   graphql.ID
+	BranchID *graphql.ID
+}) (*rentResolver, error) {
+	userID, err := parseID(args.UserID)
+	if err != nil {
+		return nil, err
+	}
+	bookID, err := parseID(args.BookID)
+	if err != nil {
+		return nil, err
+	}
+	branchID, err := parseOptionalID(args.BranchID)
+	if err != nil {
+		return nil, err
+	}
+
+	rent := domain.RentDetails{UserID: userID, BookID: bookID, BranchID: uint(branchID)}
+	err = requestOf(ctx).services.Rents.RentBook(&rent)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &rentResolver{rent: rent}, nil
+}
+
+func (r *resolver) ReturnBook(ctx context.Context, args struct {
+	RentID   graphql.ID
+	BranchID *graphql.ID
+}) (*rentResolver, error) {
+	rentID, err := parseID(args.RentID)
+	if err != nil {
+		return nil, err
+	}
+	branchID, err := parseOptionalID(args.BranchID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := requestOf(ctx)
+	err = request.services.Rents.ReturnBook(rentID, branchID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	rent, err := request.services.Rents.GetByID(rentID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	request.loaders.enqueueRents([]domain.RentDetails{*rent})
+	return &rentResolver{rent: *rent}, nil
+}
+
+type bookResolver struct {
+	book domain.Book
+}
+
+func (r *bookResolver) ID() graphql.ID {
+	return toID(r.book.ID)
+}
+
+func (r *bookResolver) Title() string {
+	return r.book.Title
+}
+
+func (r *bookResolver) Content() string {
+	return r.book.Content
+}
+
+func (r *bookResolver) Stock() int32 {
+	return int32(r.book.Stock)
+}
+
+func (r *bookResolver) Price() int32 {
+	return int32(r.book.Price)
+}
+
+func (r *bookResolver) Isbn() string {
+	return r.book.ISBN
+}
+
+type userResolver struct {
+	user domain.User
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return toID(r.user.ID)
+}
+
+func (r *userResolver) Firstname() string {
+	return r.user.Firstname
+}
+
+func (r *userResolver) Lastname() string {
+	return r.user.Lastname
+}
+
+func (r *userResolver) Email() string {
+	return r.user.Email
+}
+
+func (r *userResolver) Type() string {
+	if r.user.Type == domain.ADMIN {
+		return "ADMIN"
+	}
+	return "CUSTOMER"
+}
+
+// Rents returns rents of user, all of them when status is omitted
+func (r *userResolver) Rents(ctx context.Context, args struct{ Status *string }) ([]*rentResolver, error) {
+	request := requestOf(ctx)
+	rents, err := request.services.Rents.GetByUser(int(r.user.ID))
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	selected := make([]domain.RentDetails, 0, len(rents))
+	for _, rent := range rents {
+		if args.Status == nil || rent.Status.String() == *args.Status {
+			rent.User = r.user
+			selected = append(selected, rent)
+		}
+	}
+	request.loaders.enqueueRents(selected)
+
+	resolvers := make([]*rentResolver, 0, len(selected))
+	for _, rent := range selected {
+		resolvers = append(resolvers, &rentResolver{rent: rent})
+	}
+	return resolvers, nil
+}
+
+type rentResolver struct {
+	rent domain.RentDetails
+}
+
+func (r *rentResolver) ID() graphql.ID {
+	return toID(r.rent.ID)
+}
+
+func (r *rentResolver) Status() string {
+	return r.rent.Status.String()
+}
+
+func (r *rentResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.rent.CreatedAt}
+}
+
+func (r *rentResolver) ReturnDeadline() graphql.Time {
+	return graphql.Time{Time: r.rent.ReturnDeadline}
+}
+
+func (r *rentResolver) ReturnedAt() *graphql.Time {
+	if r.rent.ReturnedAt.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: r.rent.ReturnedAt}
+}
+
+func (r *rentResolver) Fee() int32 {
+	return int32(r.rent.Fee)
+}
+
+func (r *rentResolver) Note() string {
+	return r.rent.Note
+}
+
+func (r *rentResolver) User(ctx context.Context) (*userResolver, error) {
+	user, err := requestOf(ctx).loaders.user(r.rent.UserID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &userResolver{user: user}, nil
+}
+
+func (r *rentResolver) Book(ctx context.Context) (*bookResolver, error) {
+	book, err := requestOf(ctx).loaders.book(r.rent.BookID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return &bookResolver{book: book}, nil
+}
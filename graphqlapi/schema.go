@@ -0,0 +1,71 @@
+package graphqlapi
+
+// Schema is GraphQL schema served by Handler
+const Schema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+scalar Time
+
+type Query {
+	book(id: ID!): Book!
+	books(title: String!): [Book!]!
+	user(id: ID!): User!
+	users(query: String!): [User!]!
+	rent(id: ID!): Rent!
+}
+
+type Mutation {
+	# rentBook rents book from branch, default branch when branchId is omitted
+	rentBook(userId: ID!, bookId: ID!, branchId: ID): Rent!
+	# returnBook returns book to branch, branch it was rented from when branchId is omitted
+	returnBook(rentId: ID!, branchId: ID): Rent!
+}
+
+type Book {
+	id: ID!
+	title: String!
+	content: String!
+	stock: Int!
+	# replacement price of copy, in cents
+	price: Int!
+	isbn: String!
+}
+
+enum UserType {
+	ADMIN
+	CUSTOMER
+}
+
+type User {
+	id: ID!
+	firstname: String!
+	lastname: String!
+	email: String!
+	type: UserType!
+	rents(status: RentStatus): [Rent!]!
+}
+
+enum RentStatus {
+	RENTED
+	RETURNED
+	EXPIRED
+	LOST
+	DAMAGED
+}
+
+type Rent {
+	id: ID!
+	status: RentStatus!
+	createdAt: Time!
+	returnDeadline: Time!
+	returnedAt: Time
+	# replacement fee charged for lost or damaged book, in cents
+	fee: Int!
+	note: String!
+	user: User!
+	book: Book!
+}
+`
@@ -0,0 +1,93 @@
+// Package graphqlapi serves catalog and rent queries and rent mutations over GraphQL, see Schema.
+// Book and user lookups of one request are batched, so nested fields of lists cost one query.
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/idj1997/book-rent-core/app"
+	"github.com/idj1997/book-rent-core/auth"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/logging"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/tracing"
+)
+
+// Path is path endpoint is served on
+const Path = "/graphql"
+
+// Services are services serving one request
+type Services struct {
+	Books *service.BookService
+	Users *service.UserService
+	Rents *service.RentDetailsService
+}
+
+// Scope returns services bound to request context, which carries its log fields
+type Scope func(ctx context.Context) Services
+
+// AppScope returns services of application traced and logged under request context
+func AppScope(a *app.App) Scope {
+	return func(ctx context.Context) Services {
+		contextApp := a.WithContext(ctx)
+		return Services{Books: contextApp.Books, Users: contextApp.Users, Rents: contextApp.Rents}
+	}
+}
+
+type requestKey struct{}
+
+// request holds services and loaders of one request
+type request struct {
+	services Services
+	loaders  *loaders
+}
+
+func requestOf(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+// Handler serves schema with services of scope to actors, which record changes under actor
+// presenting its bearer token and work with records of its tenant. Requests are logged under
+// their request ID and traced under trace of their traceparent header.
+func Handler(scope Scope, actors map[string]config.ActorConfig) http.Handler {
+	schema := &relay.Handler{Schema: graphql.MustParseSchema(Schema, &resolver{})}
+	return tracing.Middleware(logging.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := auth.Authenticate(r.Context(), r.Header.Get(auth.Header), actors)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		services := scope(ctx)
+		actor := auth.ActorFromContext(ctx)
+		services = Services{
+			Books: services.Books.WithActor(actor),
+			Users: services.Users.WithActor(actor),
+			Rents: services.Rents.WithActor(actor)}
+		ctx = context.WithValue(ctx, requestKey{}, &request{services: services, loaders: newLoaders(services)})
+		schema.ServeHTTP(w, r.WithContext(ctx))
+	})))
+}
+
+// unauthorized rejects request with status 401 and GraphQL error of err
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": err.Error()}}})
+}
+
+// NewServer creates server serving Handler of scope on Path to actors of authCfg, nil when address
+// is empty
+func NewServer(cfg config.GraphQLConfig, authCfg config.AuthConfig, scope Scope) *http.Server {
+	if cfg.Address == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler(scope, authCfg.Actors))
+	return &http.Server{Addr: cfg.Address, Handler: mux}
+}
@@ -0,0 +1,59 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/idj1997/book-rent-core/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is domain of ErrorInfo detail attached to service errors
+const ErrorDomain = "bookrent"
+
+// Code returns gRPC code of service error type
+func Code(t service.ServiceErrorType) codes.Code {
+	switch t {
+	case service.NotFound:
+		return codes.NotFound
+	case service.AlreadyExist:
+		return codes.AlreadyExists
+	case service.InvalidArguments:
+		return codes.InvalidArgument
+	case service.NotEnoughBooksOnStock, service.BookAlreadyReturned, service.ActiveBookRents, service.InvalidStatusTransition:
+		return codes.FailedPrecondition
+	}
+	return codes.Unknown
+}
+
+// Status converts service error to status error with code of its type and ErrorInfo detail whose
+// reason is upper case type name, status errors are returned as they are, context errors become
+// Canceled or DeadlineExceeded and other errors become Internal without leaking their message
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+	serviceErr, ok := err.(*service.ServiceError)
+	if !ok {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return status.FromContextError(err).Err()
+		}
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	message := serviceErr.Message
+	if message == "" {
+		message = serviceErr.Type.String()
+	}
+	st, detailErr := status.New(Code(serviceErr.Type), message).
+		WithDetails(&errdetails.ErrorInfo{Reason: strings.ToUpper(serviceErr.Type.String()), Domain: ErrorDomain})
+	if detailErr != nil {
+		return status.Error(Code(serviceErr.Type), message)
+	}
+	return st.Err()
+}
@@ -0,0 +1,177 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/idj1997/book-rent-core/auth"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/logging"
+	"github.com/idj1997/book-rent-core/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizationHeader is metadata key of auth.Header
+const AuthorizationHeader = "authorization"
+
+// authenticate returns ctx carrying actor whose token is presented in incoming metadata
+func authenticate(ctx context.Context, actors map[string]config.ActorConfig) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := ""
+	if values := md.Get(AuthorizationHeader); len(values) > 0 {
+		authorization = values[0]
+	}
+	ctx, err := auth.Authenticate(ctx, authorization, actors)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return ctx, nil
+}
+
+// AuthUnaryInterceptor rejects calls without token of one of actors
+func AuthUnaryInterceptor(actors map[string]config.ActorConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, actors)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor is AuthUnaryInterceptor of streaming calls
+func AuthStreamInterceptor(actors map[string]config.ActorConfig) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), actors)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// withRequestID returns ctx carrying log entry with request ID given by caller or new one,
+// the ID is echoed in response header
+func withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := ""
+	if values := md.Get(logging.RequestIDHeader); len(values) > 0 {
+		requestID = values[0]
+	}
+	if requestID == "" {
+		requestID = logging.NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(logging.RequestIDHeader, requestID))
+	return logging.WithRequestID(ctx, requestID)
+}
+
+// logCall logs finished call at info level, at warn when rejected and at error when it failed
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	entry := logging.FromContext(ctx).WithFields(log.Fields{
+		"grpc_method": method,
+		"grpc_code":   code.String(),
+		"duration_ms": float64(time.Since(start).Nanoseconds()) / 1e6})
+	switch code {
+	case codes.OK:
+		entry.Info("grpc call finished")
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		entry.WithError(err).Error("grpc call failed")
+	default:
+		entry.WithError(err).Warn("grpc call rejected")
+	}
+}
+
+// LoggingUnaryInterceptor logs every call with its request ID, which services log with as well
+func LoggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx = withRequestID(ctx)
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// LoggingStreamInterceptor is LoggingUnaryInterceptor of streaming calls
+func LoggingStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withRequestID(stream.Context())
+	err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// metadataCarrier reads and writes trace context of call in its metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startCallSpan starts server span of method continuing trace of traceparent in incoming metadata
+func startCallSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = tracing.Extract(ctx, metadataCarrier(md))
+	ctx, span := tracing.Start(ctx, strings.TrimPrefix(method, "/"), trace.WithSpanKind(trace.SpanKindServer))
+	span.SetAttributes(attribute.String("rpc.system", "grpc"), attribute.String("rpc.method", method))
+	return ctx, span
+}
+
+// endCallSpan ends span of call, failed when call failed on server side
+func endCallSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TracingUnaryInterceptor traces every call as server span, child of span in its traceparent metadata
+func TracingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startCallSpan(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	endCallSpan(span, err)
+	return resp, err
+}
+
+// TracingStreamInterceptor is TracingUnaryInterceptor of streaming calls
+func TracingStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startCallSpan(stream.Context(), info.FullMethod)
+	err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	endCallSpan(span, err)
+	return err
+}
+
+// contextStream replaces context of server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
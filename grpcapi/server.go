@@ -0,0 +1,282 @@
+// Package grpcapi serves book, user and rent services over gRPC, see pb/bookrent.proto.
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/idj1997/book-rent-core/app"
+	"github.com/idj1997/book-rent-core/auth"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/grpcapi/pb"
+	"github.com/idj1997/book-rent-core/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Services are services serving one call
+type Services struct {
+	Books *service.BookService
+	Users *service.UserService
+	Rents *service.RentDetailsService
+}
+
+// Scope returns services bound to call context, which carries its log fields
+type Scope func(ctx context.Context) Services
+
+// AppScope returns services of application traced and logged under call context
+func AppScope(a *app.App) Scope {
+	return func(ctx context.Context) Services {
+		contextApp := a.WithContext(ctx)
+		return Services{Books: contextApp.Books, Users: contextApp.Users, Rents: contextApp.Rents}
+	}
+}
+
+// NewServer creates server of scope authenticating actors of authCfg, nil when address is empty
+func NewServer(cfg config.GRPCConfig, authCfg config.AuthConfig, scope Scope) *grpc.Server {
+	if cfg.Address == "" {
+		return nil
+	}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(TracingUnaryInterceptor, LoggingUnaryInterceptor, AuthUnaryInterceptor(authCfg.Actors)),
+		grpc.ChainStreamInterceptor(TracingStreamInterceptor, LoggingStreamInterceptor, AuthStreamInterceptor(authCfg.Actors)))
+	Register(server, scope)
+	return server
+}
+
+// Register registers book, user and rent services of scope in server
+func Register(server *grpc.Server, scope Scope) {
+	pb.RegisterBookServiceServer(server, &bookServer{scope: scope})
+	pb.RegisterUserServiceServer(server, &userServer{scope: scope})
+	pb.RegisterRentDetailsServiceServer(server, &rentServer{scope: scope})
+}
+
+// services returns services of ctx recording changes under authenticated actor
+func (s Scope) services(ctx context.Context) Services {
+	services := s(ctx)
+	actor := auth.ActorFromContext(ctx)
+	return Services{
+		Books: services.Books.WithActor(actor),
+		Users: services.Users.WithActor(actor),
+		Rents: services.Rents.WithActor(actor)}
+}
+
+type bookServer struct {
+	pb.UnimplementedBookServiceServer
+	scope Scope
+}
+
+func (s *bookServer) GetBook(ctx context.Context, req *pb.GetBookRequest) (*pb.Book, error) {
+	book, err := s.scope.services(ctx).Books.GetByID(int(req.Id))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toBook(book), nil
+}
+
+func (s *bookServer) SearchBooks(ctx context.Context, req *pb.SearchBooksRequest) (*pb.SearchBooksResponse, error) {
+	books, err := s.scope.services(ctx).Books.GetByTitle(req.Title)
+	if err != nil {
+		return nil, Status(err)
+	}
+	resp := &pb.SearchBooksResponse{Books: make([]*pb.Book, 0, len(books))}
+	for i := range books {
+		resp.Books = append(resp.Books, toBook(&books[i]))
+	}
+	return resp, nil
+}
+
+func (s *bookServer) CreateBook(ctx context.Context, req *pb.CreateBookRequest) (*pb.Book, error) {
+	if req.Book == nil {
+		return nil, status.Error(codes.InvalidArgument, "book is required")
+	}
+	book := domain.Book{
+		Title:   req.Book.Title,
+		Content: req.Book.Content,
+		Stock:   int(req.Book.Stock),
+		Price:   int(req.Book.Price),
+		ISBN:    req.Book.Isbn}
+	id, err := s.scope.services(ctx).Books.Create(&book)
+	if err != nil {
+		return nil, Status(err)
+	}
+	book.ID = uint(id)
+	return toBook(&book), nil
+}
+
+func (s *bookServer) UpdateStock(ctx context.Context, req *pb.UpdateStockRequest) (*pb.Book, error) {
+	book, err := s.scope.services(ctx).Books.UpdateStock(int(req.BookId), int(req.Stock))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toBook(book), nil
+}
+
+func (s *bookServer) DeleteBook(ctx context.Context, req *pb.DeleteBookRequest) (*pb.DeleteBookResponse, error) {
+	err := s.scope.services(ctx).Books.Delete(int(req.Id))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return &pb.DeleteBookResponse{}, nil
+}
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	scope Scope
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	user, err := s.scope.services(ctx).Users.GetByID(int(req.Id))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toUser(user), nil
+}
+
+func (s *userServer) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.SearchUsersResponse, error) {
+	users, err := s.scope.services(ctx).Users.Search(req.Query)
+	if err != nil {
+		return nil, Status(err)
+	}
+	resp := &pb.SearchUsersResponse{Users: make([]*pb.User, 0, len(users))}
+	for i := range users {
+		resp.Users = append(resp.Users, toUser(&users[i]))
+	}
+	return resp, nil
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	if _, known := pb.UserType_name[int32(req.Type)]; !known || req.Type == pb.UserType_USER_TYPE_UNSPECIFIED {
+		return nil, status.Error(codes.InvalidArgument, "user type must be USER_TYPE_ADMIN or USER_TYPE_CUSTOMER")
+	}
+	user := domain.User{
+		Firstname: req.Firstname,
+		Lastname:  req.Lastname,
+		Email:     req.Email,
+		Password:  req.Password,
+		Type:      domain.UserType(req.Type - 1)}
+	err := s.scope.services(ctx).Users.Create(&user)
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toUser(&user), nil
+}
+
+func (s *userServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	err := s.scope.services(ctx).Users.Delete(int(req.Id))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return &pb.DeleteUserResponse{}, nil
+}
+
+type rentServer struct {
+	pb.UnimplementedRentDetailsServiceServer
+	scope Scope
+}
+
+func (s *rentServer) GetRent(ctx context.Context, req *pb.GetRentRequest) (*pb.Rent, error) {
+	rent, err := s.scope.services(ctx).Rents.GetByID(int(req.Id))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toRent(rent), nil
+}
+
+func (s *rentServer) ListUserRents(ctx context.Context, req *pb.ListUserRentsRequest) (*pb.ListRentsResponse, error) {
+	rents, err := s.scope.services(ctx).Rents.GetByUser(int(req.UserId))
+	if err != nil {
+		return nil, Status(err)
+	}
+	resp := &pb.ListRentsResponse{Rents: make([]*pb.Rent, 0, len(rents))}
+	for i := range rents {
+		resp.Rents = append(resp.Rents, toRent(&rents[i]))
+	}
+	return resp, nil
+}
+
+func (s *rentServer) RentBook(ctx context.Context, req *pb.RentBookRequest) (*pb.Rent, error) {
+	rent := domain.RentDetails{UserID: int(req.UserId), BookID: int(req.BookId), BranchID: uint(req.BranchId)}
+	err := s.scope.services(ctx).Rents.RentBook(&rent)
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toRent(&rent), nil
+}
+
+func (s *rentServer) ReturnBook(ctx context.Context, req *pb.ReturnBookRequest) (*pb.Rent, error) {
+	rents := s.scope.services(ctx).Rents
+	err := rents.ReturnBook(int(req.RentId), int(req.BranchId))
+	if err != nil {
+		return nil, Status(err)
+	}
+	rent, err := rents.GetByID(int(req.RentId))
+	if err != nil {
+		return nil, Status(err)
+	}
+	return toRent(rent), nil
+}
+
+// StreamActiveRents stops at first failed send or once client cancels, as services of scope are bound
+// to stream context
+func (s *rentServer) StreamActiveRents(req *pb.StreamActiveRentsRequest, stream pb.RentDetailsService_StreamActiveRentsServer) error {
+	err := s.scope.services(stream.Context()).Rents.StreamActiveRents(func(rent domain.RentDetails) error {
+		return stream.Send(toRent(&rent))
+	})
+	return Status(err)
+}
+
+func toBook(book *domain.Book) *pb.Book {
+	return &pb.Book{
+		Id:      uint32(book.ID),
+		Title:   book.Title,
+		Content: book.Content,
+		Stock:   int32(book.Stock),
+		Price:   int32(book.Price),
+		Isbn:    book.ISBN}
+}
+
+func toUser(user *domain.User) *pb.User {
+	return &pb.User{
+		Id:        uint32(user.ID),
+		Firstname: user.Firstname,
+		Lastname:  user.Lastname,
+		Email:     user.Email,
+		Type:      toUserType(user.Type)}
+}
+
+func toRent(rent *domain.RentDetails) *pb.Rent {
+	return &pb.Rent{
+		Id:             uint32(rent.ID),
+		UserId:         uint32(rent.UserID),
+		BookId:         uint32(rent.BookID),
+		Status:         toRentStatus(rent.Status),
+		CreatedAt:      timestamp(rent.CreatedAt),
+		ReturnDeadline: timestamp(rent.ReturnDeadline),
+		ReturnedAt:     timestamp(rent.ReturnedAt),
+		Fee:            int32(rent.Fee),
+		Note:           rent.Note,
+		BranchId:       uint32(rent.BranchID),
+		ReturnBranchId: uint32(rent.ReturnBranchID)}
+}
+
+// toUserType shifts domain user type past UNSPECIFIED, which holds zero value of pb enum
+func toUserType(t domain.UserType) pb.UserType {
+	return pb.UserType(t + 1)
+}
+
+// toRentStatus shifts domain rent status past UNSPECIFIED, which holds zero value of pb enum
+func toRentStatus(s domain.RentDetailsStatus) pb.RentStatus {
+	return pb.RentStatus(s + 1)
+}
+
+// timestamp leaves zero time unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/idj1997/book-rent-core/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const megabyte = 1024 * 1024
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Configure applies logging config to logger, returned closer releases log file
+func Configure(logger *log.Logger, cfg config.LoggingConfig) (io.Closer, error) {
+	level := log.InfoLevel
+	if cfg.Level != "" {
+		var err error
+		level, err = log.ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid logging.level: %w", err)
+		}
+	}
+
+	var formatter log.Formatter
+	switch cfg.Format {
+	case "", "json":
+		formatter = &log.JSONFormatter{}
+	case "text":
+		formatter = &log.TextFormatter{FullTimestamp: true}
+	default:
+		return nil, fmt.Errorf("invalid logging.format: %q", cfg.Format)
+	}
+
+	var output io.Writer = os.Stdout
+	var closer io.Closer = nopCloser{}
+	switch cfg.OutputType {
+	case "console":
+	case "file", "both":
+		rotation := cfg.Rotation
+		file, err := OpenRotatingFile(cfg.FilePath, int64(rotation.MaxSizeMB)*megabyte,
+			rotation.Interval, rotation.MaxAge, rotation.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		output, closer = file, file
+		if cfg.OutputType == "both" {
+			output = io.MultiWriter(os.Stdout, file)
+		}
+	default:
+		return nil, fmt.Errorf("invalid logging.outputType: %q", cfg.OutputType)
+	}
+
+	logger.SetLevel(level)
+	logger.SetFormatter(formatter)
+	logger.SetReportCaller(cfg.ReportCaller)
+	logger.SetOutput(output)
+	return closer, nil
+}
@@ -0,0 +1,73 @@
+// Package logging configures logrus from logging config and carries request scoped log fields.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Fields attached to entries of one request or operation
+const (
+	RequestIDField = "request_id"
+	UserIDField    = "user_id"
+	BookIDField    = "book_id"
+	RentIDField    = "rent_id"
+)
+
+// RequestIDHeader carries request ID given by caller, new one is generated when missing
+const RequestIDHeader = "X-Request-ID"
+
+type entryKey struct{}
+
+// NewContext returns ctx carrying entry
+func NewContext(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns entry carried by ctx, entry of standard logger when there is none
+func FromContext(ctx context.Context) *log.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*log.Entry); ok {
+		return entry
+	}
+	return log.NewEntry(log.StandardLogger())
+}
+
+// WithFields returns ctx whose entry has fields added
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithFields(fields))
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithFields(ctx, log.Fields{RequestIDField: requestID})
+}
+
+func WithUserID(ctx context.Context, userID uint) context.Context {
+	return WithFields(ctx, log.Fields{UserIDField: userID})
+}
+
+func WithRentID(ctx context.Context, rentID uint) context.Context {
+	return WithFields(ctx, log.Fields{RentIDField: rentID})
+}
+
+// NewRequestID returns random 16 byte hex ID
+func NewRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Middleware puts entry with request ID into request context and echoes the ID in response header
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), requestID)))
+	})
+}
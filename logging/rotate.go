@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files so that they sort by rotation time
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile appends to log file at Path and renames it to timestamped backup once it
+// outgrows MaxSize or is older than Interval, zero value of either disables that rotation.
+// Backups beyond MaxBackups or older than MaxAge are removed, zero keeps them.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	Interval   time.Duration
+	MaxAge     time.Duration
+	MaxBackups int
+	// Now returns current time, replaced in tests
+	Now func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// OpenRotatingFile opens rotating log file at path, creating it when missing
+func OpenRotatingFile(path string, maxSize int64, interval time.Duration, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		Path:       path,
+		MaxSize:    maxSize,
+		Interval:   interval,
+		MaxAge:     maxAge,
+		MaxBackups: maxBackups,
+		Now:        time.Now}
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.due(int64(len(p))) {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate moves current file to backup and continues in new file
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// due reports whether file must be rotated before writing next bytes,
+// empty file is never rotated
+func (f *RotatingFile) due(next int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.MaxSize > 0 && f.size+next > f.MaxSize {
+		return true
+	}
+	return f.Interval > 0 && !f.Now().Before(f.openedAt.Add(f.Interval))
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error while opening log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error while opening log file: %w", err)
+	}
+
+	f.file, f.size, f.openedAt = file, info.Size(), f.Now()
+	// file left by previous run keeps its period
+	if f.size > 0 && info.ModTime().Before(f.openedAt) {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		err := f.file.Close()
+		f.file = nil
+		if err != nil {
+			return fmt.Errorf("error while closing log file: %w", err)
+		}
+	}
+
+	err := os.Rename(f.Path, f.backupPath(f.Now()))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error while rotating log file: %w", err)
+	}
+	err = f.open()
+	if err != nil {
+		return err
+	}
+	return f.prune()
+}
+
+func (f *RotatingFile) backupPath(at time.Time) string {
+	ext := filepath.Ext(f.Path)
+	return strings.TrimSuffix(f.Path, ext) + "-" + at.UTC().Format(backupTimeFormat) + ext
+}
+
+// rotatedAt parses rotation time from backup path, false when path is not backup of log file
+func (f *RotatingFile) rotatedAt(path string) (time.Time, bool) {
+	ext := filepath.Ext(f.Path)
+	prefix := strings.TrimSuffix(f.Path, ext) + "-"
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, ext) {
+		return time.Time{}, false
+	}
+	at, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(path, prefix), ext))
+	return at, err == nil
+}
+
+// Backups returns rotated files of log file ordered from oldest
+func (f *RotatingFile) Backups() ([]string, error) {
+	ext := filepath.Ext(f.Path)
+	matches, err := filepath.Glob(strings.TrimSuffix(f.Path, ext) + "-*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if _, ok := f.rotatedAt(match); ok {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (f *RotatingFile) prune() error {
+	if f.MaxBackups <= 0 && f.MaxAge <= 0 {
+		return nil
+	}
+	backups, err := f.Backups()
+	if err != nil {
+		return fmt.Errorf("error while listing log backups: %w", err)
+	}
+
+	for i, backup := range backups {
+		remove := f.MaxBackups > 0 && i < len(backups)-f.MaxBackups
+		if !remove && f.MaxAge > 0 {
+			rotatedAt, _ := f.rotatedAt(backup)
+			remove = f.Now().Sub(rotatedAt) > f.MaxAge
+		}
+		if remove {
+			err = os.Remove(backup)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error while removing log backup: %w", err)
+			}
+		}
+	}
+	return nil
+}
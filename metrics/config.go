@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Path is path of metrics endpoint
+	Path = "/metrics"
+	// LivenessPath is path of liveness probe served next to metrics
+	LivenessPath = "/healthz"
+	// ReadinessPath is path of readiness probe served next to metrics
+	ReadinessPath = "/readyz"
+)
+
+// NewServer creates server of gatherer and probes of health listening on configured address,
+// probes are not served when health is nil, nil when address is empty
+func NewServer(cfg config.MetricsConfig, gatherer prometheus.Gatherer, health *config.HealthChecker) *http.Server {
+	if cfg.Address == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{ErrorLog: log.StandardLogger()}))
+	if health != nil {
+		mux.Handle(LivenessPath, health.LivenessHandler())
+		mux.Handle(ReadinessPath, health.ReadinessHandler())
+	}
+	return &http.Server{Addr: cfg.Address, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// QueryMetricsPluginName is the name QueryMetrics is registered under in gorm plugins
+const QueryMetricsPluginName = "book-rent:metrics"
+
+const queryStartKey = "metrics:start"
+
+// QueryMetrics is gorm plugin timing repository queries by table, operation and outcome
+type QueryMetrics struct {
+	Durations *prometheus.HistogramVec
+}
+
+// NewQueryMetrics creates query metrics registered in registerer, db.Use installs them
+func NewQueryMetrics(registerer prometheus.Registerer) (*QueryMetrics, error) {
+	m := &QueryMetrics{Durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of database queries by table, operation and outcome."}, []string{"table", "operation", "outcome"})}
+	err := registerer.Register(m.Durations)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *QueryMetrics) Name() string {
+	return QueryMetricsPluginName
+}
+
+type registerFunc func(name string, fn func(*gorm.DB)) error
+
+func (m *QueryMetrics) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	operations := []struct {
+		name          string
+		before, after registerFunc
+	}{
+		{"create", callback.Create().Before("gorm:create").Register, callback.Create().After("gorm:create").Register},
+		{"query", callback.Query().Before("gorm:query").Register, callback.Query().After("gorm:query").Register},
+		{"update", callback.Update().Before("gorm:update").Register, callback.Update().After("gorm:update").Register},
+		{"delete", callback.Delete().Before("gorm:delete").Register, callback.Delete().After("gorm:delete").Register},
+		{"row", callback.Row().Before("gorm:row").Register, callback.Row().After("gorm:row").Register},
+		{"raw", callback.Raw().Before("gorm:raw").Register, callback.Raw().After("gorm:raw").Register}}
+
+	for _, operation := range operations {
+		err := operation.before("metrics:start_"+operation.name, startQuery)
+		if err != nil {
+			return err
+		}
+		err = operation.after("metrics:observe_"+operation.name, m.observe(operation.name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (m *QueryMetrics) observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "raw"
+		}
+		outcome := "ok"
+		if db.Error != nil {
+			outcome = "error"
+		}
+		observe(m.Durations, time.Since(value.(time.Time)).Seconds(), table, operation, outcome)
+	}
+}
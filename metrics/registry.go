@@ -0,0 +1,38 @@
+// Package metrics exports service, query and stats metrics to Prometheus with client_golang.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Namespace prefixes names of all exported metrics
+const Namespace = "bookrent"
+
+// NewRegistry creates registry of application metrics, unlike default registry it holds no
+// process and Go runtime collectors, so every App exports its own metrics
+func NewRegistry() *prometheus.Registry {
+	return prometheus.NewRegistry()
+}
+
+// observe records value in observer of label values of vec, mismatching label values are logged
+// instead of panicking
+func observe(vec *prometheus.HistogramVec, value float64, labelValues ...string) {
+	observer, err := vec.GetMetricWithLabelValues(labelValues...)
+	if err != nil {
+		log.Warnf("error while observing metric: %v", err)
+		return
+	}
+	observer.Observe(value)
+}
+
+// add adds value to counter of label values of vec, mismatching label values are logged
+// instead of panicking
+func add(vec *prometheus.CounterVec, value float64, labelValues ...string) {
+	counter, err := vec.GetMetricWithLabelValues(labelValues...)
+	if err != nil {
+		log.Warnf("error while counting metric: %v", err)
+		return
+	}
+	counter.Add(value)
+}
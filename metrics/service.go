@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ServiceMetrics counts and times service method calls and job runs, it is service.Observer
+type ServiceMetrics struct {
+	Calls     *prometheus.CounterVec
+	Durations *prometheus.HistogramVec
+	JobRuns   *prometheus.CounterVec
+	JobItems  *prometheus.CounterVec
+}
+
+// NewServiceMetrics creates service metrics registered in registerer
+func NewServiceMetrics(registerer prometheus.Registerer) (*ServiceMetrics, error) {
+	m := &ServiceMetrics{
+		Calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "service_calls_total",
+			Help:      "Service method calls by outcome."}, []string{"service", "method", "outcome"}),
+		Durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "service_call_duration_seconds",
+			Help:      "Latency of service method calls by outcome."}, []string{"service", "method", "outcome"}),
+		JobRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "job_runs_total",
+			Help:      "Runs of scheduled jobs by outcome."}, []string{"job", "outcome"}),
+		JobItems: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "job_items_total",
+			Help:      "Rents expired, declared lost or reminded by scheduled jobs."}, []string{"job"})}
+	for _, collector := range []prometheus.Collector{m.Calls, m.Durations, m.JobRuns, m.JobItems} {
+		err := registerer.Register(collector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *ServiceMetrics) ObserveCall(service string, method string, outcome string, duration time.Duration) {
+	add(m.Calls, 1, service, method, outcome)
+	observe(m.Durations, duration.Seconds(), service, method, outcome)
+}
+
+func (m *ServiceMetrics) ObserveJob(job string, outcome string, processed int) {
+	add(m.JobRuns, 1, job, outcome)
+	add(m.JobItems, float64(processed), job)
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/idj1997/book-rent-core/cache"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// ActiveRentStatuses are statuses counted by active rents gauge
+var ActiveRentStatuses = []domain.RentDetailsStatus{domain.RENTED, domain.EXPIRED}
+
+var (
+	activeRentsDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "active_rents"),
+		"Rents not returned yet by tenant and status.", []string{"tenant", "status"}, nil)
+	lowStockBooksDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "low_stock_books"),
+		"Books at or below low stock threshold by tenant and branch.", []string{"tenant", "branch"}, nil)
+	lowStockThresholdDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "low_stock_threshold"),
+		"Stock at or below which book counts as low on stock.", nil, nil)
+	cacheHitsDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "cache", "hits_total"),
+		"Cache hits by tenant and cached entity.", []string{"tenant", "entity"}, nil)
+	cacheMissesDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "cache", "misses_total"),
+		"Cache misses by tenant and cached entity.", []string{"tenant", "entity"}, nil)
+)
+
+// StatsCollector is prometheus.Collector querying active rents and low stock of all tenants and
+// reading cache hits and misses of every tenant on every scrape
+type StatsCollector struct {
+	Repo domain.StatsRepository
+	// LowStockThreshold is stock at or below which book counts as low on stock
+	LowStockThreshold int
+	// Caches are caches of tenants, nil when cache is disabled
+	Caches *cache.Tenants
+}
+
+func (c *StatsCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- activeRentsDesc
+	descs <- lowStockBooksDesc
+	descs <- lowStockThresholdDesc
+	descs <- cacheHitsDesc
+	descs <- cacheMissesDesc
+}
+
+func (c *StatsCollector) Collect(metrics chan<- prometheus.Metric) {
+	counts, err := c.Repo.RentsByStatus(ActiveRentStatuses)
+	if err != domain.NilRepoErrPtr {
+		log.Warnf("error while collecting active rents: %v", err)
+	}
+	for _, count := range counts {
+		metrics <- gauge(activeRentsDesc, float64(count.Rents), count.TenantID, count.Status.String())
+	}
+
+	stocks, err := c.Repo.LowStock(c.LowStockThreshold)
+	if err != domain.NilRepoErrPtr {
+		log.Warnf("error while collecting low stock: %v", err)
+	}
+	for _, stock := range stocks {
+		metrics <- gauge(lowStockBooksDesc, float64(stock.Books), stock.TenantID, strconv.FormatUint(uint64(stock.BranchID), 10))
+	}
+
+	metrics <- gauge(lowStockThresholdDesc, float64(c.LowStockThreshold))
+
+	if c.Caches == nil {
+		return
+	}
+	for tenant, tenantCache := range c.Caches.Caches() {
+		for entity, stats := range map[string]cache.Stats{"book": tenantCache.BookStats(), "user": tenantCache.UserStats()} {
+			metrics <- constMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits), tenant, entity)
+			metrics <- constMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses), tenant, entity)
+		}
+	}
+}
+
+// gauge returns gauge of desc and label values, invalid metric failing the scrape instead of panicking
+// when label values do not match desc
+func gauge(desc *prometheus.Desc, value float64, labelValues ...string) prometheus.Metric {
+	return constMetric(desc, prometheus.GaugeValue, value, labelValues...)
+}
+
+// constMetric returns metric of desc, value type and label values, invalid metric when they do not match
+func constMetric(desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labelValues ...string) prometheus.Metric {
+	metric, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	return metric
+}
@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// NewNotifier creates notifier selected by reminders config
+func NewNotifier(cfg config.RemindersConfig) (domain.Notifier, error) {
+	switch cfg.Notifier {
+	case "smtp":
+		return &SMTPNotifier{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From}, nil
+	case "file":
+		return NewFileNotifier(cfg.FilePath), nil
+	case "log":
+		return &LogNotifier{}, nil
+	}
+	return nil, fmt.Errorf("invalid reminders.notifier: %q", cfg.Notifier)
+}
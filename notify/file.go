@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/idj1997/book-rent-core/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// FileNotifier appends notifications as json lines to file instead of sending them,
+// used for tests and local development
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{Path: path}
+}
+
+func (n *FileNotifier) Send(notification domain.Notification) error {
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LogNotifier only logs notifications
+type LogNotifier struct{}
+
+func (n *LogNotifier) Send(notification domain.Notification) error {
+	log.WithField("to", notification.To).
+		WithField("subject", notification.Subject).
+		Info("notification sent")
+	return nil
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (n *SMTPNotifier) Send(notification domain.Notification) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	message, err := n.Message(notification)
+	if err != nil {
+		return err
+	}
+	address := fmt.Sprintf("%s:%d", n.Host, n.Port)
+	return smtp.SendMail(address, auth, n.From, []string{notification.To}, message)
+}
+
+// Message renders notification as mail, addresses containing line breaks are rejected so they
+// cannot inject headers, subject is stripped of line breaks and RFC 2047 encoded
+func (n *SMTPNotifier) Message(notification domain.Notification) ([]byte, error) {
+	if strings.ContainsAny(n.From, "\r\n") {
+		return nil, fmt.Errorf("sender address %q contains line break", n.From)
+	}
+	if strings.ContainsAny(notification.To, "\r\n") {
+		return nil, fmt.Errorf("recipient address %q contains line break", notification.To)
+	}
+	subject := strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(notification.Subject)
+
+	var message strings.Builder
+	message.WriteString("From: " + n.From + "\r\n")
+	message.WriteString("To: " + notification.To + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(notification.Body)
+	return []byte(message.String()), nil
+}
@@ -0,0 +1,23 @@
+package outbox
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// NewDispatcher creates dispatcher from outbox config, file and http sinks
+// are added when configured after the given sinks
+func NewDispatcher(repo domain.OutboxRepository, cfg config.OutboxConfig, sinks ...Sink) *Dispatcher {
+	if cfg.Sinks.File != "" {
+		sinks = append(sinks, NewFileSink(cfg.Sinks.File))
+	}
+	if cfg.Sinks.HTTP != "" {
+		sinks = append(sinks, NewHTTPSink(cfg.Sinks.HTTP))
+	}
+
+	return &Dispatcher{
+		Repo:        repo,
+		Sinks:       sinks,
+		BatchSize:   cfg.BatchSize,
+		MaxAttempts: cfg.MaxAttempts}
+}
@@ -0,0 +1,120 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// Sink receives outbox events, delivery must be idempotent on event ID
+// because events are redelivered until every sink accepts them
+type Sink interface {
+	Deliver(event domain.OutboxEvent) error
+}
+
+type Dispatcher struct {
+	Repo        domain.OutboxRepository
+	Sinks       []Sink
+	BatchSize   int
+	MaxAttempts int
+	// Backoff returns delay before next delivery attempt, defaults to ExponentialBackoff
+	Backoff func(attempts int) time.Duration
+}
+
+// ExponentialBackoff doubles delay with every failed attempt starting at one second, capped at one hour
+func ExponentialBackoff(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// Dispatch delivers one batch of pending events, returns number of delivered events
+func (d *Dispatcher) Dispatch() (int, error) {
+	now := time.Now()
+	events, err := d.Repo.GetPending(now, d.batchSize())
+	if err != domain.NilRepoErrPtr {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, event := range events {
+		deliveryErr := d.deliver(event)
+		if deliveryErr == nil {
+			err = d.Repo.MarkDispatched(event.ID, time.Now())
+			if err != domain.NilRepoErrPtr {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		attempts := event.Attempts + 1
+		if attempts >= d.maxAttempts() {
+			log.Errorf("outbox event %d (%s) dead lettered after %d attempts: %v", event.ID, event.Type, attempts, deliveryErr)
+			err = d.Repo.MarkDeadLettered(event.ID, time.Now(), deliveryErr.Error())
+		} else {
+			log.Warnf("outbox event %d (%s) delivery attempt %d failed: %v", event.ID, event.Type, attempts, deliveryErr)
+			err = d.Repo.MarkFailed(event.ID, attempts, time.Now().Add(d.backoff(attempts)), deliveryErr.Error())
+		}
+		if err != domain.NilRepoErrPtr {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// Run dispatches pending events every interval until stop is closed
+func (d *Dispatcher) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := d.Dispatch()
+		if err != nil {
+			log.Errorf("error while dispatching outbox events: %v", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(event domain.OutboxEvent) error {
+	for _, sink := range d.Sinks {
+		err := sink.Deliver(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) batchSize() int {
+	if d.BatchSize <= 0 {
+		return 100
+	}
+	return d.BatchSize
+}
+
+func (d *Dispatcher) maxAttempts() int {
+	if d.MaxAttempts <= 0 {
+		return 10
+	}
+	return d.MaxAttempts
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	if d.Backoff == nil {
+		return ExponentialBackoff(attempts)
+	}
+	return d.Backoff(attempts)
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+type Handler func(event domain.OutboxEvent) error
+
+// HandlerSink delivers events to in-process handlers subscribed by event type
+type HandlerSink struct {
+	mu       sync.RWMutex
+	handlers map[domain.EventType][]Handler
+}
+
+func NewHandlerSink() *HandlerSink {
+	return &HandlerSink{handlers: make(map[domain.EventType][]Handler)}
+}
+
+func (s *HandlerSink) Subscribe(eventType domain.EventType, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[eventType] = append(s.handlers[eventType], handler)
+}
+
+func (s *HandlerSink) Deliver(event domain.OutboxEvent) error {
+	s.mu.RLock()
+	handlers := s.handlers[event.Type]
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		err := handler(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileSink appends every event as a single json line to file
+type FileSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{Path: path}
+}
+
+func (s *FileSink) Deliver(event domain.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// HTTPSink posts every event as json to URL, any non 2xx response is a failed delivery
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{URL: url, Client: http.DefaultClient}
+}
+
+func (s *HTTPSink) Deliver(event domain.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Idempotency-Key", fmt.Sprint(event.ID))
+
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("sink %s responded with status %d", s.URL, response.StatusCode)
+	}
+	return nil
+}
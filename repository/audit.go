@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+type GormAuditRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormAuditRepository(db *gorm.DB) *GormAuditRepository {
+	return &GormAuditRepository{Db: db}
+}
+
+// WithContext returns repository running queries under span of ctx
+func (repo *GormAuditRepository) WithContext(ctx context.Context) domain.AuditRepository {
+	return NewGormAuditRepository(withSpan(repo.Db, ctx))
+}
+
+func (repo *GormAuditRepository) Create(record *domain.AuditRecord) error {
+	err := repo.Db.Create(record).Error
+	return ErrorToRepoError(err)
+}
+
+func (repo *GormAuditRepository) Find(filter domain.AuditFilter) ([]domain.AuditRecord, error) {
+	var records []domain.AuditRecord
+	query := repo.Db.Order("created_at, id")
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	err := query.Find(&records).Error
+	return records, ErrorToRepoError(err)
+}
@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/idj1997/book-rent-core/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GormBookRepository struct {
@@ -13,18 +17,53 @@ func NewGormBookRepository(db *gorm.DB) *GormBookRepository {
 	return &GormBookRepository{Db: db}
 }
 
+// WithContext returns repository running queries under span of ctx
+func (repo *GormBookRepository) WithContext(ctx context.Context) domain.BookRepository {
+	return NewGormBookRepository(withSpan(repo.Db, ctx))
+}
+
 func (repo *GormBookRepository) GetByID(id int) (*domain.Book, error) {
 	var book domain.Book
 	err := repo.Db.First(&book, id).Error
 	return &book, ErrorToRepoError(err)
 }
 
+func (repo *GormBookRepository) GetByIDForUpdate(id int) (*domain.Book, error) {
+	var book domain.Book
+	err := repo.Db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, id).Error
+	return &book, ErrorToRepoError(err)
+}
+
+func (repo *GormBookRepository) GetByIDs(ids []int) ([]domain.Book, error) {
+	var books []domain.Book
+	err := repo.Db.Where("id IN ?", ids).Find(&books).Error
+	return books, ErrorToRepoError(err)
+}
+
 func (repo *GormBookRepository) GetByTitle(title string) ([]domain.Book, error) {
 	var books []domain.Book
 	err := repo.Db.Where("title LIKE ?", "%"+title+"%").Find(&books).Error
 	return books, ErrorToRepoError(err)
 }
 
+func (repo *GormBookRepository) GetAll() ([]domain.Book, error) {
+	var books []domain.Book
+	err := repo.Db.Order("id").Find(&books).Error
+	return books, ErrorToRepoError(err)
+}
+
+func (repo *GormBookRepository) GetByNaturalKey(isbn string, title string) (*domain.Book, error) {
+	var book domain.Book
+	query := repo.Db.Order("id")
+	if isbn != "" {
+		query = query.Where("isbn = ?", isbn)
+	} else {
+		query = query.Where("title = ?", title)
+	}
+	err := query.First(&book).Error
+	return &book, ErrorToRepoError(err)
+}
+
 func (repo *GormBookRepository) Create(book *domain.Book) (uint, error) {
 	err := repo.Db.Create(book).Error
 	return book.ID, ErrorToRepoError(err)
@@ -39,3 +78,19 @@ func (repo *GormBookRepository) Delete(id int) error {
 	err := repo.Db.Delete(&domain.Book{}, id).Error
 	return ErrorToRepoError(err)
 }
+
+func (repo *GormBookRepository) GetDeleted() ([]domain.Book, error) {
+	var books []domain.Book
+	err := repo.Db.Unscoped().Where("deleted_at IS NOT NULL").Find(&books).Error
+	return books, ErrorToRepoError(err)
+}
+
+func (repo *GormBookRepository) Restore(id int) error {
+	return restore(repo.Db, &domain.Book{}, id)
+}
+
+// Purge permanently removes books deleted before deletedBefore, books with rent history are kept
+// on purpose, rents reference their book and book holds no personal data
+func (repo *GormBookRepository) Purge(deletedBefore time.Time) ([]uint, error) {
+	return purge(repo.Db, "books", "book_id", deletedBefore)
+}
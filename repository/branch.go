@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormBranchRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormBranchRepository(db *gorm.DB) *GormBranchRepository {
+	return &GormBranchRepository{Db: db}
+}
+
+// WithContext returns repository running queries under span of ctx
+func (repo *GormBranchRepository) WithContext(ctx context.Context) domain.BranchRepository {
+	return NewGormBranchRepository(withSpan(repo.Db, ctx))
+}
+
+func (repo *GormBranchRepository) GetByID(id int) (*domain.Branch, error) {
+	var branch domain.Branch
+	err := repo.Db.First(&branch, id).Error
+	return &branch, ErrorToRepoError(err)
+}
+
+func (repo *GormBranchRepository) GetAll() ([]domain.Branch, error) {
+	var branches []domain.Branch
+	err := repo.Db.Order("id").Find(&branches).Error
+	return branches, ErrorToRepoError(err)
+}
+
+// GetDefault returns default branch of tenant, main branch is created for tenant without one
+func (repo *GormBranchRepository) GetDefault() (*domain.Branch, error) {
+	var branch domain.Branch
+	err := repo.Db.Where("is_default").First(&branch).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &branch, ErrorToRepoError(err)
+	}
+
+	// concurrent first use creates one branch, the other insert hits default index and does nothing
+	branch = domain.Branch{Name: "main", IsDefault: true}
+	err = repo.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&branch).Error
+	if err != nil {
+		return &branch, ErrorToRepoError(err)
+	}
+	branch = domain.Branch{}
+	err = ReadPrimary(repo.Db).Where("is_default").First(&branch).Error
+	return &branch, ErrorToRepoError(err)
+}
+
+func (repo *GormBranchRepository) Create(branch *domain.Branch) error {
+	err := repo.Db.Create(branch).Error
+	return ErrorToRepoError(err)
+}
+
+func (repo *GormBranchRepository) GetStock(bookID int, branchID int) (*domain.BranchStock, error) {
+	var stock domain.BranchStock
+	err := repo.Db.
+		Where("book_id = ? AND branch_id = ?", bookID, branchID).
+		First(&stock).Error
+	return &stock, ErrorToRepoError(err)
+}
+
+// SaveStock inserts stock of book at branch or overwrites existing one
+func (repo *GormBranchRepository) SaveStock(stock *domain.BranchStock) error {
+	err := repo.Db.
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "book_id"}, {Name: "branch_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"stock"})}).
+		Create(stock).Error
+	return ErrorToRepoError(err)
+}
+
+func (repo *GormBranchRepository) GetAvailability(bookID int) ([]domain.BranchStock, error) {
+	var stocks []domain.BranchStock
+	err := repo.Db.
+		Joins("Branch").
+		Where("book_id = ? AND stock > 0", bookID).
+		Order("branch_id").
+		Find(&stocks).Error
+	return stocks, ErrorToRepoError(err)
+}
+
+func (repo *GormBranchRepository) GetAvailableBooks(branchID int) ([]domain.BranchStock, error) {
+	var stocks []domain.BranchStock
+	err := repo.Db.
+		Joins("Book").
+		Where("branch_stocks.branch_id = ? AND branch_stocks.stock > 0", branchID).
+		Order("branch_stocks.book_id").
+		Find(&stocks).Error
+	return stocks, ErrorToRepoError(err)
+}
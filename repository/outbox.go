@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+type GormOutboxRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormOutboxRepository(db *gorm.DB) *GormOutboxRepository {
+	return &GormOutboxRepository{Db: db}
+}
+
+// WithContext returns repository running queries under span of ctx
+func (repo *GormOutboxRepository) WithContext(ctx context.Context) domain.OutboxRepository {
+	return NewGormOutboxRepository(withSpan(repo.Db, ctx))
+}
+
+func (repo *GormOutboxRepository) Create(event *domain.OutboxEvent) error {
+	err := repo.Db.Create(event).Error
+	return ErrorToRepoError(err)
+}
+
+// GetPending returns undelivered events due for (re)delivery, oldest first
+func (repo *GormOutboxRepository) GetPending(now time.Time, limit int) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	err := repo.Db.
+		Where("dispatched_at IS NULL AND dead_lettered_at IS NULL").
+		Where("next_attempt_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	return events, ErrorToRepoError(err)
+}
+
+func (repo *GormOutboxRepository) GetDeadLettered() ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	err := repo.Db.
+		Where("dead_lettered_at IS NOT NULL").
+		Order("id").
+		Find(&events).Error
+	return events, ErrorToRepoError(err)
+}
+
+func (repo *GormOutboxRepository) MarkDispatched(id uint, at time.Time) error {
+	return repo.update(id, map[string]interface{}{"dispatched_at": at})
+}
+
+func (repo *GormOutboxRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return repo.update(id, map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError})
+}
+
+func (repo *GormOutboxRepository) MarkDeadLettered(id uint, at time.Time, lastError string) error {
+	return repo.update(id, map[string]interface{}{
+		"dead_lettered_at": at,
+		"last_error":       lastError})
+}
+
+// Requeue moves dead lettered event back to pending with fresh attempts
+func (repo *GormOutboxRepository) Requeue(id uint) error {
+	return repo.update(id, map[string]interface{}{
+		"dead_lettered_at": nil,
+		"attempts":         0,
+		"next_attempt_at":  time.Now()})
+}
+
+func (repo *GormOutboxRepository) update(id uint, updates map[string]interface{}) error {
+	result := repo.Db.Model(&domain.OutboxEvent{}).Where("id = ?", id).Updates(updates)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrorToRepoError(gorm.ErrRecordNotFound)
+	}
+	return ErrorToRepoError(result.Error)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+type GormReminderRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormReminderRepository(db *gorm.DB) *GormReminderRepository {
+	return &GormReminderRepository{Db: db}
+}
+
+// WithContext returns repository running queries under span of ctx
+func (repo *GormReminderRepository) WithContext(ctx context.Context) domain.ReminderRepository {
+	return NewGormReminderRepository(withSpan(repo.Db, ctx))
+}
+
+// Create fails with UniqueConstraint when the same reminder was already recorded
+func (repo *GormReminderRepository) Create(reminder *domain.SentReminder) error {
+	err := repo.Db.Create(reminder).Error
+	return ErrorToRepoError(err)
+}
+
+func (repo *GormReminderRepository) Delete(id uint) error {
+	err := repo.Db.Delete(&domain.SentReminder{}, id).Error
+	return ErrorToRepoError(err)
+}
+
+func (repo *GormReminderRepository) GetByRent(rentID uint) ([]domain.SentReminder, error) {
+	var reminders []domain.SentReminder
+	err := repo.Db.Where("rent_id = ?", rentID).Order("sent_at").Find(&reminders).Error
+	return reminders, ErrorToRepoError(err)
+}
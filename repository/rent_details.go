@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/idj1997/book-rent-core/domain"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -10,6 +14,11 @@ type GormRentDetailsRepository struct {
 	Db *gorm.DB
 }
 
+// WithContext returns repository running queries under span of ctx
+func (g *GormRentDetailsRepository) WithContext(ctx context.Context) domain.RentDetailsRepository {
+	return &GormRentDetailsRepository{Db: withSpan(g.Db, ctx)}
+}
+
 func (g *GormRentDetailsRepository) GetByID(id int) (*domain.RentDetails, error) {
 	var rent domain.RentDetails
 	err := g.Db.
@@ -23,27 +32,54 @@ func (g *GormRentDetailsRepository) Create(rent *domain.RentDetails) error {
 	return ErrorToRepoError(err)
 }
 
+// Update applies updates to rent, status changes apply only while stored rent still has status of
+// rent, otherwise InvalidTransition is returned, so concurrent or stale transitions never overwrite
 func (g *GormRentDetailsRepository) Update(rent *domain.RentDetails, updates map[string]interface{}) error {
-	err := g.Db.
-		Model(rent).               // specify model on which to perform updates
-		Omit(clause.Associations). // discard associations updates in rent pointer
-		Updates(updates).          // perform updates
-		Error
-	return ErrorToRepoError(err)
+	return g.update(g.Db, rent, updates)
 }
 
 // UpdateAssociations function will insert updated associations into rent pointer
 func (g *GormRentDetailsRepository) UpdateAssociations(rent *domain.RentDetails, updates map[string]interface{}) error {
-	err := g.Db.
-		Model(rent).
-		Omit(clause.Associations).
-		Updates(updates).
+	// fetch own update from primary before replicas catch up
+	db := ReadPrimary(g.Db)
+	err := g.update(db, rent, updates)
+	if err != domain.NilRepoErrPtr {
+		return err
+	}
+
+	err = db.
 		Preload(clause.Associations). // select nested associations in rent
 		First(rent, rent.ID).         // fetch
 		Error
 	return ErrorToRepoError(err)
 }
 
+func (g *GormRentDetailsRepository) update(db *gorm.DB, rent *domain.RentDetails, updates map[string]interface{}) error {
+	from := rent.Status
+	changesStatus, transitionErr := checkTransition(rent, updates)
+	if transitionErr != nil {
+		return transitionErr
+	}
+
+	db = db.
+		Model(rent).              // specify model on which to perform updates
+		Omit(clause.Associations) // discard associations updates in rent pointer
+	if changesStatus {
+		db = db.Where("status = ?", from)
+	}
+	result := db.Updates(updates) // perform updates
+	if result.Error != nil {
+		return ErrorToRepoError(result.Error)
+	}
+	if changesStatus && result.RowsAffected == 0 {
+		rent.Status = from
+		return &domain.RepoError{
+			Type:    domain.InvalidTransition,
+			Message: fmt.Sprintf("rent %d is no longer %s", rent.ID, from)}
+	}
+	return domain.NilRepoErrPtr
+}
+
 func (g *GormRentDetailsRepository) GetByUser(userID int) ([]domain.RentDetails, error) {
 	var rents []domain.RentDetails
 	err := g.Db.
@@ -71,20 +107,72 @@ func (g *GormRentDetailsRepository) GetByStatus(status domain.RentDetailsStatus)
 	return rents, ErrorToRepoError(err)
 }
 
-func (g *GormRentDetailsRepository) RentDetailsIterator(stream chan domain.RentDetails) {
-	// close channel
+// GetByStatusAndDeadline returns rents with deadline in (from, to] together with user and book
+func (g *GormRentDetailsRepository) GetByStatusAndDeadline(status domain.RentDetailsStatus, from time.Time, to time.Time) ([]domain.RentDetails, error) {
+	var rents []domain.RentDetails
+	err := g.Db.
+		Preload(clause.Associations).
+		Where("status = ?", status).
+		Where("return_deadline > ? AND return_deadline <= ?", from, to).
+		Order("return_deadline").
+		Find(&rents).
+		Error
+	return rents, ErrorToRepoError(err)
+}
+
+func (g *GormRentDetailsRepository) RentDetailsIterator(ctx context.Context, stream chan<- domain.RentDetails) error {
 	defer close(stream)
 
-	// create rows struct
-	rows, _ := g.Db.Model(&domain.RentDetails{}).
+	rows, err := g.Db.Model(&domain.RentDetails{}).
 		Where("status != ?", domain.RETURNED).
 		Rows()
+	if err != nil {
+		return ErrorToRepoError(err)
+	}
 	defer rows.Close()
 
-	// iterate and stream results to channel
 	for rows.Next() {
 		var rent domain.RentDetails
-		g.Db.ScanRows(rows, &rent)
-		stream <- rent
+		err = g.Db.ScanRows(rows, &rent)
+		if err != nil {
+			return ErrorToRepoError(err)
+		}
+		select {
+		case stream <- rent:
+		case <-ctx.Done():
+			return &domain.RepoError{Type: domain.Unknown, Message: ctx.Err().Error()}
+		}
+	}
+	return ErrorToRepoError(rows.Err())
+}
+
+// checkTransition rejects status updates not allowed by rent lifecycle,
+// reports whether updates change status
+func checkTransition(rent *domain.RentDetails, updates map[string]interface{}) (bool, *domain.RepoError) {
+	changesStatus := false
+	for _, key := range []string{"status", "Status"} {
+		value, ok := updates[key]
+		if !ok {
+			continue
+		}
+
+		var status domain.RentDetailsStatus
+		switch v := value.(type) {
+		case domain.RentDetailsStatus:
+			status = v
+		case int:
+			status = domain.RentDetailsStatus(v)
+		default:
+			return false, &domain.RepoError{Type: domain.InvalidField, Message: "invalid status value"}
+		}
+
+		if status != rent.Status {
+			if !rent.Status.CanTransitionTo(status) {
+				transitionErr := domain.TransitionError{From: rent.Status, To: status, Reason: "not allowed"}
+				return false, &domain.RepoError{Type: domain.InvalidTransition, Message: transitionErr.Error()}
+			}
+			changesStatus = true
+		}
 	}
+	return changesStatus, nil
 }
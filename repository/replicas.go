@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// ReplicasPluginName is the name Replicas is registered under in gorm plugins
+const ReplicasPluginName = "book-rent:replicas"
+
+type readPrimaryKey struct{}
+
+// Replicas is gorm plugin routing reads outside transactions to replica pools in round robin.
+// Writes, transactions and reads of ReadPrimary db go to primary.
+type Replicas struct {
+	Pools []gorm.ConnPool
+	next  uint32
+}
+
+// ReadPrimary returns db whose reads go to primary, for reading own writes before replicas catch up
+func ReadPrimary(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, readPrimaryKey{}, true))
+}
+
+func (r *Replicas) Name() string {
+	return ReplicasPluginName
+}
+
+func (r *Replicas) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	err := callback.Query().Before("gorm:query").Register("replicas:query", r.routeRead)
+	if err != nil {
+		return err
+	}
+	err = callback.Row().Before("gorm:row").Register("replicas:row", r.routeRead)
+	if err != nil {
+		return err
+	}
+
+	// statement reused after read keeps replica pool, writes are sent back to primary
+	err = callback.Create().Before("gorm:create").Register("replicas:create", r.routeWrite)
+	if err != nil {
+		return err
+	}
+	err = callback.Update().Before("gorm:update").Register("replicas:update", r.routeWrite)
+	if err != nil {
+		return err
+	}
+	err = callback.Delete().Before("gorm:delete").Register("replicas:delete", r.routeWrite)
+	if err != nil {
+		return err
+	}
+	return callback.Raw().Before("gorm:raw").Register("replicas:raw", r.routeWrite)
+}
+
+// Close closes replica pools opened as *sql.DB, all of them even when one fails, returns first error
+func (r *Replicas) Close() error {
+	var first error
+	for _, pool := range r.Pools {
+		if sqlDB, ok := pool.(*sql.DB); ok {
+			err := sqlDB.Close()
+			if err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (r *Replicas) routeRead(db *gorm.DB) {
+	if len(r.Pools) == 0 || readsPrimary(db) {
+		return
+	}
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return
+	}
+
+	i := atomic.AddUint32(&r.next, 1)
+	db.Statement.ConnPool = r.Pools[int(i)%len(r.Pools)]
+}
+
+func (r *Replicas) routeWrite(db *gorm.DB) {
+	if r.isReplica(db.Statement.ConnPool) {
+		db.Statement.ConnPool = db.Config.ConnPool
+	}
+}
+
+func (r *Replicas) isReplica(pool gorm.ConnPool) bool {
+	for _, replica := range r.Pools {
+		if pool == replica {
+			return true
+		}
+	}
+	return false
+}
+
+func readsPrimary(db *gorm.DB) bool {
+	readPrimary, _ := db.Statement.Context.Value(readPrimaryKey{}).(bool)
+	return readPrimary
+}
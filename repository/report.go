@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+// GormReportRepository aggregates rent history of tenant, rents of soft deleted books and users are kept
+type GormReportRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormReportRepository(db *gorm.DB) *GormReportRepository {
+	return &GormReportRepository{Db: db}
+}
+
+// WithContext returns repository running queries under span of ctx
+func (repo *GormReportRepository) WithContext(ctx context.Context) domain.ReportRepository {
+	return NewGormReportRepository(withSpan(repo.Db, ctx))
+}
+
+func (repo *GormReportRepository) MostRentedBooks(query domain.ReportQuery) ([]domain.BookRentCount, error) {
+	var counts []domain.BookRentCount
+	err := repo.Db.Raw(`
+		SELECT period, book_id, title, rents FROM (
+			SELECT counts.*, row_number() OVER (PARTITION BY period ORDER BY rents DESC, book_id) AS rank
+			FROM (
+				SELECT date_trunc(?, rent_details.created_at) AS period, rent_details.book_id, books.title, count(*) AS rents
+				FROM rent_details
+				JOIN books ON books.id = rent_details.book_id
+				WHERE rent_details.deleted_at IS NULL AND rent_details.tenant_id = ?
+				AND rent_details.created_at >= ? AND rent_details.created_at < ?
+				GROUP BY period, rent_details.book_id, books.title
+			) counts
+		) ranked
+		WHERE rank <= ?
+		ORDER BY period, rank`,
+		string(query.GroupBy), tenantOf(repo.Db), query.From, query.To, query.Limit).
+		Scan(&counts).Error
+	return counts, ErrorToRepoError(err)
+}
+
+// OverdueRates counts rents which expired, were lost or returned after deadline
+func (repo *GormReportRepository) OverdueRates(query domain.ReportQuery) ([]domain.OverdueRate, error) {
+	var rates []domain.OverdueRate
+	err := repo.Db.
+		Model(&domain.RentDetails{}).
+		Select("date_trunc(?, created_at) AS period, count(*) AS rents, "+
+			"count(*) FILTER (WHERE status IN ? OR returned_at > return_deadline) AS overdue",
+			string(query.GroupBy), []domain.RentDetailsStatus{domain.EXPIRED, domain.LOST}).
+		Where("created_at >= ? AND created_at < ?", query.From, query.To).
+		Group("period").
+		Order("period").
+		Scan(&rates).Error
+	return rates, ErrorToRepoError(err)
+}
+
+func (repo *GormReportRepository) LoanDurations(query domain.ReportQuery) ([]domain.LoanDuration, error) {
+	var durations []domain.LoanDuration
+	err := repo.Db.
+		Model(&domain.RentDetails{}).
+		Select("date_trunc(?, returned_at) AS period, count(*) AS returned, "+
+			"avg(extract(epoch FROM returned_at - created_at)) / 86400 AS average_days",
+			string(query.GroupBy)).
+		Where("status = ? AND returned_at >= ? AND returned_at < ?", domain.RETURNED, query.From, query.To).
+		Group("period").
+		Order("period").
+		Scan(&durations).Error
+	return durations, ErrorToRepoError(err)
+}
+
+func (repo *GormReportRepository) ActiveBorrowers(query domain.ReportQuery) ([]domain.ActiveBorrowers, error) {
+	var borrowers []domain.ActiveBorrowers
+	err := repo.Db.
+		Model(&domain.RentDetails{}).
+		Joins("JOIN users ON users.id = rent_details.user_id").
+		Select("date_trunc(?, rent_details.created_at) AS period, count(DISTINCT users.id) AS borrowers, count(*) AS rents",
+			string(query.GroupBy)).
+		Where("rent_details.created_at >= ? AND rent_details.created_at < ?", query.From, query.To).
+		Group("period").
+		Order("period").
+		Scan(&borrowers).Error
+	return borrowers, ErrorToRepoError(err)
+}
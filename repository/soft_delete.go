@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+// restore clears deleted_at of soft deleted record, NotFound if record is not deleted
+func restore(db *gorm.DB, model interface{}, id int) *domain.RepoError {
+	result := db.Unscoped().
+		Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrorToRepoError(gorm.ErrRecordNotFound)
+	}
+	return ErrorToRepoError(result.Error)
+}
+
+// purge permanently removes records of tenant soft deleted before deletedBefore and returns their IDs,
+// records still referenced by rent history through rentColumn are kept
+func purge(db *gorm.DB, table string, rentColumn string, deletedBefore time.Time) ([]uint, *domain.RepoError) {
+	var ids []uint
+	statement := fmt.Sprintf(`DELETE FROM %[1]s
+		WHERE tenant_id = ? AND deleted_at IS NOT NULL AND deleted_at < ?
+		AND NOT EXISTS (SELECT 1 FROM rent_details WHERE rent_details.%[2]s = %[1]s.id)
+		RETURNING id`, table, rentColumn)
+	err := ReadPrimary(db).Raw(statement, tenantOf(db), deletedBefore).Scan(&ids).Error
+	return ids, ErrorToRepoError(err)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+// GormStatsRepository counts rows of all tenants, raw queries are not tenant scoped
+type GormStatsRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormStatsRepository(db *gorm.DB) *GormStatsRepository {
+	return &GormStatsRepository{Db: db}
+}
+
+func (repo *GormStatsRepository) RentsByStatus(statuses []domain.RentDetailsStatus) ([]domain.RentStatusCount, error) {
+	var counts []domain.RentStatusCount
+	err := repo.Db.Raw(`
+		SELECT tenant_id, status, count(*) AS rents
+		FROM rent_details
+		WHERE deleted_at IS NULL AND status IN ?
+		GROUP BY tenant_id, status
+		ORDER BY tenant_id, status`,
+		statuses).
+		Scan(&counts).Error
+	return counts, ErrorToRepoError(err)
+}
+
+func (repo *GormStatsRepository) LowStock(threshold int) ([]domain.LowStockCount, error) {
+	var counts []domain.LowStockCount
+	err := repo.Db.Raw(`
+		SELECT books.tenant_id, branch_stocks.branch_id, count(*) AS books
+		FROM branch_stocks
+		JOIN books ON books.id = branch_stocks.book_id
+		JOIN branches ON branches.id = branch_stocks.branch_id
+		WHERE books.deleted_at IS NULL AND branches.deleted_at IS NULL AND branch_stocks.stock <= ?
+		GROUP BY books.tenant_id, branch_stocks.branch_id
+		ORDER BY books.tenant_id, branch_stocks.branch_id`,
+		threshold).
+		Scan(&counts).Error
+	return counts, ErrorToRepoError(err)
+}
+
+func (repo *GormStatsRepository) Tenants() ([]string, error) {
+	var tenants []string
+	err := repo.Db.Raw(`
+		SELECT tenant_id FROM books
+		UNION SELECT tenant_id FROM users
+		UNION SELECT tenant_id FROM rent_details
+		ORDER BY tenant_id`).
+		Scan(&tenants).Error
+	return tenants, ErrorToRepoError(err)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+type GormStockMovementRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormStockMovementRepository(db *gorm.DB) *GormStockMovementRepository {
+	return &GormStockMovementRepository{Db: db}
+}
+
+// WithContext returns repository running queries under span of ctx
+func (repo *GormStockMovementRepository) WithContext(ctx context.Context) domain.StockMovementRepository {
+	return NewGormStockMovementRepository(withSpan(repo.Db, ctx))
+}
+
+func (repo *GormStockMovementRepository) Create(movement *domain.StockMovement) error {
+	err := repo.Db.Create(movement).Error
+	return ErrorToRepoError(err)
+}
+
+func (repo *GormStockMovementRepository) GetByBook(bookID int) ([]domain.StockMovement, error) {
+	var movements []domain.StockMovement
+	err := repo.Db.
+		Where("book_id = ?", bookID).
+		Order("created_at, id").
+		Find(&movements).Error
+	return movements, ErrorToRepoError(err)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// tenantField is the field of models owned by tenant
+const tenantField = "TenantID"
+
+// ForTenant returns db whose statements are scoped to tenant
+func ForTenant(db *gorm.DB, tenant string) *gorm.DB {
+	return db.WithContext(domain.WithTenant(db.Statement.Context, tenant))
+}
+
+// withSpan returns db whose statements belong to span of ctx, tenant, routing and cancellation
+// of db are kept, so binding repository to span of a call never changes tenant it works for
+func withSpan(db *gorm.DB, ctx context.Context) *gorm.DB {
+	return db.WithContext(trace.ContextWithSpan(db.Statement.Context, trace.SpanFromContext(ctx)))
+}
+
+type allTenantsKey struct{}
+
+// AllTenants returns db whose queries, updates and deletes are not scoped to tenant, for workers
+// processing records of every tenant. Created records are still stamped with tenant of context.
+func AllTenants(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, allTenantsKey{}, true))
+}
+
+// RegisterTenantScope registers callbacks stamping created tenant owned records with tenant of
+// statement context and restricting queries, updates and deletes of them, including tenant owned
+// joined associations, to that tenant. Soft delete scope is bypassed by Unscoped, tenant scope is not.
+func RegisterTenantScope(db *gorm.DB) error {
+	callback := db.Callback()
+	err := callback.Create().Before("gorm:create").Register("tenant:create", stampTenant)
+	if err != nil {
+		return err
+	}
+	err = callback.Query().Before("gorm:query").Register("tenant:query", scopeTenant)
+	if err != nil {
+		return err
+	}
+	err = callback.Update().Before("gorm:update").Register("tenant:update", scopeTenant)
+	if err != nil {
+		return err
+	}
+	err = callback.Delete().Before("gorm:delete").Register("tenant:delete", scopeTenant)
+	if err != nil {
+		return err
+	}
+	return callback.Row().Before("gorm:row").Register("tenant:row", scopeTenant)
+}
+
+// tenantOf returns tenant statements of db are scoped to, for raw SQL not covered by callbacks
+func tenantOf(db *gorm.DB) string {
+	return domain.TenantFromContext(db.Statement.Context)
+}
+
+func stampTenant(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	tenant := tenantOf(db)
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			_ = db.AddError(field.Set(value.Index(i), tenant))
+		}
+	case reflect.Struct:
+		_ = db.AddError(field.Set(value, tenant))
+	}
+}
+
+func scopeTenant(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+	if all, _ := db.Statement.Context.Value(allTenantsKey{}).(bool); all {
+		return
+	}
+
+	tenant := tenantOf(db)
+	conditions := make([]clause.Expression, 0, 1)
+	if field := db.Statement.Schema.LookUpField(tenantField); field != nil {
+		conditions = append(conditions, clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+			Value:  tenant})
+	}
+	for _, join := range db.Statement.Joins {
+		if field := joinedTenantField(db.Statement.Schema, join.Name); field != nil {
+			conditions = append(conditions, clause.Eq{
+				Column: clause.Column{Table: join.Name, Name: field.DBName},
+				Value:  tenant})
+		}
+	}
+	if len(conditions) > 0 {
+		db.Statement.AddClause(clause.Where{Exprs: conditions})
+	}
+}
+
+// joinedTenantField returns tenant field of association joined by name, nil for raw joins
+// and associations not owned by tenant
+func joinedTenantField(s *schema.Schema, name string) *schema.Field {
+	relation, ok := s.Relationships.Relations[name]
+	if !ok {
+		return nil
+	}
+	return relation.FieldSchema.LookUpField(tenantField)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+)
+
+type GormTransactor struct {
+	Db *gorm.DB
+}
+
+func NewGormTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{Db: db}
+}
+
+// WithContext returns transactor running transactions under span of ctx
+func (t *GormTransactor) WithContext(ctx context.Context) domain.Transactor {
+	return NewGormTransactor(withSpan(t.Db, ctx))
+}
+
+func (t *GormTransactor) Transaction(fn func(repos domain.Repositories) error) error {
+	return t.Db.Transaction(func(tx *gorm.DB) error {
+		return fn(domain.Repositories{
+			Books:     NewGormBookRepository(tx),
+			Users:     NewGormUserRepository(tx),
+			Rents:     &GormRentDetailsRepository{Db: tx},
+			Audit:     NewGormAuditRepository(tx),
+			Outbox:    NewGormOutboxRepository(tx),
+			Movements: NewGormStockMovementRepository(tx),
+			Branches:  NewGormBranchRepository(tx),
+			Transfers: NewGormTransferRepository(tx)})
+	})
+}
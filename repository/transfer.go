@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormTransferRepository struct {
+	Db *gorm.DB
+}
+
+func NewGormTransferRepository(db *gorm.DB) *GormTransferRepository {
+	return &GormTransferRepository{Db: db}
+}
+
+// WithContext returns repository running queries under span of ctx
+func (repo *GormTransferRepository) WithContext(ctx context.Context) domain.TransferRepository {
+	return NewGormTransferRepository(withSpan(repo.Db, ctx))
+}
+
+func (repo *GormTransferRepository) GetByID(id int) (*domain.Transfer, error) {
+	var transfer domain.Transfer
+	err := repo.Db.
+		Preload(clause.Associations).
+		First(&transfer, id).Error
+	return &transfer, ErrorToRepoError(err)
+}
+
+func (repo *GormTransferRepository) GetByIDForUpdate(id int) (*domain.Transfer, error) {
+	var transfer domain.Transfer
+	err := repo.Db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload(clause.Associations).
+		First(&transfer, id).Error
+	return &transfer, ErrorToRepoError(err)
+}
+
+// GetByBranch lists transfers leaving or arriving at branch, newest first
+func (repo *GormTransferRepository) GetByBranch(branchID int) ([]domain.Transfer, error) {
+	var transfers []domain.Transfer
+	err := repo.Db.
+		Where("from_branch_id = ? OR to_branch_id = ?", branchID, branchID).
+		Order("created_at DESC, id DESC").
+		Find(&transfers).Error
+	return transfers, ErrorToRepoError(err)
+}
+
+func (repo *GormTransferRepository) Create(transfer *domain.Transfer) error {
+	err := repo.Db.Omit(clause.Associations).Create(transfer).Error
+	return ErrorToRepoError(err)
+}
+
+func (repo *GormTransferRepository) Update(transfer *domain.Transfer, updates map[string]interface{}) error {
+	err := repo.Db.
+		Model(transfer).
+		Omit(clause.Associations).
+		Updates(updates).Error
+	return ErrorToRepoError(err)
+}
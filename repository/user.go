@@ -1,10 +1,18 @@
 package repository
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"github.com/idj1997/book-rent-core/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// fullName is the normalized expression used for name matching and ranking
+const fullName = "unaccent(lower(firstname || ' ' || lastname))"
+
 type GormUserRepository struct {
 	Db *gorm.DB
 }
@@ -13,23 +21,55 @@ func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
 	return &GormUserRepository{Db: db}
 }
 
+// WithContext returns repository running queries under span of ctx
+func (repo *GormUserRepository) WithContext(ctx context.Context) domain.UserRepository {
+	return NewGormUserRepository(withSpan(repo.Db, ctx))
+}
+
 func (repo *GormUserRepository) GetByID(id int) (*domain.User, error) {
 	var user domain.User
 	err := repo.Db.First(&user, id).Error
 	return &user, ErrorToRepoError(err)
 }
 
+func (repo *GormUserRepository) GetByIDs(ids []int) ([]domain.User, error) {
+	var users []domain.User
+	err := repo.Db.Where("id IN ?", ids).Find(&users).Error
+	return users, ErrorToRepoError(err)
+}
+
 func (repo *GormUserRepository) GetByEmail(email string) (*domain.User, error) {
 	var user domain.User
 	err := repo.Db.Where("email = ?", email).First(&user).Error
 	return &user, ErrorToRepoError(err)
 }
 
+// GetByFirstnameAndLastname matches both name parts case and accent insensitive,
+// tolerating typos through trigram similarity, best matches first
 func (repo *GormUserRepository) GetByFirstnameAndLastname(firstname string, lastname string) ([]domain.User, error) {
 	var users []domain.User
 	err := repo.Db.
-		Where("firstname LIKE ?", "%"+firstname+"%").
-		Or("lastname LIKE ?", "%"+lastname+"%").
+		Where(nameMatch("firstname", firstname)).
+		Where(nameMatch("lastname", lastname)).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "similarity(" + fullName + ", unaccent(lower(?))) DESC",
+			Vars: []interface{}{strings.TrimSpace(firstname + " " + lastname)}}}).
+		Find(&users).Error
+	return users, ErrorToRepoError(err)
+}
+
+// Search matches a single free-text query against full name and email,
+// ranked by trigram similarity
+func (repo *GormUserRepository) Search(query string) ([]domain.User, error) {
+	var users []domain.User
+	query = strings.TrimSpace(query)
+	err := repo.Db.
+		Where("("+fullName+" LIKE unaccent(lower(?)) OR "+fullName+" % unaccent(lower(?))"+
+			" OR lower(email) LIKE lower(?) OR lower(email) % lower(?))",
+			likePattern(query), query, likePattern(query), query).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "greatest(similarity(" + fullName + ", unaccent(lower(?))), similarity(lower(email), lower(?))) DESC",
+			Vars: []interface{}{query, query}}}).
 		Find(&users).Error
 	return users, ErrorToRepoError(err)
 }
@@ -48,3 +88,67 @@ func (repo *GormUserRepository) Delete(id int) error {
 	err := repo.Db.Delete(&domain.User{}, id).Error
 	return ErrorToRepoError(err)
 }
+
+func (repo *GormUserRepository) GetDeleted() ([]domain.User, error) {
+	var users []domain.User
+	err := repo.Db.Unscoped().Where("deleted_at IS NOT NULL").Find(&users).Error
+	return users, ErrorToRepoError(err)
+}
+
+// Restore undeletes user unless its email was taken by an active user in the meantime,
+// checks read primary as they guard write
+func (repo *GormUserRepository) Restore(id int) error {
+	db := ReadPrimary(repo.Db)
+	var user domain.User
+	err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error
+	if err != nil {
+		return ErrorToRepoError(err)
+	}
+
+	var taken int64
+	err = db.Model(&domain.User{}).Where("email = ?", user.Email).Count(&taken).Error
+	if err != nil {
+		return ErrorToRepoError(err)
+	}
+	if taken > 0 {
+		return &domain.RepoError{
+			Type:    domain.UniqueConstraint,
+			Message: "email " + user.Email + " is used by another user"}
+	}
+
+	return restore(db, &domain.User{}, id)
+}
+
+// Purge permanently removes users deleted before deletedBefore, users with rent history are
+// anonymised instead, so their rents stay while personal data is gone
+func (repo *GormUserRepository) Purge(deletedBefore time.Time) ([]uint, error) {
+	purged, err := purge(repo.Db, "users", "user_id", deletedBefore)
+	if err != domain.NilRepoErrPtr {
+		return nil, err
+	}
+
+	// users left after purge are referenced by rents, cleared password marks anonymised user
+	var anonymised []uint
+	statement := `UPDATE users SET firstname = '', lastname = '', email = '', password = ''
+		WHERE tenant_id = ? AND deleted_at IS NOT NULL AND deleted_at < ? AND password <> ''
+		RETURNING id`
+	queryErr := ReadPrimary(repo.Db).Raw(statement, tenantOf(repo.Db), deletedBefore).Scan(&anonymised).Error
+	if queryErr != nil {
+		return nil, ErrorToRepoError(queryErr)
+	}
+	return append(purged, anonymised...), domain.NilRepoErrPtr
+}
+
+// nameMatch builds a condition for a single name column, empty value matches everything
+func nameMatch(column string, value string) clause.Expr {
+	normalized := "unaccent(lower(" + column + "))"
+	return clause.Expr{
+		SQL:  "(" + normalized + " LIKE unaccent(lower(?)) OR " + normalized + " % unaccent(lower(?)))",
+		Vars: []interface{}{likePattern(value), strings.TrimSpace(value)}}
+}
+
+// likePattern escapes LIKE wildcards in value and wraps it for substring matching
+func likePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return "%" + replacer.Replace(strings.TrimSpace(value)) + "%"
+}
@@ -0,0 +1,39 @@
+package restapi
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/mux"
+	"github.com/idj1997/book-rent-core/auth"
+	"github.com/idj1997/book-rent-core/config"
+)
+
+// UnauthenticatedCode is code of requests without valid bearer token
+const UnauthenticatedCode = "UNAUTHENTICATED"
+
+// authenticateRequests binds actor presenting bearer token of one of actors and its tenant to
+// request context, requests without valid token are rejected with UNAUTHENTICATED unless their
+// operation in spec has empty security requirement
+func authenticateRequests(spec *openapi3.T, actors map[string]config.ActorConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template, _ := mux.CurrentRoute(r).GetPathTemplate()
+			if route := Route(spec, template, r.Method); route != nil && isPublic(route.Operation) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, err := auth.Authenticate(r.Context(), r.Header.Get(auth.Header), actors)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: ErrorBody{Code: UnauthenticatedCode, Message: err.Error()}})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func isPublic(operation *openapi3.Operation) bool {
+	return operation.Security != nil && len(*operation.Security) == 0
+}
@@ -0,0 +1,59 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/idj1997/book-rent-core/service"
+)
+
+// InternalCode is code of errors not raised by services, their message is not exposed
+const InternalCode = "INTERNAL"
+
+// ErrorResponse is envelope of every failed request
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	// Code is upper case name of service error type or InternalCode
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// StatusCode maps service error type to HTTP status code
+func StatusCode(t service.ServiceErrorType) int {
+	switch t {
+	case service.NotFound:
+		return http.StatusNotFound
+	case service.InvalidArguments:
+		return http.StatusBadRequest
+	case service.AlreadyExist, service.NotEnoughBooksOnStock, service.BookAlreadyReturned,
+		service.ActiveBookRents, service.InvalidStatusTransition:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError writes envelope of err, errors not raised by services become INTERNAL
+func writeError(w http.ResponseWriter, err error) {
+	serviceErr, ok := err.(*service.ServiceError)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: ErrorBody{Code: InternalCode, Message: "internal error"}})
+		return
+	}
+	message := serviceErr.Message
+	if message == "" {
+		message = serviceErr.Type.String()
+	}
+	writeJSON(w, StatusCode(serviceErr.Type), ErrorResponse{Error: ErrorBody{
+		Code:    strings.ToUpper(serviceErr.Type.String()),
+		Message: message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
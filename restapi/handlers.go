@@ -0,0 +1,313 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+type Book struct {
+	ID      uint   `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Stock   int    `json:"stock"`
+	Price   int    `json:"price"`
+	ISBN    string `json:"isbn"`
+}
+
+type BookInput struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Stock   int    `json:"stock"`
+	Price   int    `json:"price"`
+	ISBN    string `json:"isbn"`
+}
+
+type StockInput struct {
+	Stock int `json:"stock"`
+}
+
+type User struct {
+	ID        uint   `json:"id"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+	Type      string `json:"type"`
+}
+
+type UserInput struct {
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Type      string `json:"type"`
+}
+
+type Rent struct {
+	ID             uint       `json:"id"`
+	UserID         int        `json:"userId"`
+	BookID         int        `json:"bookId"`
+	BranchID       uint       `json:"branchId"`
+	ReturnBranchID uint       `json:"returnBranchId,omitempty"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	ReturnDeadline time.Time  `json:"returnDeadline"`
+	ReturnedAt     *time.Time `json:"returnedAt,omitempty"`
+	Fee            int        `json:"fee"`
+	Note           string     `json:"note"`
+}
+
+type RentInput struct {
+	UserID   int  `json:"userId"`
+	BookID   int  `json:"bookId"`
+	BranchID uint `json:"branchId"`
+}
+
+type ReturnInput struct {
+	BranchID int `json:"branchId"`
+}
+
+// id returns id path parameter, validated by spec to be positive integer
+func id(r *http.Request) int {
+	value, _ := strconv.Atoi(mux.Vars(r)["id"])
+	return value
+}
+
+// decode reads body validated by spec into value, empty body leaves value unchanged
+func decode(r *http.Request, value interface{}) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	return json.NewDecoder(r.Body).Decode(value)
+}
+
+func (h *handlers) getSpec(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.spec)
+}
+
+func (h *handlers) searchBooks(w http.ResponseWriter, r *http.Request) {
+	books, err := h.scope(r.Context()).Books.GetByTitle(r.URL.Query().Get("title"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]Book, 0, len(books))
+	for i := range books {
+		resp = append(resp, toBook(&books[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handlers) createBook(w http.ResponseWriter, r *http.Request) {
+	var input BookInput
+	err := decode(r, &input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	book := domain.Book{
+		Title:   input.Title,
+		Content: input.Content,
+		Stock:   input.Stock,
+		Price:   input.Price,
+		ISBN:    input.ISBN}
+	bookID, err := h.scope(r.Context()).Books.Create(&book)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	book.ID = uint(bookID)
+	writeJSON(w, http.StatusCreated, toBook(&book))
+}
+
+func (h *handlers) getBook(w http.ResponseWriter, r *http.Request) {
+	book, err := h.scope(r.Context()).Books.GetByID(id(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toBook(book))
+}
+
+func (h *handlers) deleteBook(w http.ResponseWriter, r *http.Request) {
+	err := h.scope(r.Context()).Books.Delete(id(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handlers) updateStock(w http.ResponseWriter, r *http.Request) {
+	var input StockInput
+	err := decode(r, &input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	book, err := h.scope(r.Context()).Books.UpdateStock(id(r), input.Stock)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toBook(book))
+}
+
+func (h *handlers) searchUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.scope(r.Context()).Users.Search(r.URL.Query().Get("query"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]User, 0, len(users))
+	for i := range users {
+		resp = append(resp, toUser(&users[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handlers) createUser(w http.ResponseWriter, r *http.Request) {
+	var input UserInput
+	err := decode(r, &input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	user := domain.User{
+		Firstname: input.Firstname,
+		Lastname:  input.Lastname,
+		Email:     input.Email,
+		Password:  input.Password,
+		Type:      domain.CUSTOMER}
+	if input.Type == "ADMIN" {
+		user.Type = domain.ADMIN
+	}
+	err = h.scope(r.Context()).Users.Create(&user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toUser(&user))
+}
+
+func (h *handlers) getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.scope(r.Context()).Users.GetByID(id(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toUser(user))
+}
+
+func (h *handlers) deleteUser(w http.ResponseWriter, r *http.Request) {
+	err := h.scope(r.Context()).Users.Delete(id(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handlers) listUserRents(w http.ResponseWriter, r *http.Request) {
+	rents, err := h.scope(r.Context()).Rents.GetByUser(id(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]Rent, 0, len(rents))
+	for i := range rents {
+		resp = append(resp, toRent(&rents[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handlers) rentBook(w http.ResponseWriter, r *http.Request) {
+	var input RentInput
+	err := decode(r, &input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rent := domain.RentDetails{UserID: input.UserID, BookID: input.BookID, BranchID: input.BranchID}
+	err = h.scope(r.Context()).Rents.RentBook(&rent)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toRent(&rent))
+}
+
+func (h *handlers) getRent(w http.ResponseWriter, r *http.Request) {
+	rent, err := h.scope(r.Context()).Rents.GetByID(id(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toRent(rent))
+}
+
+func (h *handlers) returnBook(w http.ResponseWriter, r *http.Request) {
+	var input ReturnInput
+	err := decode(r, &input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rents := h.scope(r.Context()).Rents
+	err = rents.ReturnBook(id(r), input.BranchID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rent, err := rents.GetByID(id(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toRent(rent))
+}
+
+func toBook(book *domain.Book) Book {
+	return Book{
+		ID:      book.ID,
+		Title:   book.Title,
+		Content: book.Content,
+		Stock:   book.Stock,
+		Price:   book.Price,
+		ISBN:    book.ISBN}
+}
+
+func toUser(user *domain.User) User {
+	userType := "CUSTOMER"
+	if user.Type == domain.ADMIN {
+		userType = "ADMIN"
+	}
+	return User{
+		ID:        user.ID,
+		Firstname: user.Firstname,
+		Lastname:  user.Lastname,
+		Email:     user.Email,
+		Type:      userType}
+}
+
+func toRent(rent *domain.RentDetails) Rent {
+	resp := Rent{
+		ID:             rent.ID,
+		UserID:         rent.UserID,
+		BookID:         rent.BookID,
+		BranchID:       rent.BranchID,
+		ReturnBranchID: rent.ReturnBranchID,
+		Status:         rent.Status.String(),
+		CreatedAt:      rent.CreatedAt,
+		ReturnDeadline: rent.ReturnDeadline,
+		Fee:            rent.Fee,
+		Note:           rent.Note}
+	if !rent.ReturnedAt.IsZero() {
+		resp.ReturnedAt = &rent.ReturnedAt
+	}
+	return resp
+}
@@ -0,0 +1,104 @@
+// Package restapi serves book, user and rent services as JSON over HTTP. Routes are described by
+// OpenAPI document Spec, served on /openapi.json, and requests are validated against it.
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/mux"
+	"github.com/idj1997/book-rent-core/app"
+	"github.com/idj1997/book-rent-core/auth"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/logging"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/tracing"
+)
+
+// Services are services serving one request
+type Services struct {
+	Books *service.BookService
+	Users *service.UserService
+	Rents *service.RentDetailsService
+}
+
+// Scope returns services bound to request context, which carries its log fields
+type Scope func(ctx context.Context) Services
+
+// AppScope returns services of application traced and logged under request context
+func AppScope(a *app.App) Scope {
+	return func(ctx context.Context) Services {
+		contextApp := a.WithContext(ctx)
+		return Services{Books: contextApp.Books, Users: contextApp.Users, Rents: contextApp.Rents}
+	}
+}
+
+// withActor returns scope whose services record changes under actor authenticated in context
+func (s Scope) withActor() Scope {
+	return func(ctx context.Context) Services {
+		services := s(ctx)
+		actor := auth.ActorFromContext(ctx)
+		return Services{
+			Books: services.Books.WithActor(actor),
+			Users: services.Users.WithActor(actor),
+			Rents: services.Rents.WithActor(actor)}
+	}
+}
+
+// NewRouter registers routes of Spec served by services of scope to actors, which work with records
+// of their tenant. It panics when Spec is invalid.
+func NewRouter(scope Scope, actors map[string]config.ActorConfig) *mux.Router {
+	spec, err := LoadSpec()
+	if err != nil {
+		panic(err)
+	}
+	h := &handlers{scope: scope.withActor(), spec: spec}
+
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &service.ServiceError{Type: service.NotFound, Message: "no route " + r.URL.Path})
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: ErrorBody{
+			Code:    "INVALID_ARGUMENTS",
+			Message: "method " + r.Method + " is not allowed on " + r.URL.Path}})
+	})
+	router.Use(authenticateRequests(spec, actors), validateRequests(spec))
+
+	router.HandleFunc("/openapi.json", h.getSpec).Methods(http.MethodGet)
+	router.HandleFunc("/books", h.searchBooks).Methods(http.MethodGet)
+	router.HandleFunc("/books", h.createBook).Methods(http.MethodPost)
+	router.HandleFunc("/books/{id}", h.getBook).Methods(http.MethodGet)
+	router.HandleFunc("/books/{id}", h.deleteBook).Methods(http.MethodDelete)
+	router.HandleFunc("/books/{id}/stock", h.updateStock).Methods(http.MethodPut)
+	router.HandleFunc("/users", h.searchUsers).Methods(http.MethodGet)
+	router.HandleFunc("/users", h.createUser).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id}", h.getUser).Methods(http.MethodGet)
+	router.HandleFunc("/users/{id}", h.deleteUser).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{id}/rents", h.listUserRents).Methods(http.MethodGet)
+	router.HandleFunc("/rents", h.rentBook).Methods(http.MethodPost)
+	router.HandleFunc("/rents/{id}", h.getRent).Methods(http.MethodGet)
+	router.HandleFunc("/rents/{id}/return", h.returnBook).Methods(http.MethodPost)
+	return router
+}
+
+// Handler serves NewRouter of scope to actors, requests are logged under their request ID and
+// traced under trace of their traceparent header
+func Handler(scope Scope, actors map[string]config.ActorConfig) http.Handler {
+	return tracing.Middleware(logging.Middleware(NewRouter(scope, actors)))
+}
+
+// NewServer creates server serving Handler of scope to actors of authCfg, nil when address is empty
+func NewServer(cfg config.HTTPConfig, authCfg config.AuthConfig, scope Scope) *http.Server {
+	if cfg.Address == "" {
+		return nil
+	}
+	return &http.Server{Addr: cfg.Address, Handler: Handler(scope, authCfg.Actors), ReadHeaderTimeout: 5 * time.Second}
+}
+
+type handlers struct {
+	scope Scope
+	spec  *openapi3.T
+}
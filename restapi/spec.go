@@ -0,0 +1,537 @@
+package restapi
+
+import (
+	"context"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Spec is OpenAPI 3 document of every route served by NewRouter, requests are validated against it
+const Spec = `
+openapi: 3.0.3
+info:
+  title: book-rent
+  version: 1.0.0
+  description: Books, users and rents of book-rent. Failed requests answer with Error envelope.
+security:
+  - bearer: []
+paths:
+  /openapi.json:
+    get:
+      operationId: getSpec
+      summary: this document
+      security: []
+      responses:
+        '200':
+          description: OpenAPI document
+          content:
+            application/json:
+              schema:
+                type: object
+  /books:
+    get:
+      operationId: searchBooks
+      summary: books whose title contains title
+      parameters:
+        - name: title
+          in: query
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: matching books
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Book'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '500':
+          $ref: '#/components/responses/Internal'
+    post:
+      operationId: createBook
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/BookInput'
+      responses:
+        '201':
+          description: created book
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Book'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '409':
+          $ref: '#/components/responses/Conflict'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /books/{id}:
+    parameters:
+      - $ref: '#/components/parameters/ID'
+    get:
+      operationId: getBook
+      responses:
+        '200':
+          description: book
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Book'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '500':
+          $ref: '#/components/responses/Internal'
+    delete:
+      operationId: deleteBook
+      summary: deletes book without active rents
+      responses:
+        '204':
+          description: deleted
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '409':
+          $ref: '#/components/responses/Conflict'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /books/{id}/stock:
+    parameters:
+      - $ref: '#/components/parameters/ID'
+    put:
+      operationId: updateStock
+      description: corrects stock of book held at default branch only, rejected once stock is split across branches
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/StockInput'
+      responses:
+        '200':
+          description: book with updated stock
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Book'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /users:
+    get:
+      operationId: searchUsers
+      summary: users whose name or email matches query
+      parameters:
+        - name: query
+          in: query
+          required: true
+          schema:
+            type: string
+      responses:
+        '200':
+          description: matching users
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/User'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '500':
+          $ref: '#/components/responses/Internal'
+    post:
+      operationId: createUser
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/UserInput'
+      responses:
+        '201':
+          description: created user
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '409':
+          $ref: '#/components/responses/Conflict'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /users/{id}:
+    parameters:
+      - $ref: '#/components/parameters/ID'
+    get:
+      operationId: getUser
+      responses:
+        '200':
+          description: user
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '500':
+          $ref: '#/components/responses/Internal'
+    delete:
+      operationId: deleteUser
+      summary: deletes user without active rents
+      responses:
+        '204':
+          description: deleted
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '409':
+          $ref: '#/components/responses/Conflict'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /users/{id}/rents:
+    parameters:
+      - $ref: '#/components/parameters/ID'
+    get:
+      operationId: listUserRents
+      responses:
+        '200':
+          description: rents of user
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Rent'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /rents:
+    post:
+      operationId: rentBook
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/RentInput'
+      responses:
+        '201':
+          description: created rent
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Rent'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '409':
+          $ref: '#/components/responses/Conflict'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /rents/{id}:
+    parameters:
+      - $ref: '#/components/parameters/ID'
+    get:
+      operationId: getRent
+      responses:
+        '200':
+          description: rent
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Rent'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '500':
+          $ref: '#/components/responses/Internal'
+  /rents/{id}/return:
+    parameters:
+      - $ref: '#/components/parameters/ID'
+    post:
+      operationId: returnBook
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/ReturnInput'
+      responses:
+        '200':
+          description: returned rent
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Rent'
+        '400':
+          $ref: '#/components/responses/BadRequest'
+        '401':
+          $ref: '#/components/responses/Unauthorized'
+        '404':
+          $ref: '#/components/responses/NotFound'
+        '409':
+          $ref: '#/components/responses/Conflict'
+        '500':
+          $ref: '#/components/responses/Internal'
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+      description: token of one of auth.actors, requests work with records of its tenant
+  parameters:
+    ID:
+      name: id
+      in: path
+      required: true
+      schema:
+        type: integer
+        minimum: 1
+  responses:
+    Unauthorized:
+      description: bearer token is missing or invalid, code UNAUTHENTICATED
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+    BadRequest:
+      description: request does not match this document, code INVALID_ARGUMENTS
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+    NotFound:
+      description: resource does not exist, code NOT_FOUND
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+    Conflict:
+      description: request conflicts with current state, such as stock or rent status
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+    Internal:
+      description: unexpected failure, code UNKNOWN or INTERNAL
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Error'
+  schemas:
+    Error:
+      type: object
+      required: [error]
+      properties:
+        error:
+          type: object
+          required: [code, message]
+          properties:
+            code:
+              type: string
+              enum:
+                - UNAUTHENTICATED
+                - NOT_FOUND
+                - ALREADY_EXIST
+                - INVALID_ARGUMENTS
+                - NOT_ENOUGH_BOOKS_ON_STOCK
+                - BOOK_ALREADY_RETURNED
+                - ACTIVE_BOOK_RENTS
+                - INVALID_STATUS_TRANSITION
+                - UNKNOWN
+                - INTERNAL
+            message:
+              type: string
+    Book:
+      type: object
+      required: [id, title, content, stock, price, isbn]
+      properties:
+        id:
+          type: integer
+        title:
+          type: string
+        content:
+          type: string
+        stock:
+          type: integer
+        price:
+          type: integer
+          description: replacement price of copy, in cents
+        isbn:
+          type: string
+    BookInput:
+      type: object
+      required: [title]
+      additionalProperties: false
+      properties:
+        title:
+          type: string
+          minLength: 1
+        content:
+          type: string
+        stock:
+          type: integer
+          minimum: 0
+        price:
+          type: integer
+          minimum: 0
+          description: replacement price of copy, in cents
+        isbn:
+          type: string
+    StockInput:
+      type: object
+      required: [stock]
+      additionalProperties: false
+      properties:
+        stock:
+          type: integer
+          minimum: 0
+    UserType:
+      type: string
+      enum: [ADMIN, CUSTOMER]
+    User:
+      type: object
+      description: user never carries password, it is only accepted by createUser
+      required: [id, firstname, lastname, email, type]
+      properties:
+        id:
+          type: integer
+        firstname:
+          type: string
+        lastname:
+          type: string
+        email:
+          type: string
+        type:
+          $ref: '#/components/schemas/UserType'
+    UserInput:
+      type: object
+      required: [email, password, type]
+      additionalProperties: false
+      properties:
+        firstname:
+          type: string
+        lastname:
+          type: string
+        email:
+          type: string
+          minLength: 1
+        password:
+          type: string
+          minLength: 1
+        type:
+          $ref: '#/components/schemas/UserType'
+    RentStatus:
+      type: string
+      enum: [RENTED, RETURNED, EXPIRED, LOST, DAMAGED]
+    Rent:
+      type: object
+      required: [id, userId, bookId, branchId, status, createdAt, returnDeadline, fee, note]
+      properties:
+        id:
+          type: integer
+        userId:
+          type: integer
+        bookId:
+          type: integer
+        branchId:
+          type: integer
+        returnBranchId:
+          type: integer
+        status:
+          $ref: '#/components/schemas/RentStatus'
+        createdAt:
+          type: string
+          format: date-time
+        returnDeadline:
+          type: string
+          format: date-time
+        returnedAt:
+          type: string
+          format: date-time
+          description: omitted until book is returned
+        fee:
+          type: integer
+          description: replacement fee charged for lost or damaged book, in cents
+        note:
+          type: string
+    RentInput:
+      type: object
+      required: [userId, bookId]
+      additionalProperties: false
+      properties:
+        userId:
+          type: integer
+          minimum: 1
+        bookId:
+          type: integer
+          minimum: 1
+        branchId:
+          type: integer
+          minimum: 1
+          description: branch book is rented from, default branch when omitted
+    ReturnInput:
+      type: object
+      additionalProperties: false
+      properties:
+        branchId:
+          type: integer
+          minimum: 1
+          description: branch book is returned to, branch it was rented from when omitted
+`
+
+// LoadSpec parses and validates Spec
+func LoadSpec() (*openapi3.T, error) {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(Spec))
+	if err != nil {
+		return nil, err
+	}
+	err = spec.Validate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
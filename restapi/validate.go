@@ -0,0 +1,81 @@
+package restapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+	"github.com/idj1997/book-rent-core/service"
+)
+
+// Route returns operation of spec at path template and method, nil when spec does not describe it
+func Route(spec *openapi3.T, template string, method string) *routers.Route {
+	pathItem := spec.Paths.Find(template)
+	if pathItem == nil || pathItem.GetOperation(method) == nil {
+		return nil
+	}
+	return &routers.Route{
+		Spec:      spec,
+		Path:      template,
+		PathItem:  pathItem,
+		Method:    method,
+		Operation: pathItem.GetOperation(method)}
+}
+
+// validateRequests rejects requests not matching parameters and body of their operation in spec
+// with INVALID_ARGUMENTS
+func validateRequests(spec *openapi3.T) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template, _ := mux.CurrentRoute(r).GetPathTemplate()
+			route := Route(spec, template, r.Method)
+			if route == nil {
+				writeError(w, fmt.Errorf("route %s %s is not described by spec", r.Method, r.URL.Path))
+				return
+			}
+			// bearer tokens are already checked by authenticateRequests
+			err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: mux.Vars(r),
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}})
+			if err != nil {
+				writeError(w, &service.ServiceError{Type: service.InvalidArguments, Message: validationMessage(err)})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validationMessage describes failed validation without dumping schema
+func validationMessage(err error) string {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return err.Error()
+	}
+
+	reason := requestErr.Reason
+	var schemaErr *openapi3.SchemaError
+	if errors.As(requestErr.Err, &schemaErr) {
+		reason = schemaErr.Reason
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			reason = fmt.Sprintf("field %s: %s", strings.Join(pointer, "."), reason)
+		}
+	} else if requestErr.Err != nil {
+		reason = requestErr.Err.Error()
+	}
+
+	switch {
+	case requestErr.Parameter != nil:
+		return fmt.Sprintf("parameter %s in %s: %s", requestErr.Parameter.Name, requestErr.Parameter.In, reason)
+	case requestErr.RequestBody != nil:
+		return "request body: " + reason
+	}
+	return reason
+}
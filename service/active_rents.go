@@ -0,0 +1,132 @@
+package service
+
+import (
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// activeRents filters rents whose books are still out
+func activeRents(rents []domain.RentDetails) []domain.RentDetails {
+	active := make([]domain.RentDetails, 0)
+	for _, rent := range rents {
+		if rent.Status == domain.RENTED || rent.Status == domain.EXPIRED {
+			active = append(active, rent)
+		}
+	}
+	return active
+}
+
+// closeRents closes active rents as returned (restocking books) or lost
+func closeRents(repos domain.Repositories, actor string, rents []domain.RentDetails, closeAs domain.RentDetailsStatus) error {
+	now := time.Now()
+	for i := range rents {
+		rent := &rents[i]
+
+		err := transitionRent(repos, actor, rent, closeAs, now)
+		if err != nil {
+			return err
+		}
+
+		if closeAs == domain.RETURNED {
+			book, getErr := repos.Books.GetByID(rent.BookID)
+			if getErr != domain.NilRepoErrPtr {
+				return RepoErrorToServiceError(getErr)
+			}
+
+			err = moveStock(repos, actor, book, domain.StockMovement{
+				BranchID: rent.BranchID,
+				Kind:     domain.MovementReturn,
+				Quantity: 1,
+				RentID:   rent.ID})
+			if err != nil {
+				return err
+			}
+
+			err = publish(repos, domain.BookReturned, domain.BookReturnedPayload{
+				RentID:     rent.ID,
+				BookID:     rent.BookID,
+				UserID:     rent.UserID,
+				ReturnedAt: now})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// transitionRent moves rent to status through rent lifecycle, rejecting illegal transitions
+func transitionRent(repos domain.Repositories, actor string, rent *domain.RentDetails, to domain.RentDetailsStatus, now time.Time) error {
+	updates, err := rent.Transition(to, now)
+	if err != nil {
+		return &ServiceError{Type: InvalidStatusTransition, Message: err.Error()}
+	}
+	return updateRent(repos, actor, rent, updates)
+}
+
+// settleRent closes rent as lost or damaged, charging replacement fee and restocking
+// usable damaged copies as requested by outcome
+func settleRent(repos domain.Repositories, actor string, rent *domain.RentDetails, status domain.RentDetailsStatus, outcome domain.RentOutcome, now time.Time) error {
+	if status != domain.LOST && status != domain.DAMAGED {
+		return &ServiceError{Type: InvalidArguments}
+	}
+
+	updates, err := rent.Transition(status, now)
+	if err != nil {
+		return &ServiceError{Type: InvalidStatusTransition, Message: err.Error()}
+	}
+	fee := 0
+	if outcome.ChargeFee {
+		fee = rent.Book.Price
+		updates["fee"] = fee
+	}
+	if outcome.Note != "" {
+		updates["note"] = outcome.Note
+	}
+
+	err = updateRent(repos, actor, rent, updates)
+	if err != nil {
+		return err
+	}
+
+	restock := status == domain.DAMAGED && outcome.Restock
+	if restock {
+		err = moveStock(repos, actor, &rent.Book, domain.StockMovement{
+			BranchID: rent.BranchID,
+			Kind:     domain.MovementReturn,
+			Quantity: 1,
+			Reason:   outcome.Note,
+			RentID:   rent.ID})
+		if err != nil {
+			return err
+		}
+	}
+
+	eventType := domain.RentLost
+	if status == domain.DAMAGED {
+		eventType = domain.RentDamaged
+	}
+	return publish(repos, eventType, domain.RentClosedPayload{
+		RentID:    rent.ID,
+		BookID:    rent.BookID,
+		UserID:    rent.UserID,
+		Fee:       fee,
+		Restocked: restock,
+		ClosedAt:  now})
+}
+
+// updateRent applies and audits rent updates
+func updateRent(repos domain.Repositories, actor string, rent *domain.RentDetails, updates map[string]interface{}) error {
+	changes := updateChanges(rent, updates)
+	err := repos.Rents.Update(rent, updates)
+	if err != domain.NilRepoErrPtr {
+		return RepoErrorToServiceError(err)
+	}
+	return audit(repos, actor, rent, rent.ID, domain.AuditUpdate, changes)
+}
+
+// validCloseStatus reports whether active rents may be force closed as status
+func validCloseStatus(status domain.RentDetailsStatus) bool {
+	return status == domain.RETURNED || status == domain.LOST
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"gorm.io/gorm/schema"
+)
+
+// SystemActor is recorded for changes made without an explicit actor, e.g. scheduled jobs
+const SystemActor = "system"
+
+var columnNamer = schema.NamingStrategy{}
+
+type AuditService struct {
+	Repo domain.AuditRepository
+	// Ctx carries span calls are traced under, background when nil
+	Ctx context.Context
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (a *AuditService) WithContext(ctx context.Context) *AuditService {
+	contextService := *a
+	contextService.Ctx = ctx
+	return &contextService
+}
+
+// start starts span of method and returns copy of service whose repositories run under it
+func (a *AuditService) start(method string) (*AuditService, call) {
+	c := startCall(a.Ctx, "AuditService", method)
+	callService := *a
+	callService.Ctx = c.ctx
+	callService.Repo = domain.AuditRepositoryWithContext(c.ctx, a.Repo)
+	return &callService, c
+}
+
+func (a *AuditService) Find(filter domain.AuditFilter) (_ []domain.AuditRecord, err error) {
+	a, c := a.start("Find")
+	defer observe(c, &err)
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, &ServiceError{Type: InvalidArguments}
+	}
+
+	records, err := a.Repo.Find(filter)
+	return records, RepoErrorToServiceError(err)
+}
+
+// audit stores a single audit record within transaction of change
+func audit(repos domain.Repositories, actor string, entity interface{}, id uint, action domain.AuditAction, changes domain.AuditChanges) error {
+	entityType := reflect.TypeOf(entity)
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+
+	record := domain.AuditRecord{
+		Actor:     actorOrSystem(actor),
+		Entity:    entityType.Name(),
+		EntityID:  id,
+		Action:    action,
+		Changes:   changes,
+		CreatedAt: time.Now()}
+	err := repos.Audit.Create(&record)
+	return RepoErrorToServiceError(err)
+}
+
+// auditPurged records permanent deletion of every purged entity
+func auditPurged(repos domain.Repositories, actor string, entity interface{}, ids []uint) error {
+	for _, id := range ids {
+		err := audit(repos, actor, entity, id, domain.AuditDelete, domain.AuditChanges{
+			"purged": {Before: false, After: true}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func actorOrSystem(actor string) string {
+	if actor == "" {
+		return SystemActor
+	}
+	return actor
+}
+
+// updateChanges diffs updates against current state of model, must be called before update is applied
+func updateChanges(model interface{}, updates map[string]interface{}) domain.AuditChanges {
+	fields := auditedFields(model)
+	changes := make(domain.AuditChanges)
+	for key, after := range updates {
+		column := columnNamer.ColumnName("", key)
+		before, audited := fields[column]
+		if !audited {
+			continue
+		}
+		changes[column] = domain.FieldChange{Before: before, After: after}
+	}
+	return changes
+}
+
+// createChanges records all audited fields of created model as after values
+func createChanges(model interface{}) domain.AuditChanges {
+	changes := make(domain.AuditChanges)
+	for column, value := range auditedFields(model) {
+		changes[column] = domain.FieldChange{After: value}
+	}
+	return changes
+}
+
+// deleteChanges records all audited fields of deleted model as before values
+func deleteChanges(model interface{}) domain.AuditChanges {
+	changes := make(domain.AuditChanges)
+	for column, value := range auditedFields(model) {
+		changes[column] = domain.FieldChange{Before: value}
+	}
+	return changes
+}
+
+// auditedFields collects own scalar fields of model by column name,
+// skipping gorm.Model, associations and fields tagged with audit:"-"
+func auditedFields(model interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	value := reflect.Indirect(reflect.ValueOf(model))
+	if value.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous || field.PkgPath != "" || field.Tag.Get("audit") == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			continue
+		}
+		fields[columnNamer.ColumnName("", field.Name)] = value.Field(i).Interface()
+	}
+	return fields
+}
@@ -1,61 +1,289 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/go-playground/validator"
 	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/logging"
+	log "github.com/sirupsen/logrus"
 )
 
 type BookService struct {
-	br domain.BookRepository
+	br     domain.BookRepository
+	tx     domain.Transactor
+	actor  string
+	logger *log.Entry
+	ctx    context.Context
+}
+
+func NewBookService(br domain.BookRepository, tx domain.Transactor) *BookService {
+	return &BookService{br: br, tx: tx}
+}
+
+// WithActor returns copy of service recording changes in audit trail under actor
+func (bs *BookService) WithActor(actor string) *BookService {
+	actorService := *bs
+	actorService.actor = actor
+	return &actorService
+}
+
+// WithLogger returns copy of service logging with entry, which carries request scoped fields
+func (bs *BookService) WithLogger(entry *log.Entry) *BookService {
+	loggerService := *bs
+	loggerService.logger = entry
+	return &loggerService
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (bs *BookService) WithContext(ctx context.Context) *BookService {
+	contextService := *bs
+	contextService.ctx = ctx
+	return &contextService
 }
 
-func NewBookService(br domain.BookRepository) *BookService {
-	return &BookService{br: br}
+// start starts span of method and returns copy of service whose repositories run under it
+func (bs *BookService) start(method string) (*BookService, call) {
+	c := startCall(bs.ctx, "BookService", method)
+	callService := *bs
+	callService.ctx = c.ctx
+	callService.br = domain.BookRepositoryWithContext(c.ctx, bs.br)
+	callService.tx = domain.TransactorWithContext(c.ctx, bs.tx)
+	return &callService, c
 }
 
-func (bs *BookService) GetByID(id int) (*domain.Book, error) {
+func (bs *BookService) GetByID(id int) (_ *domain.Book, err error) {
+	bs, c := bs.start("GetByID")
+	defer observe(c, &err)
 	book, err := bs.br.GetByID(id)
 	return book, RepoErrorToServiceError(err)
 }
 
-func (bs *BookService) GetByTitle(title string) ([]domain.Book, error) {
+// GetByIDs returns books with given IDs in one query, missing IDs are skipped
+func (bs *BookService) GetByIDs(ids []int) (_ []domain.Book, err error) {
+	bs, c := bs.start("GetByIDs")
+	defer observe(c, &err)
+	if len(ids) == 0 {
+		return []domain.Book{}, nil
+	}
+	books, err := bs.br.GetByIDs(ids)
+	return books, RepoErrorToServiceError(err)
+}
+
+func (bs *BookService) GetByTitle(title string) (_ []domain.Book, err error) {
+	bs, c := bs.start("GetByTitle")
+	defer observe(c, &err)
 	books, err := bs.br.GetByTitle(title)
 	return books, RepoErrorToServiceError(err)
 }
 
-func (bs *BookService) Create(book *domain.Book) (int, error) {
+func (bs *BookService) Create(book *domain.Book) (_ int, err error) {
+	bs, c := bs.start("Create")
+	defer observe(c, &err)
 	validate := validator.New()
 	validationErr := validate.Struct(book)
 	if validationErr != nil {
 		return 0, &ServiceError{Type: InvalidArguments}
 	}
 
-	id, err := bs.br.Create(book)
-	return int(id), RepoErrorToServiceError(err)
+	var id uint
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		var createErr error
+		id, createErr = createBook(repos, bs.actor, book)
+		return createErr
+	})
+	if err != nil {
+		return 0, TransactionErrorToServiceError(err)
+	}
+	logEntry(bs.logger).WithField(logging.BookIDField, id).Info("book created")
+	return int(id), nil
+}
+
+// createBook stores, audits and publishes new book, initial stock is recorded as purchase
+// at default branch
+func createBook(repos domain.Repositories, actor string, book *domain.Book) (uint, error) {
+	id, createErr := repos.Books.Create(book)
+	if createErr != domain.NilRepoErrPtr {
+		return 0, RepoErrorToServiceError(createErr)
+	}
+
+	err := audit(repos, actor, book, id, domain.AuditCreate, createChanges(book))
+	if err != nil {
+		return 0, err
+	}
+
+	if book.Stock != 0 {
+		err = openStock(repos, actor, book)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, publish(repos, domain.BookCreated, domain.BookCreatedPayload{
+		BookID: id,
+		Title:  book.Title,
+		Stock:  book.Stock})
 }
 
-func (bs *BookService) UpdateStock(bookID int, newStock int) (*domain.Book, error) {
-	if newStock <= 0 {
+func (bs *BookService) UpdateStock(bookID int, newStock int) (_ *domain.Book, err error) {
+	bs, c := bs.start("UpdateStock")
+	defer observe(c, &err)
+	if newStock < 0 {
 		return nil, &ServiceError{Type: InvalidArguments}
 	}
 
-	book, err := bs.GetByID(bookID)
+	var book *domain.Book
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		var getErr error
+		book, getErr = repos.Books.GetByIDForUpdate(bookID)
+		if getErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(getErr)
+		}
+		return correctTotalStock(repos, bs.actor, book, newStock, "stock count")
+	})
 	if err != nil {
-		return nil, err
+		return nil, TransactionErrorToServiceError(err)
 	}
+	return book, nil
+}
 
-	stockUpdates := make(map[string]interface{})
-	stockUpdates["Stock"] = newStock
-	err = bs.br.Update(book, stockUpdates)
-	return book, RepoErrorToServiceError(err)
+// Delete soft deletes book, rejected with ActiveBookRents while any copy is rented
+func (bs *BookService) Delete(id int) (err error) {
+	bs, c := bs.start("Delete")
+	defer observe(c, &err)
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		book, rents, err := bookActiveRents(repos, id)
+		if err != nil {
+			return err
+		}
+		if len(rents) > 0 {
+			return &ServiceError{Type: ActiveBookRents}
+		}
+
+		return bs.delete(repos, book, id)
+	})
+	if err != nil {
+		return TransactionErrorToServiceError(err)
+	}
+	logEntry(bs.logger).WithField(logging.BookIDField, id).Info("book deleted")
+	return nil
+}
+
+// ForceDelete closes active rents of book as RETURNED or LOST and deletes it in one transaction
+func (bs *BookService) ForceDelete(id int, closeAs domain.RentDetailsStatus) (err error) {
+	bs, c := bs.start("ForceDelete")
+	defer observe(c, &err)
+	if !validCloseStatus(closeAs) {
+		return &ServiceError{Type: InvalidArguments}
+	}
+
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		book, rents, err := bookActiveRents(repos, id)
+		if err != nil {
+			return err
+		}
+
+		err = closeRents(repos, bs.actor, rents, closeAs)
+		if err != nil {
+			return err
+		}
+
+		return bs.delete(repos, book, id)
+	})
+	if err != nil {
+		return TransactionErrorToServiceError(err)
+	}
+	logEntry(bs.logger).WithField(logging.BookIDField, id).Infof("book deleted, active rents closed as %s", closeAs)
+	return nil
 }
 
-func (bs *BookService) Delete(id int) error {
-	_, err := bs.br.GetByID(id)
+func (bs *BookService) delete(repos domain.Repositories, book *domain.Book, id int) error {
+	err := repos.Books.Delete(id)
 	if err != domain.NilRepoErrPtr {
 		return RepoErrorToServiceError(err)
 	}
 
-	err = bs.br.Delete(id)
-	return RepoErrorToServiceError(err)
+	return audit(repos, bs.actor, book, uint(id), domain.AuditDelete, deleteChanges(book))
+}
+
+func bookActiveRents(repos domain.Repositories, bookID int) (*domain.Book, []domain.RentDetails, error) {
+	book, err := repos.Books.GetByID(bookID)
+	if err != domain.NilRepoErrPtr {
+		return nil, nil, RepoErrorToServiceError(err)
+	}
+
+	rents, err := repos.Rents.GetByBook(bookID)
+	if err != domain.NilRepoErrPtr {
+		return nil, nil, RepoErrorToServiceError(err)
+	}
+	return book, activeRents(rents), nil
+}
+
+func (bs *BookService) GetDeleted() (_ []domain.Book, err error) {
+	bs, c := bs.start("GetDeleted")
+	defer observe(c, &err)
+	books, err := bs.br.GetDeleted()
+	return books, RepoErrorToServiceError(err)
+}
+
+func (bs *BookService) Restore(id int) (err error) {
+	bs, c := bs.start("Restore")
+	defer observe(c, &err)
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		restoreErr := repos.Books.Restore(id)
+		if restoreErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(restoreErr)
+		}
+
+		return audit(repos, bs.actor, domain.NilBookPtr, uint(id), domain.AuditRestore, nil)
+	})
+	return TransactionErrorToServiceError(err)
+}
+
+// PurgeDeleted permanently removes books deleted longer than retention ago
+func (bs *BookService) PurgeDeleted(retention time.Duration) (_ int, err error) {
+	bs, c := bs.start("PurgeDeleted")
+	defer observe(c, &err)
+	if retention < 0 {
+		return 0, &ServiceError{Type: InvalidArguments}
+	}
+
+	var purged []uint
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		var purgeErr error
+		purged, purgeErr = repos.Books.Purge(time.Now().Add(-retention))
+		if purgeErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(purgeErr)
+		}
+		return auditPurged(repos, bs.actor, domain.NilBookPtr, purged)
+	})
+	if err != nil {
+		return 0, TransactionErrorToServiceError(err)
+	}
+	return len(purged), nil
+}
+
+// openStock records initial stock of created book at default branch
+func openStock(repos domain.Repositories, actor string, book *domain.Book) error {
+	branch, err := resolveBranch(repos, 0)
+	if err != nil {
+		return err
+	}
+
+	movement := domain.StockMovement{
+		BookID:    book.ID,
+		BranchID:  branch.ID,
+		Kind:      domain.MovementPurchase,
+		Quantity:  book.Stock,
+		Reason:    "initial stock",
+		Actor:     actorOrSystem(actor),
+		CreatedAt: time.Now()}
+	createErr := repos.Movements.Create(&movement)
+	if createErr != domain.NilRepoErrPtr {
+		return RepoErrorToServiceError(createErr)
+	}
+
+	saveErr := repos.Branches.SaveStock(&domain.BranchStock{BookID: book.ID, BranchID: branch.ID, Stock: book.Stock})
+	return RepoErrorToServiceError(saveErr)
 }
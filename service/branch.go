@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// transferTransitions lists allowed target statuses of transfer, RECEIVED and CANCELLED are final
+var transferTransitions = map[domain.TransferStatus][]domain.TransferStatus{
+	domain.TransferRequested: {domain.TransferShipped, domain.TransferCancelled},
+	domain.TransferShipped:   {domain.TransferReceived},
+}
+
+type BranchService struct {
+	brr   domain.BranchRepository
+	trr   domain.TransferRepository
+	tx    domain.Transactor
+	actor string
+	ctx   context.Context
+}
+
+func NewBranchService(brr domain.BranchRepository, trr domain.TransferRepository, tx domain.Transactor) *BranchService {
+	return &BranchService{brr: brr, trr: trr, tx: tx}
+}
+
+// WithActor returns copy of service recording changes in audit trail under actor
+func (bs *BranchService) WithActor(actor string) *BranchService {
+	actorService := *bs
+	actorService.actor = actor
+	return &actorService
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (bs *BranchService) WithContext(ctx context.Context) *BranchService {
+	contextService := *bs
+	contextService.ctx = ctx
+	return &contextService
+}
+
+// start starts span of method and returns copy of service whose repositories run under it
+func (bs *BranchService) start(method string) (*BranchService, call) {
+	c := startCall(bs.ctx, "BranchService", method)
+	callService := *bs
+	callService.ctx = c.ctx
+	callService.brr = domain.BranchRepositoryWithContext(c.ctx, bs.brr)
+	callService.trr = domain.TransferRepositoryWithContext(c.ctx, bs.trr)
+	callService.tx = domain.TransactorWithContext(c.ctx, bs.tx)
+	return &callService, c
+}
+
+func (bs *BranchService) GetAll() (_ []domain.Branch, err error) {
+	bs, c := bs.start("GetAll")
+	defer observe(c, &err)
+	branches, err := bs.brr.GetAll()
+	return branches, RepoErrorToServiceError(err)
+}
+
+// Create adds branch, additional branches are never default
+func (bs *BranchService) Create(branch *domain.Branch) (err error) {
+	bs, c := bs.start("Create")
+	defer observe(c, &err)
+	validationErr := validator.New().Struct(branch)
+	if validationErr != nil {
+		return &ServiceError{Type: InvalidArguments}
+	}
+
+	branch.IsDefault = false
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		createErr := repos.Branches.Create(branch)
+		if createErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(createErr)
+		}
+		return audit(repos, bs.actor, branch, branch.ID, domain.AuditCreate, createChanges(branch))
+	})
+	return TransactionErrorToServiceError(err)
+}
+
+func (bs *BranchService) GetAvailability(bookID int) (_ []domain.BranchStock, err error) {
+	bs, c := bs.start("GetAvailability")
+	defer observe(c, &err)
+	stocks, err := bs.brr.GetAvailability(bookID)
+	return stocks, RepoErrorToServiceError(err)
+}
+
+func (bs *BranchService) GetAvailableBooks(branchID int) (_ []domain.BranchStock, err error) {
+	bs, c := bs.start("GetAvailableBooks")
+	defer observe(c, &err)
+	stocks, err := bs.brr.GetAvailableBooks(branchID)
+	return stocks, RepoErrorToServiceError(err)
+}
+
+func (bs *BranchService) GetTransfers(branchID int) (_ []domain.Transfer, err error) {
+	bs, c := bs.start("GetTransfers")
+	defer observe(c, &err)
+	transfers, err := bs.trr.GetByBranch(branchID)
+	return transfers, RepoErrorToServiceError(err)
+}
+
+// RequestTransfer asks source branch to send copies, stock is not reserved until shipped
+func (bs *BranchService) RequestTransfer(bookID int, fromBranchID int, toBranchID int, quantity int) (_ *domain.Transfer, err error) {
+	bs, c := bs.start("RequestTransfer")
+	defer observe(c, &err)
+	if quantity <= 0 || fromBranchID <= 0 || toBranchID <= 0 || fromBranchID == toBranchID {
+		return nil, &ServiceError{Type: InvalidArguments}
+	}
+
+	transfer := &domain.Transfer{
+		BookID:       uint(bookID),
+		FromBranchID: uint(fromBranchID),
+		ToBranchID:   uint(toBranchID),
+		Quantity:     quantity,
+		Status:       domain.TransferRequested,
+		RequestedBy:  actorOrSystem(bs.actor)}
+	err = bs.tx.Transaction(func(repos domain.Repositories) error {
+		_, getErr := repos.Books.GetByID(bookID)
+		if getErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(getErr)
+		}
+		for _, branchID := range []int{fromBranchID, toBranchID} {
+			_, err := resolveBranch(repos, uint(branchID))
+			if err != nil {
+				return err
+			}
+		}
+
+		createErr := repos.Transfers.Create(transfer)
+		if createErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(createErr)
+		}
+		return audit(repos, bs.actor, transfer, transfer.ID, domain.AuditCreate, createChanges(transfer))
+	})
+	if err != nil {
+		return nil, TransactionErrorToServiceError(err)
+	}
+	return transfer, nil
+}
+
+// ShipTransfer takes copies off stock of source branch, they are in transit until received
+func (bs *BranchService) ShipTransfer(transferID int) (err error) {
+	bs, c := bs.start("ShipTransfer")
+	defer observe(c, &err)
+	return bs.advance(transferID, domain.TransferShipped, func(repos domain.Repositories, transfer *domain.Transfer) error {
+		return moveStock(repos, bs.actor, &transfer.Book, domain.StockMovement{
+			BranchID:   transfer.FromBranchID,
+			Kind:       domain.MovementTransferOut,
+			Quantity:   -transfer.Quantity,
+			Reason:     fmt.Sprintf("transfer to branch %d", transfer.ToBranchID),
+			TransferID: transfer.ID})
+	})
+}
+
+// ReceiveTransfer puts shipped copies on stock of target branch
+func (bs *BranchService) ReceiveTransfer(transferID int) (err error) {
+	bs, c := bs.start("ReceiveTransfer")
+	defer observe(c, &err)
+	return bs.advance(transferID, domain.TransferReceived, func(repos domain.Repositories, transfer *domain.Transfer) error {
+		return moveStock(repos, bs.actor, &transfer.Book, domain.StockMovement{
+			BranchID:   transfer.ToBranchID,
+			Kind:       domain.MovementTransferIn,
+			Quantity:   transfer.Quantity,
+			Reason:     fmt.Sprintf("transfer from branch %d", transfer.FromBranchID),
+			TransferID: transfer.ID})
+	})
+}
+
+// CancelTransfer drops transfer which was not shipped yet
+func (bs *BranchService) CancelTransfer(transferID int) (err error) {
+	bs, c := bs.start("CancelTransfer")
+	defer observe(c, &err)
+	return bs.advance(transferID, domain.TransferCancelled, nil)
+}
+
+// advance moves transfer to status and applies its stock effect in one transaction, transfer is
+// locked before its status is checked, so concurrent calls cannot apply the same effect twice
+func (bs *BranchService) advance(transferID int, to domain.TransferStatus, effect func(domain.Repositories, *domain.Transfer) error) error {
+	err := bs.tx.Transaction(func(repos domain.Repositories) error {
+		transfer, getErr := repos.Transfers.GetByIDForUpdate(transferID)
+		if getErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(getErr)
+		}
+		if !canTransferTransition(transfer.Status, to) {
+			return &ServiceError{
+				Type:    InvalidStatusTransition,
+				Message: fmt.Sprintf("invalid transfer transition %s -> %s", transfer.Status, to)}
+		}
+
+		if effect != nil {
+			err := effect(repos, transfer)
+			if err != nil {
+				return err
+			}
+		}
+
+		updates := make(map[string]interface{})
+		updates["status"] = to
+		switch to {
+		case domain.TransferShipped:
+			updates["shipped_at"] = time.Now()
+		case domain.TransferReceived:
+			updates["received_at"] = time.Now()
+		}
+		changes := updateChanges(transfer, updates)
+		updateErr := repos.Transfers.Update(transfer, updates)
+		if updateErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(updateErr)
+		}
+		return audit(repos, bs.actor, transfer, transfer.ID, domain.AuditUpdate, changes)
+	})
+	return TransactionErrorToServiceError(err)
+}
+
+func canTransferTransition(from domain.TransferStatus, to domain.TransferStatus) bool {
+	for _, allowed := range transferTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-playground/validator"
+	"github.com/idj1997/book-rent-core/catalog"
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+type importOutcome int
+
+const (
+	imported importOutcome = iota
+	updated
+	unchanged
+)
+
+// errImportRolledBack aborts atomic import transaction after first failed row
+var errImportRolledBack = errors.New("catalog import rolled back")
+
+type CatalogService struct {
+	br    domain.BookRepository
+	tx    domain.Transactor
+	actor string
+	ctx   context.Context
+}
+
+func NewCatalogService(br domain.BookRepository, tx domain.Transactor) *CatalogService {
+	return &CatalogService{br: br, tx: tx}
+}
+
+// WithActor returns copy of service recording changes in audit trail under actor
+func (cs *CatalogService) WithActor(actor string) *CatalogService {
+	actorService := *cs
+	actorService.actor = actor
+	return &actorService
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (cs *CatalogService) WithContext(ctx context.Context) *CatalogService {
+	contextService := *cs
+	contextService.ctx = ctx
+	return &contextService
+}
+
+// start starts span of method and returns copy of service whose repositories run under it
+func (cs *CatalogService) start(method string) (*CatalogService, call) {
+	c := startCall(cs.ctx, "CatalogService", method)
+	callService := *cs
+	callService.ctx = c.ctx
+	callService.br = domain.BookRepositoryWithContext(c.ctx, cs.br)
+	callService.tx = domain.TransactorWithContext(c.ctx, cs.tx)
+	return &callService, c
+}
+
+// Import upserts books of catalog matched by ISBN, or by title for rows without ISBN,
+// rows are validated up front and atomic import stores nothing unless every row succeeds
+func (cs *CatalogService) Import(r io.Reader, options domain.ImportOptions) (_ *domain.ImportReport, err error) {
+	cs, c := cs.start("Import")
+	defer observe(c, &err)
+	if options.Mode == "" {
+		options.Mode = domain.ImportAtomic
+	}
+	if options.Mode != domain.ImportAtomic && options.Mode != domain.ImportPartial {
+		return nil, &ServiceError{Type: InvalidArguments, Message: fmt.Sprintf("unsupported import mode %q", options.Mode)}
+	}
+
+	rows, err := catalog.Decode(r, options.Format)
+	if err != nil {
+		return nil, &ServiceError{Type: InvalidArguments, Message: err.Error()}
+	}
+
+	report := &domain.ImportReport{DryRun: options.DryRun, Errors: make([]domain.RowError, 0)}
+	valid := make([]catalog.Row, 0, len(rows))
+	for _, row := range rows {
+		if validationErr := validateRow(row); validationErr != nil {
+			addRowError(report, row, validationErr)
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	if options.Mode == domain.ImportAtomic && report.Failed > 0 {
+		return report, importFailed(report)
+	}
+	if options.DryRun {
+		return report, cs.plan(report, valid)
+	}
+	if options.Mode == domain.ImportPartial {
+		cs.importPartial(report, valid)
+		return report, nil
+	}
+	return report, cs.importAtomic(report, valid)
+}
+
+func (cs *CatalogService) importAtomic(report *domain.ImportReport, rows []catalog.Row) error {
+	err := cs.tx.Transaction(func(repos domain.Repositories) error {
+		for _, row := range rows {
+			outcome, rowErr := importEntry(repos, cs.actor, row.Entry)
+			if rowErr != nil {
+				addRowError(report, row, rowErr)
+				return errImportRolledBack
+			}
+			countOutcome(report, outcome)
+		}
+		return nil
+	})
+	if err == errImportRolledBack {
+		report.Created, report.Updated, report.Unchanged = 0, 0, 0
+		return importFailed(report)
+	}
+	return TransactionErrorToServiceError(err)
+}
+
+func (cs *CatalogService) importPartial(report *domain.ImportReport, rows []catalog.Row) {
+	for _, row := range rows {
+		var outcome importOutcome
+		err := cs.tx.Transaction(func(repos domain.Repositories) error {
+			var rowErr error
+			outcome, rowErr = importEntry(repos, cs.actor, row.Entry)
+			return rowErr
+		})
+		if err != nil {
+			addRowError(report, row, TransactionErrorToServiceError(err))
+			continue
+		}
+		countOutcome(report, outcome)
+	}
+}
+
+// plan classifies rows like import would without storing them,
+// repeated keys within catalog are compared against earlier rows
+func (cs *CatalogService) plan(report *domain.ImportReport, rows []catalog.Row) error {
+	seen := make(map[string]domain.Book)
+	for _, row := range rows {
+		key := naturalKey(row.Entry)
+		book, planned := seen[key]
+		if !planned {
+			existing, err := cs.br.GetByNaturalKey(row.Entry.ISBN, row.Entry.Title)
+			if err != domain.NilRepoErrPtr && err.(*domain.RepoError).Type != domain.NotFound {
+				return RepoErrorToServiceError(err)
+			}
+			if err == domain.NilRepoErrPtr {
+				book, planned = *existing, true
+			}
+		}
+
+		switch {
+		case !planned:
+			countOutcome(report, imported)
+		case len(entryUpdates(&book, row.Entry)) == 0:
+			countOutcome(report, unchanged)
+		default:
+			countOutcome(report, updated)
+		}
+		seen[key] = entryBook(row.Entry)
+	}
+	return nil
+}
+
+func (cs *CatalogService) Export(w io.Writer, format domain.CatalogFormat) (_ int, err error) {
+	cs, c := cs.start("Export")
+	defer observe(c, &err)
+	if format != domain.CSVFormat && format != domain.JSONFormat {
+		return 0, &ServiceError{Type: InvalidArguments, Message: fmt.Sprintf("unsupported catalog format %q", format)}
+	}
+
+	books, repoErr := cs.br.GetAll()
+	if repoErr != domain.NilRepoErrPtr {
+		return 0, RepoErrorToServiceError(repoErr)
+	}
+
+	entries := make([]domain.CatalogEntry, len(books))
+	for i, book := range books {
+		entries[i] = domain.CatalogEntry{
+			Title:   book.Title,
+			Content: book.Content,
+			Stock:   book.Stock,
+			Price:   book.Price,
+			ISBN:    book.ISBN}
+	}
+
+	err = catalog.Encode(w, format, entries)
+	if err != nil {
+		return 0, &ServiceError{Type: Unknown, Message: err.Error()}
+	}
+	return len(entries), nil
+}
+
+// importEntry creates book of entry or updates fields of existing one
+func importEntry(repos domain.Repositories, actor string, entry domain.CatalogEntry) (importOutcome, error) {
+	book, getErr := repos.Books.GetByNaturalKey(entry.ISBN, entry.Title)
+	if getErr != domain.NilRepoErrPtr {
+		if getErr.(*domain.RepoError).Type != domain.NotFound {
+			return 0, RepoErrorToServiceError(getErr)
+		}
+
+		newBook := entryBook(entry)
+		_, err := createBook(repos, actor, &newBook)
+		return imported, err
+	}
+
+	updates := entryUpdates(book, entry)
+	if len(updates) == 0 {
+		return unchanged, nil
+	}
+
+	// stock changes only through ledger
+	_, stockChanged := updates["stock"]
+	delete(updates, "stock")
+
+	if len(updates) > 0 {
+		changes := updateChanges(book, updates)
+		updateErr := repos.Books.Update(book, updates)
+		if updateErr != domain.NilRepoErrPtr {
+			return 0, RepoErrorToServiceError(updateErr)
+		}
+
+		err := audit(repos, actor, book, book.ID, domain.AuditUpdate, changes)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if stockChanged {
+		// correction is counted from stock locked for this transaction, not from stock read earlier
+		err := lockBook(repos, book)
+		if err == nil {
+			err = correctTotalStock(repos, actor, book, entry.Stock, "catalog import")
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return updated, nil
+}
+
+// entryUpdates lists fields of book differing from entry, ISBN is never cleared
+func entryUpdates(book *domain.Book, entry domain.CatalogEntry) map[string]interface{} {
+	updates := make(map[string]interface{})
+	if book.Title != entry.Title {
+		updates["title"] = entry.Title
+	}
+	if book.Content != entry.Content {
+		updates["content"] = entry.Content
+	}
+	if book.Stock != entry.Stock {
+		updates["stock"] = entry.Stock
+	}
+	if book.Price != entry.Price {
+		updates["price"] = entry.Price
+	}
+	if entry.ISBN != "" && book.ISBN != entry.ISBN {
+		updates["isbn"] = entry.ISBN
+	}
+	return updates
+}
+
+func entryBook(entry domain.CatalogEntry) domain.Book {
+	return domain.Book{
+		Title:   entry.Title,
+		Content: entry.Content,
+		Stock:   entry.Stock,
+		Price:   entry.Price,
+		ISBN:    entry.ISBN}
+}
+
+func validateRow(row catalog.Row) error {
+	if row.Err != nil {
+		return row.Err
+	}
+	book := entryBook(row.Entry)
+	return validator.New().Struct(&book)
+}
+
+func naturalKey(entry domain.CatalogEntry) string {
+	if entry.ISBN != "" {
+		return "isbn:" + entry.ISBN
+	}
+	return "title:" + entry.Title
+}
+
+func addRowError(report *domain.ImportReport, row catalog.Row, err error) {
+	message := err.Error()
+	if message == "" {
+		message = "error while storing book"
+		if serviceErr, ok := err.(*ServiceError); ok && serviceErr.Type == AlreadyExist {
+			message = "book already exists"
+		}
+	}
+
+	key := row.Entry.ISBN
+	if key == "" {
+		key = row.Entry.Title
+	}
+	report.Failed++
+	report.Errors = append(report.Errors, domain.RowError{Row: row.Row, Key: key, Message: message})
+}
+
+func countOutcome(report *domain.ImportReport, outcome importOutcome) {
+	switch outcome {
+	case imported:
+		report.Created++
+	case updated:
+		report.Updated++
+	case unchanged:
+		report.Unchanged++
+	}
+}
+
+func importFailed(report *domain.ImportReport) error {
+	return &ServiceError{
+		Type:    InvalidArguments,
+		Message: fmt.Sprintf("catalog import failed, %d row(s) rejected", report.Failed)}
+}
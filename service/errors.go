@@ -7,15 +7,36 @@ var NilServiceErrPtr *ServiceError
 type ServiceErrorType int
 
 const (
-	Unknown               ServiceErrorType = 0
-	NotFound              ServiceErrorType = 1
-	AlreadyExist          ServiceErrorType = 2
-	InvalidArguments      ServiceErrorType = 3
-	NotEnoughBooksOnStock ServiceErrorType = 4
-	BookAlreadyReturned   ServiceErrorType = 5
-	ActiveBookRents       ServiceErrorType = 6
+	Unknown                 ServiceErrorType = 0
+	NotFound                ServiceErrorType = 1
+	AlreadyExist            ServiceErrorType = 2
+	InvalidArguments        ServiceErrorType = 3
+	NotEnoughBooksOnStock   ServiceErrorType = 4
+	BookAlreadyReturned     ServiceErrorType = 5
+	ActiveBookRents         ServiceErrorType = 6
+	InvalidStatusTransition ServiceErrorType = 7
 )
 
+func (t ServiceErrorType) String() string {
+	switch t {
+	case NotFound:
+		return "not_found"
+	case AlreadyExist:
+		return "already_exist"
+	case InvalidArguments:
+		return "invalid_arguments"
+	case NotEnoughBooksOnStock:
+		return "not_enough_books_on_stock"
+	case BookAlreadyReturned:
+		return "book_already_returned"
+	case ActiveBookRents:
+		return "active_book_rents"
+	case InvalidStatusTransition:
+		return "invalid_status_transition"
+	}
+	return "unknown"
+}
+
 type ServiceError struct {
 	Type    ServiceErrorType
 	Message string
@@ -34,6 +55,8 @@ func RepoErrorToServiceError(err error) error {
 			errType = AlreadyExist
 		} else if repoErr.Type == domain.NotFound {
 			errType = NotFound
+		} else if repoErr.Type == domain.InvalidTransition {
+			errType = InvalidStatusTransition
 		} else {
 			errType = Unknown
 		}
@@ -41,3 +64,15 @@ func RepoErrorToServiceError(err error) error {
 	}
 	return nil
 }
+
+// TransactionErrorToServiceError passes service errors returned from transaction
+// and maps failures of transaction itself (begin, commit) to Unknown
+func TransactionErrorToServiceError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if serviceErr, ok := err.(*ServiceError); ok {
+		return serviceErr
+	}
+	return &ServiceError{Type: Unknown, Message: err.Error()}
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// publish writes domain event to outbox within transaction of state change
+func publish(repos domain.Repositories, eventType domain.EventType, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return &ServiceError{Type: Unknown, Message: err.Error()}
+	}
+
+	now := time.Now()
+	event := domain.OutboxEvent{
+		Type:          eventType,
+		Payload:       data,
+		CreatedAt:     now,
+		NextAttemptAt: now}
+	createErr := repos.Outbox.Create(&event)
+	return RepoErrorToServiceError(createErr)
+}
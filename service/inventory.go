@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+type InventoryService struct {
+	mr    domain.StockMovementRepository
+	tx    domain.Transactor
+	actor string
+	ctx   context.Context
+}
+
+func NewInventoryService(mr domain.StockMovementRepository, tx domain.Transactor) *InventoryService {
+	return &InventoryService{mr: mr, tx: tx}
+}
+
+// WithActor returns copy of service recording movements under actor
+func (is *InventoryService) WithActor(actor string) *InventoryService {
+	actorService := *is
+	actorService.actor = actor
+	return &actorService
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (is *InventoryService) WithContext(ctx context.Context) *InventoryService {
+	contextService := *is
+	contextService.ctx = ctx
+	return &contextService
+}
+
+// start starts span of method and returns copy of service whose repositories run under it
+func (is *InventoryService) start(method string) (*InventoryService, call) {
+	c := startCall(is.ctx, "InventoryService", method)
+	callService := *is
+	callService.ctx = c.ctx
+	callService.mr = domain.StockMovementRepositoryWithContext(c.ctx, is.mr)
+	callService.tx = domain.TransactorWithContext(c.ctx, is.tx)
+	return &callService, c
+}
+
+// RecordMovement applies manual movement, rent-out, return and transfer movements
+// are recorded by their workflows only
+func (is *InventoryService) RecordMovement(bookID int, branchID int, kind domain.MovementKind, quantity int, reason string) (_ *domain.StockMovement, err error) {
+	is, c := is.start("RecordMovement")
+	defer observe(c, &err)
+	if !validManualMovement(kind, quantity) || reason == "" {
+		return nil, &ServiceError{Type: InvalidArguments}
+	}
+
+	var movement *domain.StockMovement
+	err = is.tx.Transaction(func(repos domain.Repositories) error {
+		book, getErr := repos.Books.GetByIDForUpdate(bookID)
+		if getErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(getErr)
+		}
+
+		var moveErr error
+		movement, moveErr = applyLockedMovement(repos, is.actor, book, domain.StockMovement{
+			BranchID: uint(branchID),
+			Kind:     kind,
+			Quantity: quantity,
+			Reason:   reason})
+		return moveErr
+	})
+	if err != nil {
+		return nil, TransactionErrorToServiceError(err)
+	}
+	return movement, nil
+}
+
+func (is *InventoryService) GetMovements(bookID int) (_ []domain.StockMovement, err error) {
+	is, c := is.start("GetMovements")
+	defer observe(c, &err)
+	movements, err := is.mr.GetByBook(bookID)
+	return movements, RepoErrorToServiceError(err)
+}
+
+func validManualMovement(kind domain.MovementKind, quantity int) bool {
+	switch kind {
+	case domain.MovementPurchase:
+		return quantity > 0
+	case domain.MovementWriteOff:
+		return quantity < 0
+	case domain.MovementCorrection:
+		return quantity != 0
+	}
+	return false
+}
+
+// moveStock records movement of book and applies it to stock of its branch
+func moveStock(repos domain.Repositories, actor string, book *domain.Book, movement domain.StockMovement) error {
+	_, err := applyMovement(repos, actor, book, movement)
+	return err
+}
+
+// applyMovement locks book and reloads it, then applies movement to its current stock,
+// so concurrent movements of the same book wait for each other instead of losing updates
+func applyMovement(repos domain.Repositories, actor string, book *domain.Book, movement domain.StockMovement) (*domain.StockMovement, error) {
+	err := lockBook(repos, book)
+	if err != nil {
+		return nil, err
+	}
+	return applyLockedMovement(repos, actor, book, movement)
+}
+
+// lockBook replaces book by its row locked until transaction ends
+func lockBook(repos domain.Repositories, book *domain.Book) error {
+	locked, err := repos.Books.GetByIDForUpdate(int(book.ID))
+	if err != domain.NilRepoErrPtr {
+		return RepoErrorToServiceError(err)
+	}
+	*book = *locked
+	return nil
+}
+
+// applyLockedMovement stores ledger entry, then updates branch stock and book total, audits and
+// publishes change in the same transaction, so stock never drifts from ledger sum,
+// movement without branch applies to default branch. Book must be locked by caller, which
+// also serializes changes of its branch stock rows.
+func applyLockedMovement(repos domain.Repositories, actor string, book *domain.Book, movement domain.StockMovement) (*domain.StockMovement, error) {
+	branch, err := resolveBranch(repos, movement.BranchID)
+	if err != nil {
+		return nil, err
+	}
+
+	branchStock, getErr := repos.Branches.GetStock(int(book.ID), int(branch.ID))
+	if getErr != domain.NilRepoErrPtr {
+		if getErr.(*domain.RepoError).Type != domain.NotFound {
+			return nil, RepoErrorToServiceError(getErr)
+		}
+		branchStock = &domain.BranchStock{BookID: book.ID, BranchID: branch.ID}
+	}
+
+	oldStock := book.Stock
+	stock := oldStock + movement.Quantity
+	if stock < 0 || branchStock.Stock+movement.Quantity < 0 {
+		return nil, &ServiceError{Type: NotEnoughBooksOnStock}
+	}
+
+	movement.BookID = book.ID
+	movement.BranchID = branch.ID
+	movement.Actor = actorOrSystem(actor)
+	movement.CreatedAt = time.Now()
+	createErr := repos.Movements.Create(&movement)
+	if createErr != domain.NilRepoErrPtr {
+		return nil, RepoErrorToServiceError(createErr)
+	}
+
+	branchStock.Stock += movement.Quantity
+	saveErr := repos.Branches.SaveStock(branchStock)
+	if saveErr != domain.NilRepoErrPtr {
+		return nil, RepoErrorToServiceError(saveErr)
+	}
+
+	updates := make(map[string]interface{})
+	updates["stock"] = stock
+	changes := updateChanges(book, updates)
+	updateErr := repos.Books.Update(book, updates)
+	if updateErr != domain.NilRepoErrPtr {
+		return nil, RepoErrorToServiceError(updateErr)
+	}
+
+	err = audit(repos, actor, book, book.ID, domain.AuditUpdate, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = publish(repos, domain.StockChanged, domain.StockChangedPayload{
+		BookID:   book.ID,
+		BranchID: branch.ID,
+		OldStock: oldStock,
+		NewStock: stock,
+		Movement: movement.Kind,
+		Reason:   movement.Reason})
+	if err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// correctTotalStock corrects stock of locked book to stock at default branch, rejected with
+// InvalidArguments once other branches hold copies, their stock is corrected by branch movements
+func correctTotalStock(repos domain.Repositories, actor string, book *domain.Book, stock int, reason string) error {
+	if stock == book.Stock {
+		return nil
+	}
+	branch, err := resolveBranch(repos, 0)
+	if err != nil {
+		return err
+	}
+
+	stocks, getErr := repos.Branches.GetAvailability(int(book.ID))
+	if getErr != domain.NilRepoErrPtr {
+		return RepoErrorToServiceError(getErr)
+	}
+	for _, branchStock := range stocks {
+		if branchStock.BranchID != branch.ID {
+			return &ServiceError{
+				Type:    InvalidArguments,
+				Message: "stock of book is split across branches, correct stock of each branch instead"}
+		}
+	}
+
+	_, err = applyLockedMovement(repos, actor, book, domain.StockMovement{
+		BranchID: branch.ID,
+		Kind:     domain.MovementCorrection,
+		Quantity: stock - book.Stock,
+		Reason:   reason})
+	return err
+}
+
+// resolveBranch loads branch by id, 0 resolves to default branch
+func resolveBranch(repos domain.Repositories, branchID uint) (*domain.Branch, error) {
+	var branch *domain.Branch
+	var err error
+	if branchID == 0 {
+		branch, err = repos.Branches.GetDefault()
+	} else {
+		branch, err = repos.Branches.GetByID(int(branchID))
+	}
+	if err != domain.NilRepoErrPtr {
+		return nil, RepoErrorToServiceError(err)
+	}
+	return branch, nil
+}
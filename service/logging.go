@@ -0,0 +1,22 @@
+package service
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/logging"
+	log "github.com/sirupsen/logrus"
+)
+
+// logEntry returns entry, entry of standard logger when it is nil
+func logEntry(entry *log.Entry) *log.Entry {
+	if entry == nil {
+		return log.NewEntry(log.StandardLogger())
+	}
+	return entry
+}
+
+func rentFields(rent *domain.RentDetails) log.Fields {
+	return log.Fields{
+		logging.RentIDField: rent.ID,
+		logging.UserIDField: rent.UserID,
+		logging.BookIDField: rent.BookID}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/idj1997/book-rent-core/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Observer is told outcome and duration of every service method call and result of every job run,
+// outcome is "ok" or name of ServiceErrorType
+type Observer interface {
+	ObserveCall(service string, method string, outcome string, duration time.Duration)
+	ObserveJob(job string, outcome string, processed int)
+}
+
+// Jobs observed by Observer
+const (
+	ExpireRentsJob   = "expire_rents"
+	DeclareLostJob   = "declare_lost"
+	SendRemindersJob = "send_reminders"
+)
+
+type observerHolder struct {
+	Observer
+}
+
+var observer atomic.Value
+
+// SetObserver sets observer of all services, nil stops observing
+func SetObserver(o Observer) {
+	observer.Store(observerHolder{o})
+}
+
+func currentObserver() Observer {
+	holder, _ := observer.Load().(observerHolder)
+	return holder.Observer
+}
+
+// Outcome names result of call returning err
+func Outcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if serviceErr, ok := err.(*ServiceError); ok {
+		return serviceErr.Type.String()
+	}
+	return Unknown.String()
+}
+
+// call is service method call in progress
+type call struct {
+	service string
+	method  string
+	start   time.Time
+	span    trace.Span
+	// ctx carries span of call, repositories used by call are bound to it
+	ctx context.Context
+}
+
+// startCall starts span of method as child of span carried by ctx
+func startCall(ctx context.Context, service string, method string) call {
+	ctx, span := tracing.Start(ctx, service+"."+method)
+	return call{service: service, method: method, start: time.Now(), span: span, ctx: ctx}
+}
+
+// observe reports call and ends its span, deferred with pointer to its error result
+func observe(c call, err *error) {
+	outcome := Outcome(*err)
+	tracing.RecordError(c.span, *err)
+	c.span.SetAttributes(attribute.String("outcome", outcome))
+	c.span.End()
+	if o := currentObserver(); o != nil {
+		o.ObserveCall(c.service, c.method, outcome, time.Since(c.start))
+	}
+}
+
+func observeJob(job string, processed int, err error) {
+	if o := currentObserver(); o != nil {
+		o.ObserveJob(job, Outcome(err), processed)
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReminderTemplate renders subject and body of a reminder from ReminderData
+type ReminderTemplate struct {
+	Subject *template.Template
+	Body    *template.Template
+}
+
+type ReminderData struct {
+	Firstname      string
+	Lastname       string
+	BookTitle      string
+	ReturnDeadline time.Time
+	DaysLeft       int
+}
+
+var DefaultReminderTemplates = map[domain.ReminderKind]ReminderTemplate{
+	domain.DueReminder: {
+		Subject: template.Must(template.New("dueSubject").Parse(
+			`Reminder: "{{.BookTitle}}" is due in {{.DaysLeft}} day(s)`)),
+		Body: template.Must(template.New("dueBody").Parse(
+			"Hello {{.Firstname}} {{.Lastname}},\n\n" +
+				"please return \"{{.BookTitle}}\" by {{.ReturnDeadline.Format \"02 Jan 2006\"}}.\n")),
+	},
+	domain.OverdueNotice: {
+		Subject: template.Must(template.New("overdueSubject").Parse(
+			`Overdue: "{{.BookTitle}}"`)),
+		Body: template.Must(template.New("overdueBody").Parse(
+			"Hello {{.Firstname}} {{.Lastname}},\n\n" +
+				"\"{{.BookTitle}}\" was due on {{.ReturnDeadline.Format \"02 Jan 2006\"}}, please return it as soon as possible.\n")),
+	},
+}
+
+type ReminderService struct {
+	RentRepo     domain.RentDetailsRepository
+	ReminderRepo domain.ReminderRepository
+	Notifier     domain.Notifier
+	// DaysAhead lists how many days before deadline due reminders are sent
+	DaysAhead []int
+	// Templates overrides DefaultReminderTemplates when set, kinds it lacks use default templates
+	Templates map[domain.ReminderKind]ReminderTemplate
+	// Log carries operation scoped fields, standard logger when nil
+	Log *log.Entry
+	// Ctx carries span calls are traced under, background when nil
+	Ctx context.Context
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (s *ReminderService) WithContext(ctx context.Context) *ReminderService {
+	contextService := *s
+	contextService.Ctx = ctx
+	return &contextService
+}
+
+// start starts span of method and returns copy of service whose repositories run under it
+func (s *ReminderService) start(method string) (*ReminderService, call) {
+	c := startCall(s.Ctx, "ReminderService", method)
+	callService := *s
+	callService.Ctx = c.ctx
+	callService.RentRepo = domain.RentDetailsRepositoryWithContext(c.ctx, s.RentRepo)
+	callService.ReminderRepo = domain.ReminderRepositoryWithContext(c.ctx, s.ReminderRepo)
+	return &callService, c
+}
+
+// SendReminders sends due reminders for rents approaching deadline and overdue notices
+// for expired rents, each reminder is sent at most once, returns number of sent reminders
+func (s *ReminderService) SendReminders() (_ int, err error) {
+	s, c := s.start("SendReminders")
+	defer observe(c, &err)
+	now := time.Now()
+	sent := 0
+	defer func() {
+		observeJob(SendRemindersJob, sent, err)
+	}()
+
+	// closest window wins, so a rent gets only the most urgent pending reminder
+	days := append([]int(nil), s.DaysAhead...)
+	sort.Ints(days)
+	handled := make(map[uint]bool)
+	for _, daysAhead := range days {
+		rents, err := s.RentRepo.GetByStatusAndDeadline(domain.RENTED, now, now.Add(time.Duration(daysAhead)*24*time.Hour))
+		if err != domain.NilRepoErrPtr {
+			return sent, RepoErrorToServiceError(err)
+		}
+
+		for _, rent := range rents {
+			if handled[rent.ID] {
+				continue
+			}
+			handled[rent.ID] = true
+
+			ok, sendErr := s.remind(rent, domain.DueReminder, daysAhead, now)
+			if sendErr != nil {
+				return sent, sendErr
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	expired, err := s.RentRepo.GetByStatusAndDeadline(domain.EXPIRED, time.Time{}, now)
+	if err != domain.NilRepoErrPtr {
+		return sent, RepoErrorToServiceError(err)
+	}
+	for _, rent := range expired {
+		ok, sendErr := s.remind(rent, domain.OverdueNotice, 0, now)
+		if sendErr != nil {
+			return sent, sendErr
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// remind claims reminder record before sending so concurrent runs never send it twice,
+// claim is released when sending fails to retry on next run, reports whether reminder was sent
+func (s *ReminderService) remind(rent domain.RentDetails, kind domain.ReminderKind, daysBefore int, now time.Time) (bool, error) {
+	if rent.User.Email == "" {
+		return false, nil
+	}
+
+	reminder := domain.SentReminder{
+		RentID:     rent.ID,
+		Kind:       kind,
+		DaysBefore: daysBefore,
+		Email:      rent.User.Email,
+		SentAt:     now}
+	err := s.ReminderRepo.Create(&reminder)
+	if err != domain.NilRepoErrPtr {
+		if err.(*domain.RepoError).Type == domain.UniqueConstraint {
+			return false, nil // already sent
+		}
+		return false, RepoErrorToServiceError(err)
+	}
+
+	notification, sendErr := s.render(rent, kind, now)
+	if sendErr == nil {
+		sendErr = s.Notifier.Send(notification)
+	}
+	if sendErr != nil {
+		logEntry(s.Log).WithFields(rentFields(&rent)).Warnf("error while sending %s reminder: %v", kind, sendErr)
+		err = s.ReminderRepo.Delete(reminder.ID)
+		return false, RepoErrorToServiceError(err)
+	}
+	return true, nil
+}
+
+func (s *ReminderService) render(rent domain.RentDetails, kind domain.ReminderKind, now time.Time) (domain.Notification, error) {
+	templates := s.Templates
+	if templates == nil {
+		templates = DefaultReminderTemplates
+	}
+	tmpl := templates[kind]
+	if tmpl.Subject == nil || tmpl.Body == nil {
+		tmpl = DefaultReminderTemplates[kind]
+	}
+	if tmpl.Subject == nil || tmpl.Body == nil {
+		return domain.Notification{}, fmt.Errorf("no template for %s reminder", kind)
+	}
+
+	data := ReminderData{
+		Firstname:      rent.User.Firstname,
+		Lastname:       rent.User.Lastname,
+		BookTitle:      rent.Book.Title,
+		ReturnDeadline: rent.ReturnDeadline,
+		DaysLeft:       int(math.Ceil(rent.ReturnDeadline.Sub(now).Hours() / 24))}
+
+	var subject, body bytes.Buffer
+	err := tmpl.Subject.Execute(&subject, data)
+	if err != nil {
+		return domain.Notification{}, err
+	}
+	err = tmpl.Body.Execute(&body, data)
+	if err != nil {
+		return domain.Notification{}, err
+	}
+
+	return domain.Notification{
+		To:      rent.User.Email,
+		Subject: subject.String(),
+		Body:    body.String()}, nil
+}
@@ -1,106 +1,367 @@
 package service
 
 import (
+	"context"
+	"fmt"
 	"github.com/idj1997/book-rent-core/domain"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type RentDetailsService struct {
 	RentRepo domain.RentDetailsRepository
 	BookRepo domain.BookRepository
+	Tx       domain.Transactor
+	Actor    string
+	// Log carries request scoped fields, standard logger when nil
+	Log *log.Entry
+	// Ctx carries span calls are traced under, background when nil
+	Ctx context.Context
+}
+
+// WithActor returns copy of service recording changes in audit trail under actor
+func (r *RentDetailsService) WithActor(actor string) *RentDetailsService {
+	actorService := *r
+	actorService.Actor = actor
+	return &actorService
+}
+
+// WithLogger returns copy of service logging with entry
+func (r *RentDetailsService) WithLogger(entry *log.Entry) *RentDetailsService {
+	loggerService := *r
+	loggerService.Log = entry
+	return &loggerService
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (r *RentDetailsService) WithContext(ctx context.Context) *RentDetailsService {
+	contextService := *r
+	contextService.Ctx = ctx
+	return &contextService
+}
+
+// start starts span of method and returns copy of service whose repositories run under it
+func (r *RentDetailsService) start(method string) (*RentDetailsService, call) {
+	c := startCall(r.Ctx, "RentDetailsService", method)
+	callService := *r
+	callService.Ctx = c.ctx
+	callService.RentRepo = domain.RentDetailsRepositoryWithContext(c.ctx, r.RentRepo)
+	callService.BookRepo = domain.BookRepositoryWithContext(c.ctx, r.BookRepo)
+	callService.Tx = domain.TransactorWithContext(c.ctx, r.Tx)
+	return &callService, c
 }
 
-func (r *RentDetailsService) GetByID(id int) (*domain.RentDetails, error) {
+func (r *RentDetailsService) GetByID(id int) (_ *domain.RentDetails, err error) {
+	r, c := r.start("GetByID")
+	defer observe(c, &err)
 	rent, err := r.RentRepo.GetByID(id)
 	return rent, RepoErrorToServiceError(err)
 }
 
-func (r *RentDetailsService) RentBook(rent *domain.RentDetails) error {
-	book, getBookErr := r.BookRepo.GetByID(rent.BookID)
-	if getBookErr != domain.NilRepoErrPtr {
-		return RepoErrorToServiceError(getBookErr)
-	}
+func (r *RentDetailsService) RentBook(rent *domain.RentDetails) (err error) {
+	r, c := r.start("RentBook")
+	defer observe(c, &err)
+	err = r.Tx.Transaction(func(repos domain.Repositories) error {
+		book, getBookErr := repos.Books.GetByID(rent.BookID)
+		if getBookErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(getBookErr)
+		}
 
-	if book.Stock <= 0 {
-		return &ServiceError{Type: NotEnoughBooksOnStock}
-	}
+		branch, err := resolveBranch(repos, rent.BranchID)
+		if err != nil {
+			return err
+		}
 
-	rent.CreatedAt = time.Now()
-	rent.ReturnDeadline = time.Now().Add(30 * 24 * time.Hour)
-	createRentErr := r.RentRepo.Create(rent)
-	if createRentErr != domain.NilRepoErrPtr {
-		return RepoErrorToServiceError(createRentErr)
-	}
+		branchStock, getStockErr := repos.Branches.GetStock(rent.BookID, int(branch.ID))
+		if getStockErr != domain.NilRepoErrPtr {
+			if getStockErr.(*domain.RepoError).Type == domain.NotFound {
+				return &ServiceError{Type: NotEnoughBooksOnStock}
+			}
+			return RepoErrorToServiceError(getStockErr)
+		}
+		if branchStock.Stock <= 0 {
+			return &ServiceError{Type: NotEnoughBooksOnStock}
+		}
+
+		rent.BranchID = branch.ID
+		rent.CreatedAt = time.Now()
+		rent.ReturnDeadline = time.Now().Add(30 * 24 * time.Hour)
+		createRentErr := repos.Rents.Create(rent)
+		if createRentErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(createRentErr)
+		}
 
-	stockUpdate := make(map[string]interface{})
-	stockUpdate["stock"] = book.Stock - 1
+		err = audit(repos, r.Actor, rent, rent.ID, domain.AuditCreate, createChanges(rent))
+		if err != nil {
+			return err
+		}
 
-	updateStockErr := r.BookRepo.Update(book, stockUpdate)
-	if updateStockErr != domain.NilRepoErrPtr {
-		return RepoErrorToServiceError(updateStockErr)
-	}
+		err = moveStock(repos, r.Actor, book, domain.StockMovement{
+			BranchID: branch.ID,
+			Kind:     domain.MovementRentOut,
+			Quantity: -1,
+			RentID:   rent.ID})
+		if err != nil {
+			return err
+		}
 
-	return NilServiceErrPtr
+		return publish(repos, domain.BookRented, domain.BookRentedPayload{
+			RentID:         rent.ID,
+			BookID:         rent.BookID,
+			UserID:         rent.UserID,
+			ReturnDeadline: rent.ReturnDeadline})
+	})
+	if err != nil {
+		return TransactionErrorToServiceError(err)
+	}
+	logEntry(r.Log).WithFields(rentFields(rent)).Infof("book rented at branch %d", rent.BranchID)
+	return nil
 }
 
-func (r *RentDetailsService) ReturnBook(rentDetailsID int) error {
-	rent, getRentErr := r.RentRepo.GetByID(rentDetailsID)
-	if getRentErr != domain.NilRepoErrPtr {
-		return RepoErrorToServiceError(getRentErr)
-	}
+// ReturnBook returns book to branch, which may differ from branch it was rented from,
+// 0 returns it to branch it was rented from
+func (r *RentDetailsService) ReturnBook(rentDetailsID int, branchID int) (err error) {
+	r, c := r.start("ReturnBook")
+	defer observe(c, &err)
+	var rent *domain.RentDetails
+	var returnBranchID uint
+	err = r.Tx.Transaction(func(repos domain.Repositories) error {
+		var getRentErr error
+		rent, getRentErr = repos.Rents.GetByID(rentDetailsID)
+		if getRentErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(getRentErr)
+		}
 
-	if rent.Status == domain.RETURNED {
-		return &ServiceError{Type: BookAlreadyReturned}
-	}
+		if rent.Status == domain.RETURNED {
+			return &ServiceError{Type: BookAlreadyReturned}
+		}
 
-	bookUpdates := make(map[string]interface{})
-	bookUpdates["stock"] = rent.Book.Stock + 1
+		returnBranchID = uint(branchID)
+		if returnBranchID == 0 {
+			returnBranchID = rent.BranchID
+		}
+		branch, err := resolveBranch(repos, returnBranchID)
+		if err != nil {
+			return err
+		}
 
-	rentUpdates := make(map[string]interface{})
-	rentUpdates["status"] = domain.RETURNED
+		now := time.Now()
+		updates, transitionErr := rent.Transition(domain.RETURNED, now)
+		if transitionErr != nil {
+			return &ServiceError{Type: InvalidStatusTransition, Message: transitionErr.Error()}
+		}
+		updates["return_branch_id"] = branch.ID
+		err = updateRent(repos, r.Actor, rent, updates)
+		if err != nil {
+			return err
+		}
 
-	updateRentErr := r.RentRepo.Update(rent, rentUpdates)
-	if updateRentErr != domain.NilRepoErrPtr {
-		return RepoErrorToServiceError(updateRentErr)
-	}
+		err = moveStock(repos, r.Actor, &rent.Book, domain.StockMovement{
+			BranchID: branch.ID,
+			Kind:     domain.MovementReturn,
+			Quantity: 1,
+			RentID:   rent.ID})
+		if err != nil {
+			return err
+		}
 
-	updateBookErr := r.BookRepo.Update(&rent.Book, bookUpdates)
-	if updateBookErr != domain.NilRepoErrPtr {
-		return RepoErrorToServiceError(updateBookErr)
+		return publish(repos, domain.BookReturned, domain.BookReturnedPayload{
+			RentID:     rent.ID,
+			BookID:     rent.BookID,
+			UserID:     rent.UserID,
+			ReturnedAt: now})
+	})
+	if err != nil {
+		return TransactionErrorToServiceError(err)
 	}
-
-	return NilServiceErrPtr
+	logEntry(r.Log).WithFields(rentFields(rent)).Infof("book returned at branch %d", returnBranchID)
+	return nil
 }
 
-func (r *RentDetailsService) GetByUser(userID int) ([]domain.RentDetails, error) {
+func (r *RentDetailsService) GetByUser(userID int) (_ []domain.RentDetails, err error) {
+	r, c := r.start("GetByUser")
+	defer observe(c, &err)
 	rents, err := r.RentRepo.GetByUser(userID)
 	return rents, RepoErrorToServiceError(err)
 }
 
-func (r *RentDetailsService) GetByBook(bookID int) ([]domain.RentDetails, error) {
+func (r *RentDetailsService) GetByBook(bookID int) (_ []domain.RentDetails, err error) {
+	r, c := r.start("GetByBook")
+	defer observe(c, &err)
 	rents, err := r.RentRepo.GetByBook(bookID)
 	return rents, RepoErrorToServiceError(err)
 }
 
-func (r *RentDetailsService) GetByStatus(status domain.RentDetailsStatus) ([]domain.RentDetails, error) {
+func (r *RentDetailsService) GetByStatus(status domain.RentDetailsStatus) (_ []domain.RentDetails, err error) {
+	r, c := r.start("GetByStatus")
+	defer observe(c, &err)
 	rents, err := r.RentRepo.GetByStatus(status)
 	return rents, RepoErrorToServiceError(err)
 }
 
-func (r *RentDetailsService) UpdateToExpired() error {
-	stream := make(chan domain.RentDetails)
-	updates := make(map[string]interface{})
-	updates["status"] = domain.EXPIRED
+// StreamActiveRents calls fn with every rented or expired rent, stopping at first error of fn,
+// which is returned as it is, or once Ctx is done, whose error is returned
+func (r *RentDetailsService) StreamActiveRents(fn func(rent domain.RentDetails) error) (err error) {
+	r, c := r.start("StreamActiveRents")
+	defer observe(c, &err)
+	rents, stop := r.activeRents()
 
-	go r.RentRepo.RentDetailsIterator(stream)
+	for rent := range rents {
+		if rent.Status == domain.RENTED || rent.Status == domain.EXPIRED {
+			err = fn(rent)
+			if err != nil {
+				_ = stop()
+				return err
+			}
+		}
+	}
+	return stop()
+}
+
+// activeRents starts iterating rents not returned yet until Ctx is done, stop cancels iteration,
+// drains remaining rents and returns error of iterator, error of Ctx when it is done
+func (r *RentDetailsService) activeRents() (<-chan domain.RentDetails, func() error) {
+	parent := r.Ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	rents := make(chan domain.RentDetails)
+	iterated := make(chan error, 1)
+	go func() {
+		iterated <- r.RentRepo.RentDetailsIterator(ctx, rents)
+	}()
+
+	return rents, func() error {
+		cancel()
+		drain(rents)
+		err := <-iterated
+		if parent.Err() != nil {
+			return parent.Err()
+		}
+		return RepoErrorToServiceError(err)
+	}
+}
+
+func (r *RentDetailsService) UpdateToExpired() (err error) {
+	r, c := r.start("UpdateToExpired")
+	defer observe(c, &err)
+	expiredCount := 0
+	defer func() {
+		observeJob(ExpireRentsJob, expiredCount, err)
+	}()
+	stream, stop := r.activeRents()
 	for rent := range stream {
-		if rent.Status == domain.RENTED && rent.ReturnDeadline.Before(time.Now()) {
-			err := r.RentRepo.Update(&rent, updates)
-			if err != domain.NilRepoErrPtr {
-				return RepoErrorToServiceError(err)
+		now := time.Now()
+		if rent.Status == domain.RENTED && rent.ReturnDeadline.Before(now) {
+			expired := rent
+			err := r.Tx.Transaction(func(repos domain.Repositories) error {
+				err := transitionRent(repos, r.Actor, &expired, domain.EXPIRED, now)
+				if err != nil {
+					return err
+				}
+
+				return publish(repos, domain.RentExpired, domain.RentExpiredPayload{
+					RentID:         expired.ID,
+					BookID:         expired.BookID,
+					UserID:         expired.UserID,
+					ReturnDeadline: expired.ReturnDeadline})
+			})
+			if isStatusChanged(err) {
+				logEntry(r.Log).WithFields(rentFields(&expired)).Info("rent changed before it expired, skipped")
+				continue
 			}
+			if err != nil {
+				_ = stop()
+				return TransactionErrorToServiceError(err)
+			}
+			expiredCount++
+			logEntry(r.Log).WithFields(rentFields(&expired)).Info("rent expired")
+		}
+	}
+
+	return stop()
+}
+
+func (r *RentDetailsService) DeclareLost(rentDetailsID int, outcome domain.RentOutcome) (err error) {
+	r, c := r.start("DeclareLost")
+	defer observe(c, &err)
+	return r.declare(rentDetailsID, domain.LOST, outcome)
+}
+
+func (r *RentDetailsService) DeclareDamaged(rentDetailsID int, outcome domain.RentOutcome) (err error) {
+	r, c := r.start("DeclareDamaged")
+	defer observe(c, &err)
+	return r.declare(rentDetailsID, domain.DAMAGED, outcome)
+}
+
+func (r *RentDetailsService) declare(rentDetailsID int, status domain.RentDetailsStatus, outcome domain.RentOutcome) error {
+	var rent *domain.RentDetails
+	err := r.Tx.Transaction(func(repos domain.Repositories) error {
+		var getRentErr error
+		rent, getRentErr = repos.Rents.GetByID(rentDetailsID)
+		if getRentErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(getRentErr)
 		}
+		return settleRent(repos, r.Actor, rent, status, outcome, time.Now())
+	})
+	if err != nil {
+		return TransactionErrorToServiceError(err)
+	}
+	logEntry(r.Log).WithFields(rentFields(rent)).Infof("rent declared %s", status)
+	return nil
+}
+
+// UpdateToLost declares lost, with replacement fee charged, expired rents
+// whose return deadline passed more than overdueFor ago
+func (r *RentDetailsService) UpdateToLost(overdueFor time.Duration) (err error) {
+	r, c := r.start("UpdateToLost")
+	defer observe(c, &err)
+	if overdueFor <= 0 {
+		return &ServiceError{Type: InvalidArguments}
+	}
+
+	lostCount := 0
+	defer func() {
+		observeJob(DeclareLostJob, lostCount, err)
+	}()
+	now := time.Now()
+	rents, err := r.RentRepo.GetByStatusAndDeadline(domain.EXPIRED, time.Time{}, now.Add(-overdueFor))
+	if err != domain.NilRepoErrPtr {
+		return RepoErrorToServiceError(err)
 	}
 
+	outcome := domain.RentOutcome{
+		ChargeFee: true,
+		Note:      fmt.Sprintf("declared lost after being overdue for %s", overdueFor)}
+	for i := range rents {
+		rent := &rents[i]
+		txErr := r.Tx.Transaction(func(repos domain.Repositories) error {
+			return settleRent(repos, r.Actor, rent, domain.LOST, outcome, now)
+		})
+		if isStatusChanged(txErr) {
+			logEntry(r.Log).WithFields(rentFields(rent)).Info("rent changed before it was declared lost, skipped")
+			continue
+		}
+		if txErr != nil {
+			return TransactionErrorToServiceError(txErr)
+		}
+		lostCount++
+		logEntry(r.Log).WithFields(rentFields(rent)).Infof("rent declared %s after being overdue", domain.LOST)
+	}
 	return nil
 }
+
+// isStatusChanged reports whether transition failed because rent read earlier no longer has
+// status it was read with
+func isStatusChanged(err error) bool {
+	serviceErr, ok := err.(*ServiceError)
+	return ok && serviceErr.Type == InvalidStatusTransition
+}
+
+// drain consumes remaining rents so iterator goroutine can finish
+func drain(stream <-chan domain.RentDetails) {
+	for range stream {
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+
+	"github.com/idj1997/book-rent-core/domain"
+)
+
+// DefaultReportLimit caps rows per period of ranked reports when query sets no limit
+const DefaultReportLimit = 10
+
+type ReportService struct {
+	Repo domain.ReportRepository
+	// Ctx carries span calls are traced under, background when nil
+	Ctx context.Context
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (s *ReportService) WithContext(ctx context.Context) *ReportService {
+	contextService := *s
+	contextService.Ctx = ctx
+	return &contextService
+}
+
+// start starts span of method and returns copy of service whose repositories run under it
+func (s *ReportService) start(method string) (*ReportService, call) {
+	c := startCall(s.Ctx, "ReportService", method)
+	callService := *s
+	callService.Ctx = c.ctx
+	callService.Repo = domain.ReportRepositoryWithContext(c.ctx, s.Repo)
+	return &callService, c
+}
+
+func (s *ReportService) MostRentedBooks(query domain.ReportQuery) (_ []domain.BookRentCount, err error) {
+	s, c := s.start("MostRentedBooks")
+	defer observe(c, &err)
+	query, err = validReportQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, repoErr := s.Repo.MostRentedBooks(query)
+	return counts, RepoErrorToServiceError(repoErr)
+}
+
+func (s *ReportService) OverdueRates(query domain.ReportQuery) (_ []domain.OverdueRate, err error) {
+	s, c := s.start("OverdueRates")
+	defer observe(c, &err)
+	query, err = validReportQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, repoErr := s.Repo.OverdueRates(query)
+	if repoErr != domain.NilRepoErrPtr {
+		return nil, RepoErrorToServiceError(repoErr)
+	}
+	for i := range rates {
+		if rates[i].Rents > 0 {
+			rates[i].Rate = float64(rates[i].Overdue) / float64(rates[i].Rents)
+		}
+	}
+	return rates, nil
+}
+
+func (s *ReportService) LoanDurations(query domain.ReportQuery) (_ []domain.LoanDuration, err error) {
+	s, c := s.start("LoanDurations")
+	defer observe(c, &err)
+	query, err = validReportQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	durations, repoErr := s.Repo.LoanDurations(query)
+	return durations, RepoErrorToServiceError(repoErr)
+}
+
+func (s *ReportService) ActiveBorrowers(query domain.ReportQuery) (_ []domain.ActiveBorrowers, err error) {
+	s, c := s.start("ActiveBorrowers")
+	defer observe(c, &err)
+	query, err = validReportQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	borrowers, repoErr := s.Repo.ActiveBorrowers(query)
+	return borrowers, RepoErrorToServiceError(repoErr)
+}
+
+// validReportQuery requires closed date range and known grouping, defaulting limit
+func validReportQuery(query domain.ReportQuery) (domain.ReportQuery, error) {
+	if query.From.IsZero() || query.To.IsZero() || !query.From.Before(query.To) {
+		return query, &ServiceError{Type: InvalidArguments, Message: "invalid report date range"}
+	}
+
+	switch query.GroupBy {
+	case domain.GroupByDay, domain.GroupByWeek, domain.GroupByMonth:
+	case "":
+		query.GroupBy = domain.GroupByDay
+	default:
+		return query, &ServiceError{Type: InvalidArguments, Message: "invalid report grouping"}
+	}
+
+	if query.Limit < 0 {
+		return query, &ServiceError{Type: InvalidArguments, Message: "invalid report limit"}
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultReportLimit
+	}
+	return query, nil
+}
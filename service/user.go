@@ -1,38 +1,252 @@
 package service
 
 import (
+	"context"
 	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/logging"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	Repo domain.UserRepository
+	Repo  domain.UserRepository
+	Tx    domain.Transactor
+	Actor string
+	// Log carries request scoped fields, standard logger when nil
+	Log *log.Entry
+	// Ctx carries span calls are traced under, background when nil
+	Ctx context.Context
+}
+
+// WithActor returns copy of service recording changes in audit trail under actor
+func (u *UserService) WithActor(actor string) *UserService {
+	actorService := *u
+	actorService.Actor = actor
+	return &actorService
+}
+
+// WithLogger returns copy of service logging with entry
+func (u *UserService) WithLogger(entry *log.Entry) *UserService {
+	loggerService := *u
+	loggerService.Log = entry
+	return &loggerService
+}
+
+// WithContext returns copy of service tracing calls as children of span carried by ctx
+func (u *UserService) WithContext(ctx context.Context) *UserService {
+	contextService := *u
+	contextService.Ctx = ctx
+	return &contextService
 }
 
-func (u *UserService) GetByID(id int) (*domain.User, error) {
+// start starts span of method and returns copy of service whose repositories run under it
+func (u *UserService) start(method string) (*UserService, call) {
+	c := startCall(u.Ctx, "UserService", method)
+	callService := *u
+	callService.Ctx = c.ctx
+	callService.Repo = domain.UserRepositoryWithContext(c.ctx, u.Repo)
+	callService.Tx = domain.TransactorWithContext(c.ctx, u.Tx)
+	return &callService, c
+}
+
+func (u *UserService) GetByID(id int) (_ *domain.User, err error) {
+	u, c := u.start("GetByID")
+	defer observe(c, &err)
 	user, err := u.Repo.GetByID(id)
 	return user, RepoErrorToServiceError(err)
 }
 
-func (u *UserService) GetByEmail(email string) (*domain.User, error) {
+// GetByIDs returns users with given IDs in one query, missing IDs are skipped
+func (u *UserService) GetByIDs(ids []int) (_ []domain.User, err error) {
+	u, c := u.start("GetByIDs")
+	defer observe(c, &err)
+	if len(ids) == 0 {
+		return []domain.User{}, nil
+	}
+	users, err := u.Repo.GetByIDs(ids)
+	return users, RepoErrorToServiceError(err)
+}
+
+func (u *UserService) GetByEmail(email string) (_ *domain.User, err error) {
+	u, c := u.start("GetByEmail")
+	defer observe(c, &err)
 	user, err := u.Repo.GetByEmail(email)
 	return user, RepoErrorToServiceError(err)
 }
 
-func (u *UserService) GetByFirstnameAndLastname(firstname string, lastname string) ([]domain.User, error) {
-	panic("implement me")
+func (u *UserService) GetByFirstnameAndLastname(firstname string, lastname string) (_ []domain.User, err error) {
+	u, c := u.start("GetByFirstnameAndLastname")
+	defer observe(c, &err)
+	if strings.TrimSpace(firstname) == "" && strings.TrimSpace(lastname) == "" {
+		return nil, &ServiceError{Type: InvalidArguments}
+	}
+
+	users, err := u.Repo.GetByFirstnameAndLastname(firstname, lastname)
+	return users, RepoErrorToServiceError(err)
+}
+
+func (u *UserService) Search(query string) (_ []domain.User, err error) {
+	u, c := u.start("Search")
+	defer observe(c, &err)
+	if strings.TrimSpace(query) == "" {
+		return nil, &ServiceError{Type: InvalidArguments}
+	}
+
+	users, err := u.Repo.Search(query)
+	return users, RepoErrorToServiceError(err)
+}
+
+// Create stores user with password replaced by its bcrypt hash, empty password is rejected
+func (u *UserService) Create(user *domain.User) (err error) {
+	u, c := u.start("Create")
+	defer observe(c, &err)
+	if user.Password == "" {
+		return &ServiceError{Type: InvalidArguments, Message: "password is required"}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return &ServiceError{Type: Unknown, Message: err.Error()}
+	}
+	user.Password = string(hash)
+
+	err = u.Tx.Transaction(func(repos domain.Repositories) error {
+		createErr := repos.Users.Create(user)
+		if createErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(createErr)
+		}
+
+		err := audit(repos, u.Actor, user, user.ID, domain.AuditCreate, createChanges(user))
+		if err != nil {
+			return err
+		}
+
+		return publish(repos, domain.UserCreated, domain.UserCreatedPayload{
+			UserID: user.ID,
+			Email:  user.Email})
+	})
+	if err != nil {
+		return TransactionErrorToServiceError(err)
+	}
+	logEntry(u.Log).WithField(logging.UserIDField, user.ID).Info("user created")
+	return nil
 }
 
-func (u *UserService) Create(user *domain.User) error {
-	err := u.Repo.Create(user)
-	return RepoErrorToServiceError(err)
+// Delete soft deletes user, rejected with ActiveBookRents while user holds any book
+func (u *UserService) Delete(id int) (err error) {
+	u, c := u.start("Delete")
+	defer observe(c, &err)
+	err = u.Tx.Transaction(func(repos domain.Repositories) error {
+		user, rents, err := userActiveRents(repos, id)
+		if err != nil {
+			return err
+		}
+		if len(rents) > 0 {
+			return &ServiceError{Type: ActiveBookRents}
+		}
+
+		return u.delete(repos, user, id)
+	})
+	if err != nil {
+		return TransactionErrorToServiceError(err)
+	}
+	logEntry(u.Log).WithField(logging.UserIDField, id).Info("user deleted")
+	return nil
 }
 
-func (u *UserService) Delete(id int) error {
-	_, err := u.GetByID(id)
+// ForceDelete closes active rents of user as RETURNED or LOST and deletes user in one transaction
+func (u *UserService) ForceDelete(id int, closeAs domain.RentDetailsStatus) (err error) {
+	u, c := u.start("ForceDelete")
+	defer observe(c, &err)
+	if !validCloseStatus(closeAs) {
+		return &ServiceError{Type: InvalidArguments}
+	}
+
+	err = u.Tx.Transaction(func(repos domain.Repositories) error {
+		user, rents, err := userActiveRents(repos, id)
+		if err != nil {
+			return err
+		}
+
+		err = closeRents(repos, u.Actor, rents, closeAs)
+		if err != nil {
+			return err
+		}
+
+		return u.delete(repos, user, id)
+	})
 	if err != nil {
-		return err
+		return TransactionErrorToServiceError(err)
+	}
+	logEntry(u.Log).WithField(logging.UserIDField, id).Infof("user deleted, active rents closed as %s", closeAs)
+	return nil
+}
+
+func (u *UserService) delete(repos domain.Repositories, user *domain.User, id int) error {
+	err := repos.Users.Delete(id)
+	if err != domain.NilRepoErrPtr {
+		return RepoErrorToServiceError(err)
 	}
 
-	err = u.Repo.Delete(id)
-	return RepoErrorToServiceError(err)
+	return audit(repos, u.Actor, user, uint(id), domain.AuditDelete, deleteChanges(user))
+}
+
+func userActiveRents(repos domain.Repositories, userID int) (*domain.User, []domain.RentDetails, error) {
+	user, err := repos.Users.GetByID(userID)
+	if err != domain.NilRepoErrPtr {
+		return nil, nil, RepoErrorToServiceError(err)
+	}
+
+	rents, err := repos.Rents.GetByUser(userID)
+	if err != domain.NilRepoErrPtr {
+		return nil, nil, RepoErrorToServiceError(err)
+	}
+	return user, activeRents(rents), nil
+}
+
+func (u *UserService) GetDeleted() (_ []domain.User, err error) {
+	u, c := u.start("GetDeleted")
+	defer observe(c, &err)
+	users, err := u.Repo.GetDeleted()
+	return users, RepoErrorToServiceError(err)
+}
+
+func (u *UserService) Restore(id int) (err error) {
+	u, c := u.start("Restore")
+	defer observe(c, &err)
+	err = u.Tx.Transaction(func(repos domain.Repositories) error {
+		restoreErr := repos.Users.Restore(id)
+		if restoreErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(restoreErr)
+		}
+
+		return audit(repos, u.Actor, domain.NilUserPtr, uint(id), domain.AuditRestore, nil)
+	})
+	return TransactionErrorToServiceError(err)
+}
+
+// PurgeDeleted permanently removes users deleted longer than retention ago, users with rent
+// history are anonymised instead
+func (u *UserService) PurgeDeleted(retention time.Duration) (_ int, err error) {
+	u, c := u.start("PurgeDeleted")
+	defer observe(c, &err)
+	if retention < 0 {
+		return 0, &ServiceError{Type: InvalidArguments}
+	}
+
+	var purged []uint
+	err = u.Tx.Transaction(func(repos domain.Repositories) error {
+		var purgeErr error
+		purged, purgeErr = repos.Users.Purge(time.Now().Add(-retention))
+		if purgeErr != domain.NilRepoErrPtr {
+			return RepoErrorToServiceError(purgeErr)
+		}
+		return auditPurged(repos, u.Actor, domain.NilUserPtr, purged)
+	})
+	if err != nil {
+		return 0, TransactionErrorToServiceError(err)
+	}
+	return len(purged), nil
 }
@@ -0,0 +1,236 @@
+package test
+
+import (
+	"context"
+	"github.com/idj1997/book-rent-core/app"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/outbox"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type AppIntegrationTestSuite struct {
+	suite.Suite
+}
+
+func TestAppIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &AppIntegrationTestSuite{})
+}
+
+func (suite *AppIntegrationTestSuite) TestNew_WithTestConfig_ExpectServicesReady() {
+	a := assert.New(suite.T())
+
+	application, err := app.New("test", "../config.yml")
+	suite.Require().Nil(err)
+
+	book, err := application.Books.GetByID(10000)
+	a.Nil(err)
+	a.Equal("title1", book.Title)
+	a.Equal(config.HealthUp, application.Health.Readiness(context.Background()).Status)
+
+	a.Nil(application.Close())
+}
+
+func (suite *AppIntegrationTestSuite) TestWithContext_ExpectServiceRepositoryAndSQLTraced() {
+	a := assert.New(suite.T())
+	application, err := app.New("test", "../config.yml")
+	suite.Require().Nil(err)
+	defer application.Close()
+	recorder, reset := recordSpans()
+	defer reset()
+
+	_, err = application.WithContext(context.Background()).Users.GetByID(10000)
+	a.Nil(err)
+
+	spans := spansByName(recorder)
+	repoSpan := spans["UserRepository.GetByID"]
+	suite.Require().NotNil(repoSpan)
+	a.Equal(spans["UserService.GetByID"].SpanContext().SpanID(), repoSpan.Parent().SpanID())
+	suite.Require().NotEmpty(repoSpan.Events())
+	a.Equal("sql", repoSpan.Events()[0].Name)
+	a.Contains(repoSpan.Events()[0].Attributes, attribute.String("db.table", "users"))
+}
+
+func (suite *AppIntegrationTestSuite) TestApplyPolicies_WithRentOverdueInOtherTenant_ExpectDeclaredLost() {
+	a := assert.New(suite.T())
+	application, err := app.New("test", "../config.yml")
+	suite.Require().Nil(err)
+	defer application.Close()
+
+	// policies commit, so they run against own tenant removed afterwards
+	tenant := "policies"
+	db := repository.ForTenant(application.DB, tenant)
+	defer func() {
+		for _, table := range []string{"audit_records", "outbox_events", "stock_movements", "rent_details", "books", "users"} {
+			application.DB.Exec("DELETE FROM "+table+" WHERE tenant_id = ?", tenant)
+		}
+	}()
+	book := domain.Book{Title: "lost", Content: "lost", Price: 1000}
+	_, err = repository.NewGormBookRepository(db).Create(&book)
+	suite.Require().Nil(err)
+	user := domain.User{Firstname: "lost", Lastname: "lost", Email: "lost@policies.com", Password: "secret"}
+	suite.Require().Nil(repository.NewGormUserRepository(db).Create(&user))
+	rent := domain.RentDetails{
+		UserID:         int(user.ID),
+		BookID:         int(book.ID),
+		Status:         domain.EXPIRED,
+		ReturnDeadline: time.Now().Add(-application.Config.Policies.LostAfter - time.Hour)}
+	suite.Require().Nil((&repository.GormRentDetailsRepository{Db: db}).Create(&rent))
+
+	a.Nil(application.ApplyPolicies())
+
+	stored, err := (&repository.GormRentDetailsRepository{Db: db}).GetByID(int(rent.ID))
+	a.Nil(err)
+	a.Equal(domain.LOST, stored.Status)
+	a.Equal(book.Price, stored.Fee)
+}
+
+func (suite *AppIntegrationTestSuite) TestApplyPolicies_WithRentedRentOverdue_ExpectExpiredAndDeclaredLost() {
+	a := assert.New(suite.T())
+	application, err := app.New("test", "../config.yml")
+	suite.Require().Nil(err)
+	defer application.Close()
+
+	tenant := "policies"
+	db := repository.ForTenant(application.DB, tenant)
+	defer func() {
+		for _, table := range []string{"audit_records", "outbox_events", "stock_movements", "rent_details", "books", "users"} {
+			application.DB.Exec("DELETE FROM "+table+" WHERE tenant_id = ?", tenant)
+		}
+	}()
+	book := domain.Book{Title: "overdue", Content: "overdue", Price: 1000}
+	_, err = repository.NewGormBookRepository(db).Create(&book)
+	suite.Require().Nil(err)
+	user := domain.User{Firstname: "overdue", Lastname: "overdue", Email: "overdue@policies.com", Password: "secret"}
+	suite.Require().Nil(repository.NewGormUserRepository(db).Create(&user))
+	rents := &repository.GormRentDetailsRepository{Db: db}
+	expired := domain.RentDetails{
+		UserID:         int(user.ID),
+		BookID:         int(book.ID),
+		Status:         domain.RENTED,
+		ReturnDeadline: time.Now().Add(-time.Hour)}
+	suite.Require().Nil(rents.Create(&expired))
+	lost := domain.RentDetails{
+		UserID:         int(user.ID),
+		BookID:         int(book.ID),
+		Status:         domain.RENTED,
+		ReturnDeadline: time.Now().Add(-application.Config.Policies.LostAfter - time.Hour)}
+	suite.Require().Nil(rents.Create(&lost))
+
+	a.Nil(application.ApplyPolicies())
+
+	stored, err := rents.GetByID(int(expired.ID))
+	a.Nil(err)
+	a.Equal(domain.EXPIRED, stored.Status)
+	stored, err = rents.GetByID(int(lost.ID))
+	a.Nil(err)
+	a.Equal(domain.LOST, stored.Status)
+}
+
+func (suite *AppIntegrationTestSuite) TestApplyPolicies_WithBookDeletedBeforeRetention_ExpectPurged() {
+	a := assert.New(suite.T())
+	application, err := app.New("test", "../config.yml")
+	suite.Require().Nil(err)
+	defer application.Close()
+
+	tenant := "policies"
+	db := repository.ForTenant(application.DB, tenant)
+	defer func() {
+		for _, table := range []string{"audit_records", "outbox_events", "stock_movements", "branch_stocks", "books", "users"} {
+			application.DB.Exec("DELETE FROM "+table+" WHERE tenant_id = ?", tenant)
+		}
+	}()
+	books := repository.NewGormBookRepository(db)
+	expired := domain.Book{Title: "purged", Content: "purged", Price: 1000}
+	_, err = books.Create(&expired)
+	suite.Require().Nil(err)
+	retained := domain.Book{Title: "retained", Content: "retained", Price: 1000}
+	_, err = books.Create(&retained)
+	suite.Require().Nil(err)
+	retention := application.Config.Policies.PurgeRetention
+	application.DB.Exec("UPDATE books SET deleted_at = ? WHERE id = ?", time.Now().Add(-retention-time.Hour), expired.ID)
+	application.DB.Exec("UPDATE books SET deleted_at = ? WHERE id = ?", time.Now().Add(-time.Hour), retained.ID)
+
+	a.Nil(application.ApplyPolicies())
+
+	var ids []uint
+	application.DB.Unscoped().Model(&domain.Book{}).Where("tenant_id = ?", tenant).Pluck("id", &ids)
+	a.Equal([]uint{retained.ID}, ids)
+}
+
+func (suite *AppIntegrationTestSuite) TestSendReminders_WithRentDueInOtherTenant_ExpectReminderSent() {
+	a := assert.New(suite.T())
+	application, err := app.New("test", "../config.yml")
+	suite.Require().Nil(err)
+	defer application.Close()
+
+	tenant := "reminders"
+	db := repository.ForTenant(application.DB, tenant)
+	rent := domain.RentDetails{}
+	defer func() {
+		application.DB.Exec("DELETE FROM sent_reminders WHERE rent_id = ?", rent.ID)
+		for _, table := range []string{"audit_records", "outbox_events", "stock_movements", "branch_stocks", "rent_details", "books", "users"} {
+			application.DB.Exec("DELETE FROM "+table+" WHERE tenant_id = ?", tenant)
+		}
+	}()
+	book := domain.Book{Title: "due", Content: "due", Price: 1000}
+	_, err = repository.NewGormBookRepository(db).Create(&book)
+	suite.Require().Nil(err)
+	user := domain.User{Firstname: "due", Lastname: "due", Email: "due@reminders.com", Password: "secret"}
+	suite.Require().Nil(repository.NewGormUserRepository(db).Create(&user))
+	rent = domain.RentDetails{
+		UserID:         int(user.ID),
+		BookID:         int(book.ID),
+		Status:         domain.RENTED,
+		ReturnDeadline: time.Now().Add(12 * time.Hour)}
+	suite.Require().Nil((&repository.GormRentDetailsRepository{Db: db}).Create(&rent))
+
+	a.Nil(application.SendReminders())
+
+	reminders, err := repository.NewGormReminderRepository(application.DB).GetByRent(rent.ID)
+	a.Nil(err)
+	suite.Require().Len(reminders, 1)
+	a.Equal(domain.DueReminder, reminders[0].Kind)
+	a.Equal(user.Email, reminders[0].Email)
+}
+
+func (suite *AppIntegrationTestSuite) TestStartOutbox_WithBookCreated_ExpectEventDelivered() {
+	a := assert.New(suite.T())
+	application, err := app.New("test", "../config.yml")
+	suite.Require().Nil(err)
+	defer application.Close()
+
+	tenant := "outbox"
+	defer func() {
+		for _, table := range []string{"audit_records", "outbox_events", "stock_movements", "branch_stocks", "books"} {
+			application.DB.Exec("DELETE FROM "+table+" WHERE tenant_id = ?", tenant)
+		}
+	}()
+	delivered := make(chan domain.OutboxEvent, 1)
+	handlers := outbox.NewHandlerSink()
+	handlers.Subscribe(domain.BookCreated, func(event domain.OutboxEvent) error {
+		if event.TenantID == tenant {
+			delivered <- event
+		}
+		return nil
+	})
+	application.Outbox.Sinks = append(application.Outbox.Sinks, handlers)
+	book := domain.Book{Title: "dispatched", Content: "dispatched", Price: 1000}
+	_, err = application.WithContext(domain.WithTenant(context.Background(), tenant)).Books.Create(&book)
+	suite.Require().Nil(err)
+
+	application.StartOutbox()
+
+	select {
+	case event := <-delivered:
+		a.Equal(domain.BookCreated, event.Type)
+	case <-time.After(10 * time.Second):
+		a.Fail("outbox event was not delivered")
+	}
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/app"
+	"github.com/idj1997/book-rent-core/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AppUnitTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestAppUnitTestSuite(t *testing.T) {
+	suite.Run(t, &AppUnitTestSuite{})
+}
+
+func (suite *AppUnitTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "app")
+	suite.Require().Nil(err)
+	suite.dir = dir
+}
+
+func (suite *AppUnitTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.dir)
+}
+
+func (suite *AppUnitTestSuite) writeConfig(content string) string {
+	path := filepath.Join(suite.dir, "config.yml")
+	suite.Require().Nil(ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func (suite *AppUnitTestSuite) TestNew_WithMissingConfig_ExpectError() {
+	a := assert.New(suite.T())
+
+	application, err := app.New("test", filepath.Join(suite.dir, "missing.yml"))
+	a.Nil(application)
+	a.NotNil(err)
+}
+
+
+func (suite *AppUnitTestSuite) TestLoadStatementsFromFile_WithMissingFile_ExpectError() {
+	a := assert.New(suite.T())
+
+	statements, err := config.LoadStatementsFromFile(filepath.Join(suite.dir, "init.sql"))
+	a.Nil(statements)
+	a.NotNil(err)
+}
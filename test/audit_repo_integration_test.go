@@ -0,0 +1,90 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type AuditRepoIntegrationTestSuite struct {
+	suite.Suite
+	Repo *repository.GormAuditRepository
+	Db   *gorm.DB
+}
+
+func TestAuditRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &AuditRepoIntegrationTestSuite{})
+}
+
+func (suite *AuditRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+}
+
+func (suite *AuditRepoIntegrationTestSuite) SetupTest() {
+	tx := suite.Db.Begin()
+	suite.Repo = repository.NewGormAuditRepository(tx)
+
+	records := []domain.AuditRecord{
+		{Actor: "admin", Entity: "Book", EntityID: 10001, Action: domain.AuditUpdate,
+			Changes:   domain.AuditChanges{"stock": {Before: 15, After: 3}},
+			CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Actor: "admin", Entity: "User", EntityID: 10000, Action: domain.AuditDelete,
+			CreatedAt: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Actor: "system", Entity: "RentDetails", EntityID: 10000, Action: domain.AuditUpdate,
+			CreatedAt: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}}
+	for i := range records {
+		_ = suite.Repo.Create(&records[i])
+	}
+}
+
+func (suite *AuditRepoIntegrationTestSuite) TearDownTest() {
+	suite.Repo.Db.Rollback()
+	suite.Repo.Db = nil
+}
+
+func (suite *AuditRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *AuditRepoIntegrationTestSuite) TestFind_WithEntityAndID_ExpectChangesRestored() {
+	a := assert.New(suite.T())
+
+	records, err := suite.Repo.Find(domain.AuditFilter{Entity: "Book", EntityID: 10001})
+	a.Nil(err)
+	a.Equal(1, len(records))
+	a.Equal("admin", records[0].Actor)
+	a.EqualValues(15, records[0].Changes["stock"].Before)
+	a.EqualValues(3, records[0].Changes["stock"].After)
+}
+
+func (suite *AuditRepoIntegrationTestSuite) TestFind_WithActor_ExpectOrderedByTime() {
+	a := assert.New(suite.T())
+
+	records, err := suite.Repo.Find(domain.AuditFilter{Actor: "admin"})
+	a.Nil(err)
+	a.Equal(2, len(records))
+	a.Equal("Book", records[0].Entity)
+	a.Equal("User", records[1].Entity)
+}
+
+func (suite *AuditRepoIntegrationTestSuite) TestFind_WithTimeRange_ExpectWithinRange() {
+	a := assert.New(suite.T())
+	filter := domain.AuditFilter{
+		From: time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}
+
+	records, err := suite.Repo.Find(filter)
+	a.Nil(err)
+	a.Equal(1, len(records))
+	a.Equal("User", records[0].Entity)
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditServiceUnitTestSuite struct {
+	suite.Suite
+	service     *service.AuditService
+	repo        *repo_mocks.MockedAuditRepository
+	bookRepo    *repo_mocks.MockedBookRepository
+	bookService *service.BookService
+}
+
+func TestAuditServiceUnitTestSuite(t *testing.T) {
+	suite.Run(t, &AuditServiceUnitTestSuite{})
+}
+
+func (suite *AuditServiceUnitTestSuite) SetupTest() {
+	suite.repo = &repo_mocks.MockedAuditRepository{}
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.service = &service.AuditService{Repo: suite.repo}
+	outboxRepo := &repo_mocks.MockedOutboxRepository{}
+	outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	movementRepo := &repo_mocks.MockedStockMovementRepository{}
+	movementRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	branchRepo := repo_mocks.NewMockedBranchRepository()
+	branchRepo.On("GetStock", mock.Anything, mock.Anything).Return(&domain.BranchStock{Stock: 100}, domain.NilRepoErrPtr)
+	branchRepo.On("GetAvailability", mock.Anything).Return([]domain.BranchStock{}, domain.NilRepoErrPtr)
+	suite.bookService = service.NewBookService(suite.bookRepo, &repo_mocks.MockedTransactor{
+		Repos: domain.Repositories{Books: suite.bookRepo, Audit: suite.repo, Outbox: outboxRepo, Movements: movementRepo, Branches: branchRepo}})
+}
+
+func (suite *AuditServiceUnitTestSuite) TestFind_WithInvertedTimeRange_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+	filter := domain.AuditFilter{From: time.Now(), To: time.Now().Add(-time.Hour)}
+
+	_, err := suite.service.Find(filter)
+	a.Error(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+}
+
+func (suite *AuditServiceUnitTestSuite) TestFind_WithFilter_ExpectRecords() {
+	a := assert.New(suite.T())
+	filter := domain.AuditFilter{Entity: "Book", Actor: "admin"}
+	records := []domain.AuditRecord{{Entity: "Book", Actor: "admin"}}
+
+	suite.repo.
+		On("Find", filter).
+		Return(records, domain.NilRepoErrPtr)
+
+	returnedRecords, err := suite.service.Find(filter)
+	a.Nil(err)
+	a.Equal(records, returnedRecords)
+}
+
+func (suite *AuditServiceUnitTestSuite) TestUpdateStock_WithActor_ExpectStockDiffRecorded() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "test", Content: "test", Stock: 10}
+	book.ID = 10001
+
+	suite.bookRepo.
+		On("GetByIDForUpdate", 10001).
+		Return(&book, domain.NilRepoErrPtr)
+
+	suite.bookRepo.
+		On("Update", &book, mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	var record *domain.AuditRecord
+	suite.repo.
+		On("Create", mock.Anything).
+		Run(func(args mock.Arguments) { record = args.Get(0).(*domain.AuditRecord) }).
+		Return(domain.NilRepoErrPtr)
+
+	_, err := suite.bookService.WithActor("admin").UpdateStock(10001, 3)
+	a.Nil(err)
+	a.NotNil(record)
+	a.Equal("admin", record.Actor)
+	a.Equal("Book", record.Entity)
+	a.Equal(uint(10001), record.EntityID)
+	a.Equal(domain.AuditUpdate, record.Action)
+	a.Equal(domain.FieldChange{Before: 10, After: 3}, record.Changes["stock"])
+}
+
+func (suite *AuditServiceUnitTestSuite) TestCreate_WithoutActor_ExpectSystemActor() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "test", Content: "test", Stock: 1}
+
+	suite.bookRepo.
+		On("Create", &book).
+		Return(uint(5), domain.NilRepoErrPtr)
+
+	var record *domain.AuditRecord
+	suite.repo.
+		On("Create", mock.Anything).
+		Run(func(args mock.Arguments) { record = args.Get(0).(*domain.AuditRecord) }).
+		Return(domain.NilRepoErrPtr)
+
+	_, err := suite.bookService.Create(&book)
+	a.Nil(err)
+	a.Equal(service.SystemActor, record.Actor)
+	a.Equal(domain.AuditCreate, record.Action)
+	a.Equal("test", record.Changes["title"].After)
+}
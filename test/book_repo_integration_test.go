@@ -5,6 +5,7 @@ import (
 	"github.com/idj1997/book-rent-core/domain"
 	"github.com/idj1997/book-rent-core/repository"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
@@ -23,8 +24,11 @@ func TestRunTestSuite(t *testing.T) {
 }
 
 func (suite *BookRepoIntegrationTestSuite) SetupSuite() {
-	config.InitConfig("test", "../config.yml")
-	suite.Db = config.OpenPostgresDB()
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
 }
 
 func (suite *BookRepoIntegrationTestSuite) SetupTest() {
@@ -41,7 +45,7 @@ func (suite *BookRepoIntegrationTestSuite) TearDownTest() {
 }
 
 func (suite *BookRepoIntegrationTestSuite) TearDownSuite() {
-	config.ClosePostgresDB(suite.Db)
+	suite.Nil(config.ClosePostgresDB(suite.Db))
 }
 
 func (suite *BookRepoIntegrationTestSuite) TestGetByID_WithInvalidID_ExpectNotFound() {
@@ -186,3 +190,101 @@ func (suite *BookRepoIntegrationTestSuite) TestDelete_WithValidID_ExpectDeleted(
 	a.NotNil(err)
 	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
 }
+
+func (suite *BookRepoIntegrationTestSuite) TestGetDeleted_WithDeletedBook_ExpectListed() {
+	a := assert.New(suite.T())
+	const ID int = 10000
+
+	err := suite.Repo.Delete(ID)
+	a.Nil(err)
+
+	books, err := suite.Repo.GetDeleted()
+	a.Nil(err)
+	a.Equal(1, len(books))
+	a.Equal(uint(ID), books[0].ID)
+}
+
+func (suite *BookRepoIntegrationTestSuite) TestRestore_WithDeletedBook_ExpectRestored() {
+	a := assert.New(suite.T())
+	const ID int = 10000
+
+	err := suite.Repo.Delete(ID)
+	a.Nil(err)
+
+	err = suite.Repo.Restore(ID)
+	a.Nil(err)
+
+	book, err := suite.Repo.GetByID(ID)
+	a.Nil(err)
+	a.Equal(uint(ID), book.ID)
+}
+
+func (suite *BookRepoIntegrationTestSuite) TestRestore_WithActiveBook_ExpectNotFound() {
+	a := assert.New(suite.T())
+
+	err := suite.Repo.Restore(10000)
+	a.Error(err)
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+}
+
+func (suite *BookRepoIntegrationTestSuite) TestPurge_WithDeletedBooks_ExpectRentHistoryKept() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "purged", Content: "purged", Stock: 1}
+	id, _ := suite.Repo.Create(&book)
+
+	a.Nil(suite.Repo.Delete(int(id)))
+	a.Nil(suite.Repo.Delete(10000)) // has rent history
+
+	purged, err := suite.Repo.Purge(time.Now().Add(time.Hour))
+	a.Nil(err)
+	a.Equal([]uint{id}, purged)
+
+	books, _ := suite.Repo.GetDeleted()
+	a.Equal(1, len(books))
+	a.Equal(uint(10000), books[0].ID)
+}
+
+func (suite *BookRepoIntegrationTestSuite) TestPurge_WithinRetention_ExpectNothingPurged() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "recent", Content: "recent", Stock: 1}
+	id, _ := suite.Repo.Create(&book)
+	a.Nil(suite.Repo.Delete(int(id)))
+
+	purged, err := suite.Repo.Purge(time.Now().Add(-time.Hour))
+	a.Nil(err)
+	a.Empty(purged)
+}
+
+func (suite *BookRepoIntegrationTestSuite) TestGetByNaturalKey_WithISBN_ExpectMatchedByISBN() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "title1", Content: "isbn edition", Stock: 1, ISBN: "978-0-00-000001-1"}
+	id, _ := suite.Repo.Create(&book)
+
+	found, err := suite.Repo.GetByNaturalKey("978-0-00-000001-1", "title1")
+	a.Nil(err)
+	a.Equal(id, found.ID)
+
+	found, err = suite.Repo.GetByNaturalKey("", "title1")
+	a.Nil(err)
+	a.Equal(uint(10000), found.ID)
+}
+
+func (suite *BookRepoIntegrationTestSuite) TestGetByNaturalKey_WithUnknownTitle_ExpectNotFound() {
+	a := assert.New(suite.T())
+
+	_, err := suite.Repo.GetByNaturalKey("", "title")
+	a.Error(err)
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+}
+
+func (suite *BookRepoIntegrationTestSuite) TestCreate_WithDuplicateISBN_ExpectUniqueConstraint() {
+	a := assert.New(suite.T())
+	first := domain.Book{Title: "first", Content: "first", ISBN: "978-0-00-000002-8"}
+	second := domain.Book{Title: "second", Content: "second", ISBN: "978-0-00-000002-8"}
+
+	_, err := suite.Repo.Create(&first)
+	a.Nil(err)
+	_, err = suite.Repo.Create(&second)
+	a.Error(err)
+	a.Equal(domain.UniqueConstraint, err.(*domain.RepoError).Type)
+}
@@ -5,15 +5,22 @@ import (
 	"github.com/idj1997/book-rent-core/service"
 	"github.com/idj1997/book-rent-core/test/repo_mocks"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
 type BookServiceUnitTestSuite struct {
 	suite.Suite
-	service *service.BookService
-	repo    *repo_mocks.MockedBookRepository
+	service  *service.BookService
+	repo     *repo_mocks.MockedBookRepository
+	rentRepo  *repo_mocks.MockedRentDetailsRepository
+	auditRepo  *repo_mocks.MockedAuditRepository
+	outboxRepo *repo_mocks.MockedOutboxRepository
+	movementRepo *repo_mocks.MockedStockMovementRepository
+	branchRepo   *repo_mocks.MockedBranchRepository
 }
 
 func TestBookServiceUnitTestSuite(t *testing.T) {
@@ -22,7 +29,23 @@ func TestBookServiceUnitTestSuite(t *testing.T) {
 
 func (suite *BookServiceUnitTestSuite) SetupTest() {
 	suite.repo = &repo_mocks.MockedBookRepository{}
-	suite.service = service.NewBookService(suite.repo)
+	suite.rentRepo = &repo_mocks.MockedRentDetailsRepository{}
+	suite.auditRepo = &repo_mocks.MockedAuditRepository{}
+	suite.auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.outboxRepo = &repo_mocks.MockedOutboxRepository{}
+	suite.outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.movementRepo = &repo_mocks.MockedStockMovementRepository{}
+	suite.movementRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.branchRepo = repo_mocks.NewMockedBranchRepository()
+	suite.branchRepo.On("GetStock", mock.Anything, mock.Anything).Return(&domain.BranchStock{Stock: 100}, domain.NilRepoErrPtr)
+	suite.service = service.NewBookService(suite.repo, &repo_mocks.MockedTransactor{
+		Repos: domain.Repositories{
+			Books:     suite.repo,
+			Rents:     suite.rentRepo,
+			Audit:     suite.auditRepo,
+			Outbox:    suite.outboxRepo,
+			Movements: suite.movementRepo,
+			Branches:  suite.branchRepo}})
 }
 
 func (suite *BookServiceUnitTestSuite) TestGetByID_WithInvalidId_ExpectNotFound() {
@@ -141,7 +164,7 @@ func (suite *BookServiceUnitTestSuite) TestUpdateStock_WithInvalidBookID_ExpectN
 	var bookPtr *domain.Book = nil
 
 	suite.repo.
-		On("GetByID", int(book.ID)).
+		On("GetByIDForUpdate", int(book.ID)).
 		Return(bookPtr, &domain.RepoError{Type: domain.NotFound})
 
 	_, err := suite.service.UpdateStock(int(book.ID), 120)
@@ -159,11 +182,15 @@ func (suite *BookServiceUnitTestSuite) TestUpdateStock_WithValidArgs_ExpectOk()
 	newStockCount := 15
 
 	suite.repo.
-		On("GetByID", int(book.ID)).
+		On("GetByIDForUpdate", int(book.ID)).
 		Return(&book, domain.NilRepoErrPtr)
 
+	suite.branchRepo.
+		On("GetAvailability", int(book.ID)).
+		Return([]domain.BranchStock{{BookID: book.ID, BranchID: 1, Stock: 10}}, domain.NilRepoErrPtr)
+
 	updates := make(map[string]interface{})
-	updates["Stock"] = newStockCount
+	updates["stock"] = newStockCount
 
 	suite.repo.
 		On("Update", &book, updates).
@@ -173,6 +200,25 @@ func (suite *BookServiceUnitTestSuite) TestUpdateStock_WithValidArgs_ExpectOk()
 	a.Nil(err)
 }
 
+func (suite *BookServiceUnitTestSuite) TestUpdateStock_WithStockSplitAcrossBranches_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "test title", Content: "test content", Stock: 10}
+	book.ID = 1
+
+	suite.repo.
+		On("GetByIDForUpdate", 1).
+		Return(&book, domain.NilRepoErrPtr)
+	suite.branchRepo.
+		On("GetAvailability", 1).
+		Return([]domain.BranchStock{{BookID: 1, BranchID: 1, Stock: 7}, {BookID: 1, BranchID: 2, Stock: 3}}, domain.NilRepoErrPtr)
+
+	_, err := suite.service.UpdateStock(1, 15)
+	a.NotNil(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+	suite.movementRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+	suite.repo.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything)
+}
+
 func (suite *BookServiceUnitTestSuite) TestDelete_WithInvalidID_ExpectNotFound() {
 	a := assert.New(suite.T())
 	id := 11234
@@ -194,6 +240,10 @@ func (suite *BookServiceUnitTestSuite) TestDelete_WithValidID_ExpectOk() {
 		On("GetByID", id).
 		Return(domain.NilBookPtr, domain.NilRepoErrPtr)
 
+	suite.rentRepo.
+		On("GetByBook", id).
+		Return([]domain.RentDetails{{Status: domain.RETURNED}}, domain.NilRepoErrPtr)
+
 	suite.repo.
 		On("Delete", id).
 		Return(domain.NilRepoErrPtr)
@@ -202,4 +252,205 @@ func (suite *BookServiceUnitTestSuite) TestDelete_WithValidID_ExpectOk() {
 	suite.repo.AssertCalled(suite.T(), "GetByID", id)
 	suite.repo.AssertCalled(suite.T(), "Delete", id)
 	a.Nil(err)
-}
\ No newline at end of file
+}
+
+func (suite *BookServiceUnitTestSuite) TestDelete_WithActiveRents_ExpectActiveBookRents() {
+	a := assert.New(suite.T())
+	id := 10000
+
+	suite.repo.
+		On("GetByID", id).
+		Return(&domain.Book{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByBook", id).
+		Return([]domain.RentDetails{{Status: domain.RETURNED}, {Status: domain.EXPIRED}}, domain.NilRepoErrPtr)
+
+	err := suite.service.Delete(id)
+	a.Error(err)
+	a.Equal(service.ActiveBookRents, err.(*service.ServiceError).Type)
+	suite.repo.AssertNotCalled(suite.T(), "Delete", id)
+}
+
+func (suite *BookServiceUnitTestSuite) TestForceDelete_WithInvalidCloseStatus_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	err := suite.service.ForceDelete(10000, domain.EXPIRED)
+	a.Error(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+}
+
+func (suite *BookServiceUnitTestSuite) TestForceDelete_WithActiveRentsAsReturned_ExpectRestockedAndDeleted() {
+	a := assert.New(suite.T())
+	id := 10000
+	book := domain.Book{Title: "test", Content: "test", Stock: 0}
+	book.ID = uint(id)
+	rents := []domain.RentDetails{{BookID: id, Status: domain.RENTED}}
+
+	suite.repo.
+		On("GetByID", id).
+		Return(&book, domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("GetByIDForUpdate", id).
+		Return(&book, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByBook", id).
+		Return(rents, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("Update", mock.Anything, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			return updates["status"] == domain.RETURNED
+		})).
+		Return(domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("Update", &book, map[string]interface{}{"stock": 1}).
+		Return(domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("Delete", id).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.service.ForceDelete(id, domain.RETURNED)
+	a.Nil(err)
+	suite.repo.AssertCalled(suite.T(), "Delete", id)
+}
+
+func (suite *BookServiceUnitTestSuite) TestForceDelete_WithActiveRentsAsLost_ExpectNotRestocked() {
+	a := assert.New(suite.T())
+	id := 10000
+	rents := []domain.RentDetails{{BookID: id, Status: domain.EXPIRED}}
+
+	suite.repo.
+		On("GetByID", id).
+		Return(&domain.Book{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByBook", id).
+		Return(rents, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("Update", mock.Anything, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			return updates["status"] == domain.LOST
+		})).
+		Return(domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("Delete", id).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.service.ForceDelete(id, domain.LOST)
+	a.Nil(err)
+	suite.repo.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything)
+}
+
+func (suite *BookServiceUnitTestSuite) TestRestore_WithActiveBook_ExpectNotFound() {
+	a := assert.New(suite.T())
+	id := 10000
+
+	suite.repo.
+		On("Restore", id).
+		Return(&domain.RepoError{Type: domain.NotFound})
+
+	err := suite.service.Restore(id)
+	a.Error(err)
+	a.Equal(service.NotFound, err.(*service.ServiceError).Type)
+}
+
+func (suite *BookServiceUnitTestSuite) TestRestore_WithDeletedBook_ExpectOk() {
+	a := assert.New(suite.T())
+	id := 10000
+
+	suite.repo.
+		On("Restore", id).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.service.Restore(id)
+	a.Nil(err)
+}
+
+func (suite *BookServiceUnitTestSuite) TestPurgeDeleted_WithNegativeRetention_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	_, err := suite.service.PurgeDeleted(-time.Hour)
+	a.Error(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+}
+
+func (suite *BookServiceUnitTestSuite) TestPurgeDeleted_WithRetention_ExpectCutoffInPast() {
+	a := assert.New(suite.T())
+	retention := 24 * time.Hour
+
+	suite.repo.
+		On("Purge", mock.MatchedBy(func(cutoff time.Time) bool {
+			return cutoff.Before(time.Now().Add(-retention + time.Minute))
+		})).
+		Return([]uint{1, 2, 3}, domain.NilRepoErrPtr)
+
+	purged, err := suite.service.PurgeDeleted(retention)
+	a.Nil(err)
+	a.Equal(3, purged)
+}
+
+func (suite *BookServiceUnitTestSuite) TestPurgeDeleted_WithPurgedBooks_ExpectDeleteAuditedPerBook() {
+	a := assert.New(suite.T())
+	suite.repo.
+		On("Purge", mock.AnythingOfType("time.Time")).
+		Return([]uint{4, 7}, domain.NilRepoErrPtr)
+
+	var records []*domain.AuditRecord
+	suite.auditRepo.ExpectedCalls = nil
+	suite.auditRepo.
+		On("Create", mock.Anything).
+		Run(func(args mock.Arguments) { records = append(records, args.Get(0).(*domain.AuditRecord)) }).
+		Return(domain.NilRepoErrPtr)
+
+	_, err := suite.service.WithActor("admin").PurgeDeleted(time.Hour)
+	a.Nil(err)
+	suite.Require().Equal(2, len(records))
+	for i, id := range []uint{4, 7} {
+		a.Equal("Book", records[i].Entity)
+		a.Equal(id, records[i].EntityID)
+		a.Equal(domain.AuditDelete, records[i].Action)
+		a.Equal("admin", records[i].Actor)
+	}
+}
+
+func (suite *BookServiceUnitTestSuite) TestPurgeDeleted_WithFailedAudit_ExpectError() {
+	a := assert.New(suite.T())
+	suite.repo.
+		On("Purge", mock.AnythingOfType("time.Time")).
+		Return([]uint{4}, domain.NilRepoErrPtr)
+	suite.auditRepo.ExpectedCalls = nil
+	suite.auditRepo.
+		On("Create", mock.Anything).
+		Return(&domain.RepoError{Type: domain.Unknown})
+
+	purged, err := suite.service.PurgeDeleted(time.Hour)
+	a.Error(err)
+	a.Zero(purged)
+}
+
+func (suite *BookServiceUnitTestSuite) TestUpdateStock_ToZero_ExpectCorrectionRecorded() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "test", Content: "test", Stock: 10}
+	book.ID = 1
+
+	suite.repo.
+		On("GetByIDForUpdate", 1).
+		Return(&book, domain.NilRepoErrPtr)
+	suite.branchRepo.
+		On("GetAvailability", 1).
+		Return([]domain.BranchStock{{BookID: 1, BranchID: 1, Stock: 10}}, domain.NilRepoErrPtr)
+	suite.repo.
+		On("Update", &book, map[string]interface{}{"stock": 0}).
+		Return(domain.NilRepoErrPtr)
+
+	_, err := suite.service.UpdateStock(1, 0)
+	a.Nil(err)
+	suite.movementRepo.AssertCalled(suite.T(), "Create", mock.MatchedBy(func(movement *domain.StockMovement) bool {
+		return movement.Kind == domain.MovementCorrection && movement.Quantity == -10
+	}))
+}
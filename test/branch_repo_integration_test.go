@@ -0,0 +1,81 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type BranchRepoIntegrationTestSuite struct {
+	suite.Suite
+	Repo *repository.GormBranchRepository
+	Db   *gorm.DB
+}
+
+func TestBranchRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &BranchRepoIntegrationTestSuite{})
+}
+
+func (suite *BranchRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+}
+
+func (suite *BranchRepoIntegrationTestSuite) SetupTest() {
+	tx := suite.Db.Begin()
+	suite.Repo = repository.NewGormBranchRepository(tx)
+}
+
+func (suite *BranchRepoIntegrationTestSuite) TearDownTest() {
+	suite.Repo.Db.Rollback()
+	suite.Repo.Db = nil
+}
+
+func (suite *BranchRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *BranchRepoIntegrationTestSuite) TestGetStock_WithSeededBook_ExpectStockAtDefaultBranch() {
+	a := assert.New(suite.T())
+
+	branch, err := suite.Repo.GetDefault()
+	a.Nil(err)
+	a.True(branch.IsDefault)
+
+	stock, err := suite.Repo.GetStock(10000, int(branch.ID))
+	a.Nil(err)
+	a.Equal(5, stock.Stock)
+}
+
+func (suite *BranchRepoIntegrationTestSuite) TestSaveStock_WithNewBranch_ExpectUpsert() {
+	a := assert.New(suite.T())
+	branch := domain.Branch{Name: "east", Address: "East street 1"}
+
+	err := suite.Repo.Create(&branch)
+	a.Nil(err)
+
+	_, err = suite.Repo.GetStock(10000, int(branch.ID))
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+
+	err = suite.Repo.SaveStock(&domain.BranchStock{BookID: 10000, BranchID: branch.ID, Stock: 2})
+	a.Nil(err)
+	err = suite.Repo.SaveStock(&domain.BranchStock{BookID: 10000, BranchID: branch.ID, Stock: 3})
+	a.Nil(err)
+
+	availability, err := suite.Repo.GetAvailability(10000)
+	a.Nil(err)
+	a.Equal(2, len(availability))
+
+	books, err := suite.Repo.GetAvailableBooks(int(branch.ID))
+	a.Nil(err)
+	a.Equal(1, len(books))
+	a.Equal(3, books[0].Stock)
+}
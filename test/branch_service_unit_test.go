@@ -0,0 +1,177 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type BranchServiceUnitTestSuite struct {
+	suite.Suite
+	service      *service.BranchService
+	bookRepo     *repo_mocks.MockedBookRepository
+	branchRepo   *repo_mocks.MockedBranchRepository
+	transferRepo *repo_mocks.MockedTransferRepository
+	movementRepo *repo_mocks.MockedStockMovementRepository
+	east         *domain.Branch
+}
+
+func TestBranchServiceUnitTestSuite(t *testing.T) {
+	suite.Run(t, &BranchServiceUnitTestSuite{})
+}
+
+func (suite *BranchServiceUnitTestSuite) SetupTest() {
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.branchRepo = repo_mocks.NewMockedBranchRepository()
+	suite.transferRepo = &repo_mocks.MockedTransferRepository{}
+	suite.movementRepo = &repo_mocks.MockedStockMovementRepository{}
+	suite.movementRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	auditRepo := &repo_mocks.MockedAuditRepository{}
+	auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	outboxRepo := &repo_mocks.MockedOutboxRepository{}
+	outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+
+	suite.east = &domain.Branch{Name: "east"}
+	suite.east.ID = 2
+	suite.branchRepo.On("GetByID", 2).Return(suite.east, domain.NilRepoErrPtr)
+
+	suite.service = service.NewBranchService(suite.branchRepo, suite.transferRepo, &repo_mocks.MockedTransactor{
+		Repos: domain.Repositories{
+			Books:     suite.bookRepo,
+			Audit:     auditRepo,
+			Outbox:    outboxRepo,
+			Movements: suite.movementRepo,
+			Branches:  suite.branchRepo,
+			Transfers: suite.transferRepo}})
+}
+
+func (suite *BranchServiceUnitTestSuite) transfer(status domain.TransferStatus) *domain.Transfer {
+	transfer := &domain.Transfer{
+		BookID:       10,
+		FromBranchID: 1,
+		ToBranchID:   2,
+		Quantity:     3,
+		Status:       status,
+		Book:         domain.Book{Title: "test", Content: "test", Stock: 8}}
+	transfer.ID = 5
+	transfer.Book.ID = 10
+	suite.transferRepo.On("GetByIDForUpdate", 5).Return(transfer, domain.NilRepoErrPtr)
+	suite.bookRepo.On("GetByIDForUpdate", 10).Return(&transfer.Book, domain.NilRepoErrPtr)
+	return transfer
+}
+
+func (suite *BranchServiceUnitTestSuite) TestRequestTransfer_WithInvalidArgs_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	_, err := suite.service.RequestTransfer(10, 1, 1, 3)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+
+	_, err = suite.service.RequestTransfer(10, 1, 2, 0)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+	suite.transferRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *BranchServiceUnitTestSuite) TestRequestTransfer_WithValidArgs_ExpectRequestedWithoutStockChange() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "test", Content: "test", Stock: 8}
+
+	suite.bookRepo.On("GetByID", 10).Return(&book, domain.NilRepoErrPtr)
+	suite.transferRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+
+	transfer, err := suite.service.WithActor("clerk").RequestTransfer(10, 1, 2, 3)
+	a.Nil(err)
+	a.Equal(domain.TransferRequested, transfer.Status)
+	a.Equal("clerk", transfer.RequestedBy)
+	suite.movementRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *BranchServiceUnitTestSuite) TestShipTransfer_WithRequested_ExpectStockLeftSourceBranch() {
+	a := assert.New(suite.T())
+	transfer := suite.transfer(domain.TransferRequested)
+
+	suite.branchRepo.
+		On("GetStock", 10, 1).
+		Return(&domain.BranchStock{BookID: 10, BranchID: 1, Stock: 5}, domain.NilRepoErrPtr)
+	suite.bookRepo.
+		On("Update", &transfer.Book, map[string]interface{}{"stock": 5}).
+		Return(domain.NilRepoErrPtr)
+	suite.transferRepo.
+		On("Update", transfer, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			return updates["status"] == domain.TransferShipped
+		})).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.service.ShipTransfer(5)
+	a.Nil(err)
+	suite.branchRepo.AssertCalled(suite.T(), "SaveStock", &domain.BranchStock{BookID: 10, BranchID: 1, Stock: 2})
+	suite.movementRepo.AssertCalled(suite.T(), "Create", mock.MatchedBy(func(movement *domain.StockMovement) bool {
+		return movement.Kind == domain.MovementTransferOut && movement.Quantity == -3 && movement.TransferID == 5
+	}))
+}
+
+func (suite *BranchServiceUnitTestSuite) TestShipTransfer_AboveBranchStock_ExpectNotEnoughBooks() {
+	a := assert.New(suite.T())
+	suite.transfer(domain.TransferRequested)
+
+	suite.branchRepo.
+		On("GetStock", 10, 1).
+		Return(&domain.BranchStock{BookID: 10, BranchID: 1, Stock: 2}, domain.NilRepoErrPtr)
+
+	err := suite.service.ShipTransfer(5)
+	a.NotNil(err)
+	a.Equal(service.NotEnoughBooksOnStock, err.(*service.ServiceError).Type)
+	suite.transferRepo.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything)
+}
+
+func (suite *BranchServiceUnitTestSuite) TestReceiveTransfer_WithShipped_ExpectStockArrivedAtTargetBranch() {
+	a := assert.New(suite.T())
+	transfer := suite.transfer(domain.TransferShipped)
+
+	suite.branchRepo.
+		On("GetStock", 10, 2).
+		Return(&domain.BranchStock{}, &domain.RepoError{Type: domain.NotFound})
+	suite.bookRepo.
+		On("Update", &transfer.Book, map[string]interface{}{"stock": 11}).
+		Return(domain.NilRepoErrPtr)
+	suite.transferRepo.
+		On("Update", transfer, mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.service.ReceiveTransfer(5)
+	a.Nil(err)
+	suite.branchRepo.AssertCalled(suite.T(), "SaveStock", &domain.BranchStock{BookID: 10, BranchID: 2, Stock: 3})
+}
+
+func (suite *BranchServiceUnitTestSuite) TestReceiveTransfer_WithRequested_ExpectInvalidStatusTransition() {
+	a := assert.New(suite.T())
+	suite.transfer(domain.TransferRequested)
+
+	err := suite.service.ReceiveTransfer(5)
+	a.NotNil(err)
+	a.Equal(service.InvalidStatusTransition, err.(*service.ServiceError).Type)
+}
+
+func (suite *BranchServiceUnitTestSuite) TestCancelTransfer_WithShipped_ExpectInvalidStatusTransition() {
+	a := assert.New(suite.T())
+	suite.transfer(domain.TransferShipped)
+
+	err := suite.service.CancelTransfer(5)
+	a.NotNil(err)
+	a.Equal(service.InvalidStatusTransition, err.(*service.ServiceError).Type)
+}
+
+func (suite *BranchServiceUnitTestSuite) TestGetAvailableBooks_WithBranch_ExpectStocks() {
+	a := assert.New(suite.T())
+	stocks := []domain.BranchStock{{BookID: 10, BranchID: 2, Stock: 4}}
+
+	suite.branchRepo.On("GetAvailableBooks", 2).Return(stocks, domain.NilRepoErrPtr)
+
+	result, err := suite.service.GetAvailableBooks(2)
+	a.Nil(err)
+	a.Equal(stocks, result)
+}
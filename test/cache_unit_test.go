@@ -0,0 +1,270 @@
+package test
+
+import (
+	"context"
+	"github.com/idj1997/book-rent-core/cache"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheUnitTestSuite struct {
+	suite.Suite
+	backend  *cache.LRU
+	cache    *cache.Cache
+	now      time.Time
+	bookRepo *repo_mocks.MockedBookRepository
+	userRepo *repo_mocks.MockedUserRepository
+	books    *cache.BookRepository
+	users    *cache.UserRepository
+}
+
+func TestCacheUnitTestSuite(t *testing.T) {
+	suite.Run(t, &CacheUnitTestSuite{})
+}
+
+func (suite *CacheUnitTestSuite) SetupTest() {
+	suite.now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.backend = cache.NewLRU(2, time.Minute)
+	suite.backend.Now = func() time.Time { return suite.now }
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.userRepo = &repo_mocks.MockedUserRepository{}
+	suite.cache = cache.NewCache(suite.backend)
+	suite.books = cache.NewBookRepository(suite.bookRepo, suite.cache)
+	suite.users = cache.NewUserRepository(suite.userRepo, suite.cache)
+}
+
+func (suite *CacheUnitTestSuite) book(id uint, stock int) *domain.Book {
+	book := &domain.Book{Title: "title", Content: "content", Stock: stock}
+	book.ID = id
+	return book
+}
+
+func (suite *CacheUnitTestSuite) user(id uint, email string) *domain.User {
+	user := &domain.User{Firstname: "john", Lastname: "doe", Email: email}
+	user.ID = id
+	return user
+}
+
+func (suite *CacheUnitTestSuite) TestLRU_AboveCapacity_ExpectLeastRecentlyUsedEvicted() {
+	a := assert.New(suite.T())
+
+	suite.backend.Set("a", 1)
+	suite.backend.Set("b", 2)
+	_, _ = suite.backend.Get("a")
+	suite.backend.Set("c", 3)
+
+	_, ok := suite.backend.Get("b")
+	a.False(ok)
+	value, ok := suite.backend.Get("a")
+	a.True(ok)
+	a.Equal(1, value)
+	a.Equal(2, suite.backend.Len())
+}
+
+func (suite *CacheUnitTestSuite) TestLRU_AfterTTL_ExpectExpired() {
+	a := assert.New(suite.T())
+
+	suite.backend.Set("a", 1)
+	suite.now = suite.now.Add(59 * time.Second)
+	_, ok := suite.backend.Get("a")
+	a.True(ok)
+
+	suite.now = suite.now.Add(time.Second)
+	_, ok = suite.backend.Get("a")
+	a.False(ok)
+	a.Equal(0, suite.backend.Len())
+}
+
+func (suite *CacheUnitTestSuite) TestPrefixed_WithSharedBackend_ExpectSeparateKeys() {
+	a := assert.New(suite.T())
+	acme := cache.Prefixed(suite.backend, "acme")
+
+	acme.Set("a", 1)
+	_, ok := suite.backend.Get("a")
+	a.False(ok)
+	value, ok := acme.Get("a")
+	a.True(ok)
+	a.Equal(1, value)
+}
+
+func (suite *CacheUnitTestSuite) TestBookGetByID_WithTenantsSharingBackend_ExpectSeparateEntries() {
+	a := assert.New(suite.T())
+	acmeRepo := &repo_mocks.MockedBookRepository{}
+	acmeRepo.On("GetByID", 10).Return(suite.book(10, 1), domain.NilRepoErrPtr).Once()
+	globexRepo := &repo_mocks.MockedBookRepository{}
+	globexRepo.On("GetByID", 10).Return(suite.book(10, 2), domain.NilRepoErrPtr).Once()
+	globexRepo.On("Update", mock.Anything, mock.Anything).Return(domain.NilRepoErrPtr)
+	tenants := cache.NewTenants(suite.backend)
+	acme := cache.NewBookRepository(acmeRepo, tenants.ForContext(domain.WithTenant(context.Background(), "acme")))
+	globex := cache.NewBookRepository(globexRepo, tenants.ForContext(domain.WithTenant(context.Background(), "globex")))
+
+	_, err := acme.GetByID(10)
+	a.Nil(err)
+	book, err := globex.GetByID(10)
+	a.Nil(err)
+	a.Equal(2, book.Stock)
+
+	suite.Require().Nil(globex.Update(book, map[string]interface{}{"stock": 3}))
+	book, err = acme.GetByID(10)
+	a.Nil(err)
+	a.Equal(1, book.Stock)
+	a.Equal(cache.Stats{Hits: 1, Misses: 1}, acme.Stats())
+	globexRepo.AssertNumberOfCalls(suite.T(), "GetByID", 1)
+}
+
+func (suite *CacheUnitTestSuite) TestForTenant_WithoutTenant_ExpectDefaultTenantEntries() {
+	a := assert.New(suite.T())
+
+	cache.ForTenant(context.Background(), suite.backend).Set("a", 1)
+	value, ok := cache.ForTenant(domain.WithTenant(context.Background(), domain.DefaultTenant), suite.backend).Get("a")
+	a.True(ok)
+	a.Equal(1, value)
+}
+
+func (suite *CacheUnitTestSuite) TestBookGetByID_Twice_ExpectInnerCalledOnce() {
+	a := assert.New(suite.T())
+	suite.bookRepo.On("GetByID", 10).Return(suite.book(10, 5), domain.NilRepoErrPtr).Once()
+
+	first, err := suite.books.GetByID(10)
+	a.Nil(err)
+	first.Stock = 0
+
+	second, err := suite.books.GetByID(10)
+	a.Nil(err)
+	a.Equal(5, second.Stock)
+	a.Equal(cache.Stats{Hits: 1, Misses: 1}, suite.books.Stats())
+	suite.bookRepo.AssertNumberOfCalls(suite.T(), "GetByID", 1)
+}
+
+func (suite *CacheUnitTestSuite) TestBookGetByID_WithNotFound_ExpectNotCached() {
+	a := assert.New(suite.T())
+	suite.bookRepo.
+		On("GetByID", 10).
+		Return(&domain.Book{}, &domain.RepoError{Type: domain.NotFound})
+
+	_, err := suite.books.GetByID(10)
+	a.NotNil(err)
+	_, err = suite.books.GetByID(10)
+	a.NotNil(err)
+	a.Equal(cache.Stats{Misses: 2}, suite.books.Stats())
+}
+
+func (suite *CacheUnitTestSuite) TestBookUpdateAndDelete_ExpectEvicted() {
+	a := assert.New(suite.T())
+	book := suite.book(10, 5)
+	updates := map[string]interface{}{"title": "new"}
+	suite.bookRepo.On("GetByID", 10).Return(book, domain.NilRepoErrPtr)
+	suite.bookRepo.On("Update", book, updates).Return(domain.NilRepoErrPtr)
+	suite.bookRepo.On("Delete", 10).Return(domain.NilRepoErrPtr)
+
+	_, _ = suite.books.GetByID(10)
+	err := suite.books.Update(book, updates)
+	a.Nil(err)
+	_, _ = suite.books.GetByID(10)
+	err = suite.books.Delete(10)
+	a.Nil(err)
+	_, _ = suite.books.GetByID(10)
+
+	a.Equal(cache.Stats{Misses: 3}, suite.books.Stats())
+}
+
+func (suite *CacheUnitTestSuite) TestUserGetByEmail_AfterGetByID_ExpectHit() {
+	a := assert.New(suite.T())
+	suite.userRepo.On("GetByID", 10).Return(suite.user(10, "john@doe.com"), domain.NilRepoErrPtr).Once()
+
+	_, err := suite.users.GetByID(10)
+	a.Nil(err)
+	user, err := suite.users.GetByEmail("john@doe.com")
+	a.Nil(err)
+	a.Equal(uint(10), user.ID)
+	a.Equal(cache.Stats{Hits: 1, Misses: 1}, suite.users.Stats())
+	suite.userRepo.AssertNotCalled(suite.T(), "GetByEmail", mock.Anything)
+}
+
+func (suite *CacheUnitTestSuite) TestUserGetByEmail_AfterEmailChanged_ExpectMiss() {
+	a := assert.New(suite.T())
+	user := suite.user(10, "john@doe.com")
+	updates := map[string]interface{}{"email": "john@acme.com"}
+	suite.userRepo.On("GetByEmail", "john@doe.com").Return(user, domain.NilRepoErrPtr).Once()
+	suite.userRepo.On("Update", user, updates).Return(domain.NilRepoErrPtr)
+	suite.userRepo.On("GetByID", 10).Return(suite.user(10, "john@acme.com"), domain.NilRepoErrPtr)
+	suite.userRepo.
+		On("GetByEmail", "john@doe.com").
+		Return(&domain.User{}, &domain.RepoError{Type: domain.NotFound})
+
+	_, _ = suite.users.GetByEmail("john@doe.com")
+	err := suite.users.Update(user, updates)
+	a.Nil(err)
+	_, _ = suite.users.GetByID(10)
+
+	_, err = suite.users.GetByEmail("john@doe.com")
+	a.NotNil(err)
+	a.Equal(cache.Stats{Misses: 3}, suite.users.Stats())
+}
+
+func (suite *CacheUnitTestSuite) TestTransaction_WithBookUpdate_ExpectEvictedAfterwards() {
+	a := assert.New(suite.T())
+	book := suite.book(10, 5)
+	updates := map[string]interface{}{"stock": 4}
+	txBookRepo := &repo_mocks.MockedBookRepository{}
+	txBookRepo.On("Update", book, updates).Return(domain.NilRepoErrPtr)
+	suite.bookRepo.On("GetByID", 10).Return(book, domain.NilRepoErrPtr)
+	transactor := cache.NewTransactor(
+		&repo_mocks.MockedTransactor{Repos: domain.Repositories{Books: txBookRepo}},
+		suite.cache)
+
+	_, _ = suite.books.GetByID(10)
+	err := transactor.Transaction(func(repos domain.Repositories) error {
+		_ = repos.Books.Update(book, updates)
+		return nil
+	})
+	a.Nil(err)
+	_, _ = suite.books.GetByID(10)
+
+	a.Equal(cache.Stats{Misses: 2}, suite.books.Stats())
+}
+
+func (suite *CacheUnitTestSuite) TestBookGetByID_WithUpdateWhileLoading_ExpectStaleBookNotCached() {
+	a := assert.New(suite.T())
+	book := suite.book(10, 5)
+	updates := map[string]interface{}{"stock": 4}
+	suite.bookRepo.On("Update", book, updates).Return(domain.NilRepoErrPtr)
+	suite.bookRepo.
+		On("GetByID", 10).
+		Run(func(args mock.Arguments) {
+			// concurrent request commits update after book was read
+			suite.Require().Nil(cache.NewBookRepository(suite.bookRepo, suite.cache).Update(book, updates))
+		}).
+		Return(suite.book(10, 5), domain.NilRepoErrPtr).Once()
+	suite.bookRepo.On("GetByID", 10).Return(suite.book(10, 4), domain.NilRepoErrPtr).Once()
+
+	_, err := suite.books.GetByID(10)
+	a.Nil(err)
+	loaded, err := suite.books.GetByID(10)
+	a.Nil(err)
+	a.Equal(4, loaded.Stock)
+	a.Equal(cache.Stats{Misses: 2}, suite.books.Stats())
+}
+
+func (suite *CacheUnitTestSuite) TestTenantsForContext_AcrossRequests_ExpectSharedCacheAndStats() {
+	a := assert.New(suite.T())
+	tenants := cache.NewTenants(suite.backend)
+	ctx := domain.WithTenant(context.Background(), "acme")
+	suite.bookRepo.On("GetByID", 10).Return(suite.book(10, 5), domain.NilRepoErrPtr).Once()
+
+	_, err := cache.NewBookRepository(suite.bookRepo, tenants.ForContext(ctx)).GetByID(10)
+	a.Nil(err)
+	_, err = cache.NewBookRepository(suite.bookRepo, tenants.ForContext(ctx)).GetByID(10)
+	a.Nil(err)
+
+	caches := tenants.Caches()
+	suite.Require().Len(caches, 1)
+	a.Equal(cache.Stats{Hits: 1, Misses: 1}, caches["acme"].BookStats())
+	suite.bookRepo.AssertNumberOfCalls(suite.T(), "GetByID", 1)
+}
@@ -0,0 +1,208 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type CatalogServiceUnitTestSuite struct {
+	suite.Suite
+	service    *service.CatalogService
+	repo       *repo_mocks.MockedBookRepository
+	auditRepo  *repo_mocks.MockedAuditRepository
+	outboxRepo *repo_mocks.MockedOutboxRepository
+	movements  *repo_mocks.MockedStockMovementRepository
+	notFound   *domain.Book
+}
+
+func TestCatalogServiceUnitTestSuite(t *testing.T) {
+	suite.Run(t, &CatalogServiceUnitTestSuite{})
+}
+
+func (suite *CatalogServiceUnitTestSuite) SetupTest() {
+	suite.repo = &repo_mocks.MockedBookRepository{}
+	suite.auditRepo = &repo_mocks.MockedAuditRepository{}
+	suite.auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.outboxRepo = &repo_mocks.MockedOutboxRepository{}
+	suite.outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.movements = &repo_mocks.MockedStockMovementRepository{}
+	suite.movements.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	branchRepo := repo_mocks.NewMockedBranchRepository()
+	branchRepo.On("GetStock", mock.Anything, mock.Anything).Return(&domain.BranchStock{Stock: 100}, domain.NilRepoErrPtr)
+	branchRepo.On("GetAvailability", mock.Anything).Return([]domain.BranchStock{}, domain.NilRepoErrPtr)
+	suite.service = service.NewCatalogService(suite.repo, &repo_mocks.MockedTransactor{
+		Repos: domain.Repositories{
+			Books:     suite.repo,
+			Audit:     suite.auditRepo,
+			Outbox:    suite.outboxRepo,
+			Movements: suite.movements,
+			Branches:  branchRepo}})
+}
+
+const catalogCSV = `title,content,stock,price,isbn
+new book,new content,3,1500,978-0-00-000001-1
+title1,content1,7,1999,
+`
+
+func (suite *CatalogServiceUnitTestSuite) TestImport_WithNewAndExistingBooks_ExpectUpserted() {
+	a := assert.New(suite.T())
+	existing := domain.Book{Title: "title1", Content: "content1", Stock: 5, Price: 1999}
+	existing.ID = 2
+
+	suite.repo.
+		On("GetByNaturalKey", "978-0-00-000001-1", "new book").
+		Return(suite.notFound, &domain.RepoError{Type: domain.NotFound})
+	suite.repo.
+		On("GetByNaturalKey", "", "title1").
+		Return(&existing, domain.NilRepoErrPtr)
+	suite.repo.
+		On("GetByIDForUpdate", 2).
+		Return(&existing, domain.NilRepoErrPtr)
+	suite.repo.
+		On("Create", mock.MatchedBy(func(book *domain.Book) bool {
+			return book.ISBN == "978-0-00-000001-1" && book.Stock == 3 && book.Price == 1500
+		})).
+		Return(uint(1), domain.NilRepoErrPtr)
+	suite.repo.
+		On("Update", &existing, map[string]interface{}{"stock": 7}).
+		Return(domain.NilRepoErrPtr)
+
+	report, err := suite.service.Import(strings.NewReader(catalogCSV), domain.ImportOptions{Format: domain.CSVFormat})
+	a.Nil(err)
+	a.Equal(1, report.Created)
+	a.Equal(1, report.Updated)
+	a.Zero(report.Failed)
+}
+
+func (suite *CatalogServiceUnitTestSuite) TestImport_AtomicWithInvalidRow_ExpectNothingStored() {
+	a := assert.New(suite.T())
+	input := catalogCSV + "missing content,,1,0,\n"
+
+	report, err := suite.service.Import(strings.NewReader(input), domain.ImportOptions{Format: domain.CSVFormat})
+	a.NotNil(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+	a.Equal(1, report.Failed)
+	a.Equal(3, report.Errors[0].Row)
+	a.Equal("missing content", report.Errors[0].Key)
+	suite.repo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+	suite.repo.AssertNotCalled(suite.T(), "GetByNaturalKey", mock.Anything, mock.Anything)
+}
+
+func (suite *CatalogServiceUnitTestSuite) TestImport_AtomicWithFailedStore_ExpectRolledBack() {
+	a := assert.New(suite.T())
+	input := `[{"title": "a", "content": "a", "stock": 1}, {"title": "b", "content": "b", "stock": 1}]`
+
+	suite.repo.
+		On("GetByNaturalKey", "", mock.Anything).
+		Return(suite.notFound, &domain.RepoError{Type: domain.NotFound})
+	suite.repo.
+		On("Create", mock.MatchedBy(func(book *domain.Book) bool { return book.Title == "a" })).
+		Return(uint(1), domain.NilRepoErrPtr)
+	suite.repo.
+		On("Create", mock.MatchedBy(func(book *domain.Book) bool { return book.Title == "b" })).
+		Return(uint(0), &domain.RepoError{Type: domain.UniqueConstraint})
+
+	report, err := suite.service.Import(strings.NewReader(input), domain.ImportOptions{Format: domain.JSONFormat})
+	a.NotNil(err)
+	a.Zero(report.Created)
+	a.Equal(1, report.Failed)
+	a.Equal(2, report.Errors[0].Row)
+	a.Equal("book already exists", report.Errors[0].Message)
+}
+
+func (suite *CatalogServiceUnitTestSuite) TestImport_PartialWithInvalidRow_ExpectValidRowsStored() {
+	a := assert.New(suite.T())
+	input := `[{"title": "a", "content": "a", "stock": 1}, {"title": "b", "content": "b", "stock": "many"}]`
+
+	suite.repo.
+		On("GetByNaturalKey", "", "a").
+		Return(suite.notFound, &domain.RepoError{Type: domain.NotFound})
+	suite.repo.
+		On("Create", mock.Anything).
+		Return(uint(1), domain.NilRepoErrPtr)
+
+	report, err := suite.service.Import(strings.NewReader(input), domain.ImportOptions{
+		Format: domain.JSONFormat,
+		Mode:   domain.ImportPartial})
+	a.Nil(err)
+	a.Equal(1, report.Created)
+	a.Equal(1, report.Failed)
+	a.Equal(2, report.Errors[0].Row)
+}
+
+func (suite *CatalogServiceUnitTestSuite) TestImport_WithDryRun_ExpectClassifiedWithoutWrites() {
+	a := assert.New(suite.T())
+	existing := domain.Book{Title: "title1", Content: "content1", Stock: 7, Price: 1999}
+	input := catalogCSV + "new book,new content,4,1500,978-0-00-000001-1\n"
+
+	suite.repo.
+		On("GetByNaturalKey", "978-0-00-000001-1", "new book").
+		Return(suite.notFound, &domain.RepoError{Type: domain.NotFound})
+	suite.repo.
+		On("GetByNaturalKey", "", "title1").
+		Return(&existing, domain.NilRepoErrPtr)
+
+	report, err := suite.service.Import(strings.NewReader(input), domain.ImportOptions{
+		Format: domain.CSVFormat,
+		DryRun: true})
+	a.Nil(err)
+	a.True(report.DryRun)
+	a.Equal(1, report.Created)
+	a.Equal(1, report.Unchanged)
+	a.Equal(1, report.Updated) // repeated ISBN updates book created by first row
+	suite.repo.AssertNumberOfCalls(suite.T(), "GetByNaturalKey", 2)
+	suite.repo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+	suite.repo.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything)
+}
+
+func (suite *CatalogServiceUnitTestSuite) TestImport_WithMissingHeaderColumn_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	report, err := suite.service.Import(strings.NewReader("title,stock\na,1\n"), domain.ImportOptions{Format: domain.CSVFormat})
+	a.Nil(report)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+}
+
+func (suite *CatalogServiceUnitTestSuite) TestExport_AsJSON_ExpectAllBooks() {
+	a := assert.New(suite.T())
+	books := []domain.Book{
+		{Title: "title1", Content: "content1", Stock: 5, Price: 1999},
+		{Title: "title2", Content: "content2", Stock: 15, ISBN: "978-0-00-000002-8"}}
+	suite.repo.On("GetAll").Return(books, domain.NilRepoErrPtr)
+
+	var out bytes.Buffer
+	count, err := suite.service.Export(&out, domain.JSONFormat)
+	a.Nil(err)
+	a.Equal(2, count)
+
+	var entries []domain.CatalogEntry
+	a.Nil(json.Unmarshal(out.Bytes(), &entries))
+	a.Equal("978-0-00-000002-8", entries[1].ISBN)
+	a.Equal(1999, entries[0].Price)
+}
+
+func (suite *CatalogServiceUnitTestSuite) TestExport_AsCSV_ExpectImportable() {
+	a := assert.New(suite.T())
+	books := []domain.Book{{Title: "title, with comma", Content: "content", Stock: 2}}
+	suite.repo.On("GetAll").Return(books, domain.NilRepoErrPtr)
+
+	var out bytes.Buffer
+	_, err := suite.service.Export(&out, domain.CSVFormat)
+	a.Nil(err)
+
+	suite.repo.
+		On("GetByNaturalKey", "", "title, with comma").
+		Return(&books[0], domain.NilRepoErrPtr)
+	report, err := suite.service.Import(&out, domain.ImportOptions{Format: domain.CSVFormat, DryRun: true})
+	a.Nil(err)
+	a.Equal(1, report.Unchanged)
+}
@@ -0,0 +1,208 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const validConfig = `
+dev:
+  logging:
+    outputType: console
+  database:
+    host: localhost
+    port: 5432
+    user: postgres
+    password: postgres
+    dbname: books
+    sslmode: disable
+    replicas:
+      - host: replica1
+    pool:
+      maxOpenConns: 25
+      connMaxLifetime: 30m
+  outbox:
+    interval: 5s
+    batchSize: 100
+    maxAttempts: 10
+  reminders:
+    daysAhead: [7, 1]
+    notifier: log
+  cache:
+    size: 100
+    ttl: 1m
+
+test:
+  logging:
+    outputType: console
+  database:
+    host: localhost
+    port: 5432
+    user: postgres
+    password: postgres
+    dbname: books_test
+  outbox:
+    interval: 1s
+    batchSize: 10
+    maxAttempts: 3
+  reminders:
+    notifier: log
+`
+
+type ConfigUnitTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestConfigUnitTestSuite(t *testing.T) {
+	suite.Run(t, &ConfigUnitTestSuite{})
+}
+
+func (suite *ConfigUnitTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "config")
+	suite.Require().Nil(err)
+	suite.dir = dir
+}
+
+func (suite *ConfigUnitTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.dir)
+}
+
+func (suite *ConfigUnitTestSuite) writeFile(name string, content string) string {
+	path := filepath.Join(suite.dir, name)
+	suite.Require().Nil(ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func (suite *ConfigUnitTestSuite) setEnv(key string, value string) {
+	suite.Require().Nil(os.Setenv(key, value))
+	suite.T().Cleanup(func() {
+		_ = os.Unsetenv(key)
+	})
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithValidFile_ExpectTypedConfig() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+
+	cfg, err := config.Load("dev", path)
+	suite.Require().Nil(err)
+	a.Equal("dev", cfg.Env)
+	a.Equal("books", cfg.Database.DBName)
+	a.Equal(5432, cfg.Database.Port)
+	a.Equal(30*time.Minute, cfg.Database.Pool.ConnMaxLifetime)
+	a.Equal([]int{7, 1}, cfg.Reminders.DaysAhead)
+	a.Equal(5*time.Second, cfg.Outbox.Interval)
+	a.Equal(100, cfg.Cache.Size)
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithReplica_ExpectMissingSettingsInherited() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+
+	cfg, err := config.Load("dev", path)
+	suite.Require().Nil(err)
+	suite.Require().Len(cfg.Database.Replicas, 1)
+	replica := cfg.Database.Replicas[0]
+	a.Equal("replica1", replica.Host)
+	a.Equal(5432, replica.Port)
+	a.Equal("books", replica.DBName)
+	a.Equal("postgres", replica.Password)
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_TwoEnvironments_ExpectIndependentConfigs() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+
+	dev, err := config.Load("dev", path)
+	suite.Require().Nil(err)
+	test, err := config.Load("test", path)
+	suite.Require().Nil(err)
+
+	a.Equal("books", dev.Database.DBName)
+	a.Equal("books_test", test.Database.DBName)
+	a.Empty(test.Database.Replicas)
+	a.Equal(10, test.Outbox.BatchSize)
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithEnvOverride_ExpectOverriddenValue() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+	suite.setEnv("BOOKRENT_DATABASE_PASSWORD", "from-env")
+	suite.setEnv("BOOKRENT_DATABASE_PORT", "6432")
+
+	cfg, err := config.Load("dev", path)
+	suite.Require().Nil(err)
+	a.Equal("from-env", cfg.Database.Password)
+	a.Equal(6432, cfg.Database.Port)
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithSecretFile_ExpectValueReadFromFile() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+	secret := suite.writeFile("password", "s3cret\n")
+	suite.setEnv("BOOKRENT_DATABASE_PASSWORD_FILE", secret)
+
+	cfg, err := config.Load("dev", path)
+	suite.Require().Nil(err)
+	a.Equal("s3cret", cfg.Database.Password)
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithMissingSecretFile_ExpectError() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+	suite.setEnv("BOOKRENT_DATABASE_PASSWORD_FILE", filepath.Join(suite.dir, "missing"))
+
+	cfg, err := config.Load("dev", path)
+	a.Nil(cfg)
+	suite.Require().NotNil(err)
+	a.Contains(err.Error(), "DATABASE_PASSWORD_FILE")
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithInvalidNotifier_ExpectError() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+	suite.setEnv("BOOKRENT_REMINDERS_NOTIFIER", "pigeon")
+
+	cfg, err := config.Load("dev", path)
+	a.Nil(cfg)
+	suite.Require().NotNil(err)
+	a.Contains(err.Error(), "Notifier")
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithFileNotifierWithoutPath_ExpectError() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+	suite.setEnv("BOOKRENT_REMINDERS_NOTIFIER", "file")
+
+	cfg, err := config.Load("dev", path)
+	a.Nil(cfg)
+	suite.Require().NotNil(err)
+	a.Contains(err.Error(), "reminders.filePath")
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_WithMissingEnvironment_ExpectError() {
+	a := assert.New(suite.T())
+	path := suite.writeFile("config.yml", validConfig)
+
+	cfg, err := config.Load("prod", path)
+	a.Nil(cfg)
+	suite.Require().NotNil(err)
+	a.Contains(err.Error(), "prod")
+}
+
+func (suite *ConfigUnitTestSuite) TestLoad_RepoConfigFile_ExpectValid() {
+	a := assert.New(suite.T())
+
+	for _, env := range []string{"dev", "test"} {
+		_, err := config.Load(env, "../config.yml")
+		a.Nil(err, env)
+	}
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/idj1997/book-rent-core/config"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type GormLoggerUnitTestSuite struct {
+	suite.Suite
+	hook   *logtest.Hook
+	logger *log.Logger
+}
+
+func TestGormLoggerUnitTestSuite(t *testing.T) {
+	suite.Run(t, &GormLoggerUnitTestSuite{})
+}
+
+func (suite *GormLoggerUnitTestSuite) SetupTest() {
+	suite.logger, suite.hook = logtest.NewNullLogger()
+}
+
+func (suite *GormLoggerUnitTestSuite) trace(gormLogger logger.Interface, sql string, elapsed time.Duration, err error) {
+	gormLogger.Trace(context.Background(), time.Now().Add(-elapsed), func() (string, int64) {
+		return sql, 3
+	}, err)
+}
+
+func (suite *GormLoggerUnitTestSuite) TestTrace_WithInfoLevel_ExpectStatementFields() {
+	a := assert.New(suite.T())
+	gormLogger := config.NewGormLogger(suite.logger, config.SQLLogConfig{Level: "info"})
+
+	suite.trace(gormLogger, `SELECT * FROM "books" WHERE id = 1`, time.Millisecond, nil)
+
+	entry := suite.hook.LastEntry()
+	suite.Require().NotNil(entry)
+	a.Equal(log.InfoLevel, entry.Level)
+	a.Equal(`SELECT * FROM "books" WHERE id = 1`, entry.Data["sql"])
+	a.Equal(int64(3), entry.Data["rows"])
+	a.True(entry.Data["duration_ms"].(float64) >= 1)
+	a.Contains(entry.Data["caller"], "gorm_logger_unit_test.go")
+}
+
+func (suite *GormLoggerUnitTestSuite) TestTrace_WithSlowStatement_ExpectWarning() {
+	a := assert.New(suite.T())
+	gormLogger := config.NewGormLogger(suite.logger, config.SQLLogConfig{Level: "warn", SlowThreshold: 100 * time.Millisecond})
+
+	suite.trace(gormLogger, "SELECT 1", time.Millisecond, nil)
+	a.Empty(suite.hook.AllEntries())
+
+	suite.trace(gormLogger, "SELECT 1", time.Second, nil)
+	entry := suite.hook.LastEntry()
+	suite.Require().NotNil(entry)
+	a.Equal(log.WarnLevel, entry.Level)
+	a.Equal(float64(100), entry.Data["slow_threshold_ms"])
+}
+
+func (suite *GormLoggerUnitTestSuite) TestTrace_WithErrors_ExpectRecordNotFoundNotLoggedAsError() {
+	a := assert.New(suite.T())
+	gormLogger := config.NewGormLogger(suite.logger, config.SQLLogConfig{Level: "error"})
+
+	suite.trace(gormLogger, "SELECT 1", time.Millisecond, gorm.ErrRecordNotFound)
+	a.Empty(suite.hook.AllEntries())
+
+	suite.trace(gormLogger, "SELECT 1", time.Millisecond, errors.New("connection refused"))
+	entry := suite.hook.LastEntry()
+	suite.Require().NotNil(entry)
+	a.Equal(log.ErrorLevel, entry.Level)
+	a.Equal("connection refused", entry.Data[log.ErrorKey].(error).Error())
+}
+
+func (suite *GormLoggerUnitTestSuite) TestLogMode_WithSilent_ExpectNothingLogged() {
+	gormLogger := config.NewGormLogger(suite.logger, config.SQLLogConfig{Level: "info"}).LogMode(logger.Silent)
+
+	suite.trace(gormLogger, "SELECT 1", time.Millisecond, errors.New("failed"))
+	gormLogger.Error(context.Background(), "failed %d", 1)
+
+	suite.Empty(suite.hook.AllEntries())
+}
+
+func (suite *GormLoggerUnitTestSuite) TestTrace_WithPassword_ExpectValueRedacted() {
+	a := assert.New(suite.T())
+	gormLogger := config.NewGormLogger(suite.logger, config.SQLLogConfig{Level: "info"})
+	statements := map[string]string{
+		`UPDATE "users" SET "password"='se''cr,et',"email"='a@b.c' WHERE id = 1`: `UPDATE "users" SET "password"='[REDACTED]',"email"='a@b.c' WHERE id = 1`,
+		`SELECT * FROM "users" WHERE password = 'x\'y' AND id = 2`:             `SELECT * FROM "users" WHERE password = '[REDACTED]' AND id = 2`,
+		`INSERT INTO "users" ("email","password","stock") VALUES ('a','s\'e,(c',1),('b',md5('t'),2) RETURNING "id"`: `INSERT INTO "users" ("email","password","stock") VALUES ('a','[REDACTED]',1),('b','[REDACTED]',2) RETURNING "id"`,
+		`INSERT INTO "books" ("title","stock") VALUES ('password',1)`: `INSERT INTO "books" ("title","stock") VALUES ('password',1)`,
+	}
+
+	for sql, expected := range statements {
+		suite.trace(gormLogger, sql, time.Millisecond, nil)
+		a.Equal(expected, suite.hook.LastEntry().Data["sql"])
+	}
+}
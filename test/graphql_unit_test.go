@@ -0,0 +1,230 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/idj1997/book-rent-core/auth"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/graphqlapi"
+	"github.com/idj1997/book-rent-core/logging"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type GraphQLUnitTestSuite struct {
+	suite.Suite
+	bookRepo *repo_mocks.MockedBookRepository
+	userRepo *repo_mocks.MockedUserRepository
+	rentRepo *repo_mocks.MockedRentDetailsRepository
+	server   *httptest.Server
+	// scoped is context services of last request were scoped to
+	scoped context.Context
+}
+
+const graphQLTestToken = "graphql-test-token"
+
+type graphQLResponse struct {
+	Data   json.RawMessage
+	Errors []struct {
+		Message    string
+		Extensions map[string]string
+	}
+}
+
+func TestGraphQLUnitTestSuite(t *testing.T) {
+	suite.Run(t, &GraphQLUnitTestSuite{})
+}
+
+func (suite *GraphQLUnitTestSuite) SetupTest() {
+	suite.scoped = nil
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.userRepo = &repo_mocks.MockedUserRepository{}
+	suite.rentRepo = &repo_mocks.MockedRentDetailsRepository{}
+	movements := &repo_mocks.MockedStockMovementRepository{}
+	movements.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	outboxRepo := &repo_mocks.MockedOutboxRepository{}
+	outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	auditRepo := &repo_mocks.MockedAuditRepository{}
+	auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	branches := repo_mocks.NewMockedBranchRepository()
+	branches.On("GetStock", mock.Anything, mock.Anything).Return(&domain.BranchStock{Stock: 0}, domain.NilRepoErrPtr)
+	tx := &repo_mocks.MockedTransactor{Repos: domain.Repositories{
+		Books:     suite.bookRepo,
+		Users:     suite.userRepo,
+		Rents:     suite.rentRepo,
+		Audit:     auditRepo,
+		Outbox:    outboxRepo,
+		Movements: movements,
+		Branches:  branches}}
+	scope := func(ctx context.Context) graphqlapi.Services {
+		suite.scoped = ctx
+		return graphqlapi.Services{
+			Books: service.NewBookService(suite.bookRepo, tx).WithLogger(logging.FromContext(ctx)),
+			Users: (&service.UserService{Repo: suite.userRepo, Tx: tx}).WithLogger(logging.FromContext(ctx)),
+			Rents: &service.RentDetailsService{RentRepo: suite.rentRepo, BookRepo: suite.bookRepo, Tx: tx}}
+	}
+	actors := map[string]config.ActorConfig{"tester": {Token: graphQLTestToken, Tenant: "acme"}}
+	suite.server = httptest.NewServer(graphqlapi.Handler(scope, actors))
+}
+
+func (suite *GraphQLUnitTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *GraphQLUnitTestSuite) post(token string, query string, variables map[string]interface{}) *http.Response {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	suite.Require().Nil(err)
+	req, err := http.NewRequest(http.MethodPost, suite.server.URL, bytes.NewReader(body))
+	suite.Require().Nil(err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set(auth.Header, "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	suite.Require().Nil(err)
+	return resp
+}
+
+func (suite *GraphQLUnitTestSuite) query(query string, variables map[string]interface{}) graphQLResponse {
+	resp := suite.post(graphQLTestToken, query, variables)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	suite.Require().Nil(json.NewDecoder(resp.Body).Decode(&result))
+	return result
+}
+
+func (suite *GraphQLUnitTestSuite) TestQuery_WithoutValidToken_ExpectUnauthorized() {
+	a := assert.New(suite.T())
+
+	for _, token := range []string{"", "wrong-token"} {
+		resp := suite.post(token, `{ book(id: "3") { title } }`, nil)
+		var result graphQLResponse
+		suite.Require().Nil(json.NewDecoder(resp.Body).Decode(&result))
+		resp.Body.Close()
+
+		a.Equal(http.StatusUnauthorized, resp.StatusCode, token)
+		suite.Require().Len(result.Errors, 1)
+	}
+	a.Nil(suite.scoped)
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByID", mock.Anything)
+}
+
+func (suite *GraphQLUnitTestSuite) TestQuery_WithToken_ExpectScopedToActorAndItsTenant() {
+	a := assert.New(suite.T())
+	suite.bookRepo.On("GetByID", 3).Return(domain.NilBookPtr, &domain.RepoError{Type: domain.NotFound})
+
+	suite.query(`{ book(id: "3") { title } }`, nil)
+
+	suite.Require().NotNil(suite.scoped)
+	a.Equal("tester", auth.ActorFromContext(suite.scoped))
+	a.Equal("acme", domain.TenantFromContext(suite.scoped))
+}
+
+func (suite *GraphQLUnitTestSuite) TestUserRents_ExpectBooksLoadedInOneBatch() {
+	a := assert.New(suite.T())
+	user := domain.User{Firstname: "first", Email: "first@mail.com", Type: domain.CUSTOMER}
+	user.ID = 5
+	suite.userRepo.On("GetByIDs", []int{5}).Return([]domain.User{user}, domain.NilRepoErrPtr)
+	rents := []domain.RentDetails{{UserID: 5, BookID: 1}, {UserID: 5, BookID: 2}, {UserID: 5, BookID: 1, Status: domain.RETURNED}}
+	for i := range rents {
+		rents[i].ID = uint(i + 1)
+	}
+	suite.rentRepo.On("GetByUser", 5).Return(rents, domain.NilRepoErrPtr)
+	books := []domain.Book{{Title: "first"}, {Title: "second"}}
+	books[0].ID = 1
+	books[1].ID = 2
+	suite.bookRepo.On("GetByIDs", mock.Anything).Return(books, domain.NilRepoErrPtr)
+
+	result := suite.query(`{ user(id: "5") { email type rents { status book { title } } } }`, nil)
+
+	suite.Require().Empty(result.Errors)
+	a.JSONEq(`{"user": {"email": "first@mail.com", "type": "CUSTOMER", "rents": [
+		{"status": "RENTED", "book": {"title": "first"}},
+		{"status": "RENTED", "book": {"title": "second"}},
+		{"status": "RETURNED", "book": {"title": "first"}}]}}`, string(result.Data))
+	suite.bookRepo.AssertNumberOfCalls(suite.T(), "GetByIDs", 1)
+	a.ElementsMatch([]int{1, 2}, suite.bookRepo.Calls[0].Arguments.Get(0))
+}
+
+func (suite *GraphQLUnitTestSuite) TestUserRents_WithStatus_ExpectFiltered() {
+	a := assert.New(suite.T())
+	user := domain.User{Email: "first@mail.com"}
+	user.ID = 5
+	suite.userRepo.On("GetByIDs", []int{5}).Return([]domain.User{user}, domain.NilRepoErrPtr)
+	rents := []domain.RentDetails{{UserID: 5, BookID: 1}, {UserID: 5, BookID: 2, Status: domain.RETURNED}}
+	suite.rentRepo.On("GetByUser", 5).Return(rents, domain.NilRepoErrPtr)
+	book := domain.Book{Title: "second"}
+	book.ID = 2
+	suite.bookRepo.On("GetByIDs", []int{2}).Return([]domain.Book{book}, domain.NilRepoErrPtr)
+
+	result := suite.query(`{ user(id: "5") { rents(status: RETURNED) { book { title } user { email } } } }`, nil)
+
+	suite.Require().Empty(result.Errors)
+	a.JSONEq(`{"user": {"rents": [{"book": {"title": "second"}, "user": {"email": "first@mail.com"}}]}}`, string(result.Data))
+	suite.userRepo.AssertNumberOfCalls(suite.T(), "GetByIDs", 1)
+}
+
+func (suite *GraphQLUnitTestSuite) TestBook_WithMissingBook_ExpectNotFoundCode() {
+	a := assert.New(suite.T())
+	suite.bookRepo.On("GetByIDs", []int{9}).Return([]domain.Book{}, domain.NilRepoErrPtr)
+
+	result := suite.query(`{ book(id: "9") { title } }`, nil)
+
+	suite.Require().Len(result.Errors, 1)
+	a.Equal("NOT_FOUND", result.Errors[0].Extensions["code"])
+}
+
+func (suite *GraphQLUnitTestSuite) TestBook_WithInvalidID_ExpectInvalidArgumentsCode() {
+	a := assert.New(suite.T())
+
+	result := suite.query(`{ book(id: "abc") { title } }`, nil)
+
+	suite.Require().Len(result.Errors, 1)
+	a.Equal("INVALID_ARGUMENTS", result.Errors[0].Extensions["code"])
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByIDs", mock.Anything)
+}
+
+func (suite *GraphQLUnitTestSuite) TestRentBook_WithEmptyStock_ExpectNotEnoughBooksCode() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "title"}
+	book.ID = 3
+	suite.bookRepo.On("GetByID", 3).Return(&book, domain.NilRepoErrPtr)
+
+	result := suite.query(`mutation($user: ID!, $book: ID!) { rentBook(userId: $user, bookId: $book) { id } }`,
+		map[string]interface{}{"user": "5", "book": "3"})
+
+	suite.Require().Len(result.Errors, 1)
+	a.Equal("NOT_ENOUGH_BOOKS_ON_STOCK", result.Errors[0].Extensions["code"])
+	suite.rentRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *GraphQLUnitTestSuite) TestReturnBook_ExpectReturnedRentWithBook() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "title", Stock: 1}
+	book.ID = 3
+	rent := domain.RentDetails{UserID: 5, BookID: 3, Book: book}
+	rent.ID = 7
+	returned := rent
+	returned.Status = domain.RETURNED
+	suite.rentRepo.On("GetByID", 7).Return(&rent, domain.NilRepoErrPtr).Once()
+	suite.rentRepo.On("GetByID", 7).Return(&returned, domain.NilRepoErrPtr).Once()
+	suite.rentRepo.On("Update", &rent, mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.bookRepo.On("GetByIDForUpdate", 3).Return(&book, domain.NilRepoErrPtr)
+	suite.bookRepo.On("Update", mock.Anything, mock.Anything).Return(domain.NilRepoErrPtr)
+
+	result := suite.query(`mutation { returnBook(rentId: "7") { id status book { title } } }`, nil)
+
+	suite.Require().Empty(result.Errors)
+	a.JSONEq(`{"returnBook": {"id": "7", "status": "RETURNED", "book": {"title": "title"}}}`, string(result.Data))
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByIDs", mock.Anything)
+}
@@ -0,0 +1,249 @@
+package test
+
+import (
+	"context"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/grpcapi"
+	"github.com/idj1997/book-rent-core/grpcapi/pb"
+	"github.com/idj1997/book-rent-core/logging"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"io"
+	"net"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const grpcTestToken = "secret-token"
+
+type GRPCUnitTestSuite struct {
+	suite.Suite
+	bookRepo  *repo_mocks.MockedBookRepository
+	userRepo  *repo_mocks.MockedUserRepository
+	rentRepo  *repo_mocks.MockedRentDetailsRepository
+	auditRepo *repo_mocks.MockedAuditRepository
+	server    *grpc.Server
+	conn      *grpc.ClientConn
+	books     pb.BookServiceClient
+	users     pb.UserServiceClient
+	rents     pb.RentDetailsServiceClient
+}
+
+func TestGRPCUnitTestSuite(t *testing.T) {
+	suite.Run(t, &GRPCUnitTestSuite{})
+}
+
+func (suite *GRPCUnitTestSuite) SetupTest() {
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.userRepo = &repo_mocks.MockedUserRepository{}
+	suite.rentRepo = &repo_mocks.MockedRentDetailsRepository{}
+	suite.auditRepo = &repo_mocks.MockedAuditRepository{}
+	outboxRepo := &repo_mocks.MockedOutboxRepository{}
+	outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	tx := &repo_mocks.MockedTransactor{Repos: domain.Repositories{
+		Books:  suite.bookRepo,
+		Users:  suite.userRepo,
+		Rents:  suite.rentRepo,
+		Audit:  suite.auditRepo,
+		Outbox: outboxRepo}}
+	scope := func(ctx context.Context) grpcapi.Services {
+		return grpcapi.Services{
+			Books: service.NewBookService(suite.bookRepo, tx).WithLogger(logging.FromContext(ctx)),
+			Users: (&service.UserService{Repo: suite.userRepo, Tx: tx}).WithLogger(logging.FromContext(ctx)),
+			Rents: (&service.RentDetailsService{RentRepo: suite.rentRepo, BookRepo: suite.bookRepo, Tx: tx}).WithContext(ctx)}
+	}
+
+	suite.server = grpcapi.NewServer(config.GRPCConfig{Address: "bufconn"},
+		config.AuthConfig{Actors: map[string]config.ActorConfig{"tester": {Token: grpcTestToken}}}, scope)
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = suite.server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure())
+	suite.Require().Nil(err)
+	suite.conn = conn
+	suite.books = pb.NewBookServiceClient(conn)
+	suite.users = pb.NewUserServiceClient(conn)
+	suite.rents = pb.NewRentDetailsServiceClient(conn)
+}
+
+func (suite *GRPCUnitTestSuite) TearDownTest() {
+	_ = suite.conn.Close()
+	suite.server.Stop()
+}
+
+func authorized(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), grpcapi.AuthorizationHeader, "Bearer "+token)
+}
+
+func (suite *GRPCUnitTestSuite) TestGetBook_ExpectBookMapped() {
+	a := assert.New(suite.T())
+	book := &domain.Book{Title: "title", Content: "content", Stock: 3, Price: 1500, ISBN: "isbn"}
+	book.ID = 7
+	suite.bookRepo.On("GetByID", 7).Return(book, domain.NilRepoErrPtr)
+
+	resp, err := suite.books.GetBook(authorized(grpcTestToken), &pb.GetBookRequest{Id: 7})
+
+	suite.Require().Nil(err)
+	a.Equal(uint32(7), resp.Id)
+	a.Equal("title", resp.Title)
+	a.Equal(int32(3), resp.Stock)
+	a.Equal(int32(1500), resp.Price)
+	a.Equal("isbn", resp.Isbn)
+}
+
+func (suite *GRPCUnitTestSuite) TestGetBook_WithNotFound_ExpectNotFoundCodeAndReason() {
+	a := assert.New(suite.T())
+	suite.bookRepo.On("GetByID", 1).Return(domain.NilBookPtr, &domain.RepoError{Type: domain.NotFound})
+
+	_, err := suite.books.GetBook(authorized(grpcTestToken), &pb.GetBookRequest{Id: 1})
+
+	st := status.Convert(err)
+	a.Equal(codes.NotFound, st.Code())
+	suite.Require().Len(st.Details(), 1)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	a.Equal("NOT_FOUND", info.Reason)
+	a.Equal(grpcapi.ErrorDomain, info.Domain)
+}
+
+func (suite *GRPCUnitTestSuite) TestCall_WithoutValidToken_ExpectUnauthenticated() {
+	a := assert.New(suite.T())
+
+	_, err := suite.books.GetBook(context.Background(), &pb.GetBookRequest{Id: 1})
+	a.Equal(codes.Unauthenticated, status.Code(err))
+
+	_, err = suite.books.GetBook(authorized("wrong"), &pb.GetBookRequest{Id: 1})
+	a.Equal(codes.Unauthenticated, status.Code(err))
+
+	stream, err := suite.rents.StreamActiveRents(authorized("wrong"), &pb.StreamActiveRentsRequest{})
+	suite.Require().Nil(err)
+	_, err = stream.Recv()
+	a.Equal(codes.Unauthenticated, status.Code(err))
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByID", mock.Anything)
+}
+
+func (suite *GRPCUnitTestSuite) TestCreateUser_ExpectAuditedUnderActorWithoutPassword() {
+	a := assert.New(suite.T())
+	suite.userRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+
+	resp, err := suite.users.CreateUser(authorized(grpcTestToken), &pb.CreateUserRequest{
+		Firstname: "first",
+		Email:     "first@mail.com",
+		Password:  "password",
+		Type:      pb.UserType_USER_TYPE_CUSTOMER})
+
+	suite.Require().Nil(err)
+	a.Equal("first@mail.com", resp.Email)
+	a.Equal(pb.UserType_USER_TYPE_CUSTOMER, resp.Type)
+	created := suite.userRepo.Calls[0].Arguments.Get(0).(*domain.User)
+	a.Nil(bcrypt.CompareHashAndPassword([]byte(created.Password), []byte("password")))
+	a.Equal(domain.CUSTOMER, created.Type)
+	record := suite.auditRepo.Calls[0].Arguments.Get(0).(*domain.AuditRecord)
+	a.Equal("tester", record.Actor)
+}
+
+func (suite *GRPCUnitTestSuite) TestCreateUser_WithUnspecifiedType_ExpectInvalidArgument() {
+	a := assert.New(suite.T())
+
+	_, err := suite.users.CreateUser(authorized(grpcTestToken), &pb.CreateUserRequest{
+		Firstname: "first",
+		Email:     "first@mail.com",
+		Password:  "password"})
+
+	a.Equal(codes.InvalidArgument, status.Code(err))
+	suite.userRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *GRPCUnitTestSuite) TestStreamActiveRents_ExpectOnlyRentedAndExpiredStreamed() {
+	a := assert.New(suite.T())
+
+	stream, err := suite.rents.StreamActiveRents(authorized(grpcTestToken), &pb.StreamActiveRentsRequest{})
+	suite.Require().Nil(err)
+
+	var statuses []pb.RentStatus
+	for {
+		rent, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		suite.Require().Nil(err)
+		statuses = append(statuses, rent.Status)
+	}
+	a.Equal([]pb.RentStatus{pb.RentStatus_RENT_STATUS_RENTED, pb.RentStatus_RENT_STATUS_EXPIRED}, statuses)
+}
+
+func (suite *GRPCUnitTestSuite) TestCall_ExpectLoggedWithEchoedRequestID() {
+	a := assert.New(suite.T())
+	hook := logtest.NewGlobal()
+	defer log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+	suite.bookRepo.On("GetByID", 1).Return(domain.NilBookPtr, &domain.RepoError{Type: domain.NotFound})
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(authorized(grpcTestToken), logging.RequestIDHeader, "request-1")
+	_, _ = suite.books.GetBook(ctx, &pb.GetBookRequest{Id: 1}, grpc.Header(&header))
+
+	a.Equal([]string{"request-1"}, header.Get(logging.RequestIDHeader))
+	entry := hook.LastEntry()
+	suite.Require().NotNil(entry)
+	a.Equal(log.WarnLevel, entry.Level)
+	a.Equal("request-1", entry.Data[logging.RequestIDField])
+	a.Equal("/bookrent.v1.BookService/GetBook", entry.Data["grpc_method"])
+	a.Equal("NotFound", entry.Data["grpc_code"])
+}
+
+func (suite *GRPCUnitTestSuite) TestCall_WithTraceparent_ExpectServerSpanContinuesRemoteTrace() {
+	a := assert.New(suite.T())
+	recorder, reset := recordSpans()
+	defer reset()
+	suite.bookRepo.On("GetByID", 1).Return(domain.NilBookPtr, &domain.RepoError{Type: domain.NotFound})
+
+	ctx := metadata.AppendToOutgoingContext(authorized(grpcTestToken), "traceparent", remoteTraceparent)
+	_, _ = suite.books.GetBook(ctx, &pb.GetBookRequest{Id: 1})
+
+	server := spansByName(recorder)["bookrent.v1.BookService/GetBook"]
+	suite.Require().NotNil(server)
+	a.Equal("4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	a.Equal("00f067aa0ba902b7", server.Parent().SpanID().String())
+	a.Equal(trace.SpanKindServer, server.SpanKind())
+	a.Contains(server.Attributes(), attribute.String("rpc.grpc.status_code", "NotFound"))
+	a.Equal(otelcodes.Unset, server.Status().Code)
+}
+
+func (suite *GRPCUnitTestSuite) TestCode_ExpectEveryServiceErrorTypeMapped() {
+	a := assert.New(suite.T())
+
+	a.Equal(codes.NotFound, grpcapi.Code(service.NotFound))
+	a.Equal(codes.AlreadyExists, grpcapi.Code(service.AlreadyExist))
+	a.Equal(codes.InvalidArgument, grpcapi.Code(service.InvalidArguments))
+	a.Equal(codes.FailedPrecondition, grpcapi.Code(service.NotEnoughBooksOnStock))
+	a.Equal(codes.FailedPrecondition, grpcapi.Code(service.BookAlreadyReturned))
+	a.Equal(codes.FailedPrecondition, grpcapi.Code(service.ActiveBookRents))
+	a.Equal(codes.FailedPrecondition, grpcapi.Code(service.InvalidStatusTransition))
+	a.Equal(codes.Unknown, grpcapi.Code(service.Unknown))
+	a.Equal(codes.Internal, status.Code(grpcapi.Status(io.ErrUnexpectedEOF)))
+	a.Equal(codes.Canceled, status.Code(grpcapi.Status(context.Canceled)))
+	a.Equal(codes.DeadlineExceeded, status.Code(grpcapi.Status(context.DeadlineExceeded)))
+	a.Nil(grpcapi.Status(nil))
+}
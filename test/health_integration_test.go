@@ -0,0 +1,43 @@
+package test
+
+import (
+	"context"
+	"github.com/idj1997/book-rent-core/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type HealthIntegrationTestSuite struct {
+	suite.Suite
+	Db  *gorm.DB
+	Cfg *config.Config
+}
+
+func TestHealthIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &HealthIntegrationTestSuite{})
+}
+
+func (suite *HealthIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db, suite.Cfg = db, cfg
+}
+
+func (suite *HealthIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *HealthIntegrationTestSuite) TestReadiness_WithMigratedDB_ExpectUp() {
+	a := assert.New(suite.T())
+
+	report := config.NewHealthChecker(suite.Db).Readiness(context.Background())
+	a.Equal(config.HealthUp, report.Status)
+	a.Equal(config.SchemaVersion, report.SchemaVersion)
+	a.Equal(suite.Cfg.Database.Pool.MaxOpenConns, report.Pool.MaxOpenConnections)
+	a.Empty(report.Error)
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/idj1997/book-rent-core/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type flakyPinger struct {
+	failures int
+	pings    int
+}
+
+func (p *flakyPinger) Ping() error {
+	p.pings++
+	if p.pings <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+type HealthUnitTestSuite struct {
+	suite.Suite
+}
+
+func TestHealthUnitTestSuite(t *testing.T) {
+	suite.Run(t, &HealthUnitTestSuite{})
+}
+
+func (suite *HealthUnitTestSuite) TestWaitForDB_WithRecoveringDB_ExpectRetried() {
+	a := assert.New(suite.T())
+	pinger := &flakyPinger{failures: 2}
+
+	err := config.WaitForDB(pinger, config.StartupConfig{Attempts: 3, Backoff: time.Millisecond})
+	a.Nil(err)
+	a.Equal(3, pinger.pings)
+}
+
+func (suite *HealthUnitTestSuite) TestWaitForDB_WithUnreachableDB_ExpectLastError() {
+	a := assert.New(suite.T())
+	pinger := &flakyPinger{failures: 5}
+
+	err := config.WaitForDB(pinger, config.StartupConfig{Attempts: 3, Backoff: time.Millisecond})
+	a.NotNil(err)
+	a.Equal(3, pinger.pings)
+}
+
+func (suite *HealthUnitTestSuite) TestReadinessHandler_WithUnreachableDB_ExpectServiceUnavailable() {
+	a := assert.New(suite.T())
+	db, err := gorm.Open(
+		postgres.Open("host=127.0.0.1 port=1 user=postgres dbname=none sslmode=disable connect_timeout=1"),
+		&gorm.Config{DisableAutomaticPing: true})
+	a.Nil(err)
+	checker := config.NewHealthChecker(db)
+
+	a.Equal(config.HealthDown, checker.Liveness(context.Background()).Status)
+
+	recorder := httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	a.Equal(http.StatusServiceUnavailable, recorder.Code)
+
+	var report config.HealthReport
+	a.Nil(json.NewDecoder(recorder.Body).Decode(&report))
+	a.Equal(config.HealthDown, report.Status)
+	a.Equal(config.SchemaVersion, report.ExpectedSchemaVersion)
+	a.NotEmpty(report.Error)
+}
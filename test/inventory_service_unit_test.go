@@ -0,0 +1,123 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type InventoryServiceUnitTestSuite struct {
+	suite.Suite
+	service      *service.InventoryService
+	bookRepo     *repo_mocks.MockedBookRepository
+	movementRepo *repo_mocks.MockedStockMovementRepository
+	outboxRepo   *repo_mocks.MockedOutboxRepository
+	branchRepo   *repo_mocks.MockedBranchRepository
+}
+
+func TestInventoryServiceUnitTestSuite(t *testing.T) {
+	suite.Run(t, &InventoryServiceUnitTestSuite{})
+}
+
+func (suite *InventoryServiceUnitTestSuite) SetupTest() {
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.movementRepo = &repo_mocks.MockedStockMovementRepository{}
+	auditRepo := &repo_mocks.MockedAuditRepository{}
+	auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.outboxRepo = &repo_mocks.MockedOutboxRepository{}
+	suite.outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.branchRepo = repo_mocks.NewMockedBranchRepository()
+	suite.service = service.NewInventoryService(suite.movementRepo, &repo_mocks.MockedTransactor{
+		Repos: domain.Repositories{
+			Books:     suite.bookRepo,
+			Audit:     auditRepo,
+			Outbox:    suite.outboxRepo,
+			Movements: suite.movementRepo,
+			Branches:  suite.branchRepo}})
+}
+
+func (suite *InventoryServiceUnitTestSuite) TestRecordMovement_WithPurchase_ExpectStockIncreased() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "test", Content: "test", Stock: 2}
+	book.ID = 10
+
+	suite.bookRepo.
+		On("GetByIDForUpdate", 10).
+		Return(&book, domain.NilRepoErrPtr)
+	suite.branchRepo.
+		On("GetStock", 10, 1).
+		Return(&domain.BranchStock{BookID: 10, BranchID: 1, Stock: 2}, domain.NilRepoErrPtr)
+	suite.movementRepo.
+		On("Create", mock.MatchedBy(func(movement *domain.StockMovement) bool {
+			return movement.BookID == 10 && movement.Kind == domain.MovementPurchase &&
+				movement.BranchID == 1 && movement.Quantity == 5 && movement.Actor == "admin"
+		})).
+		Return(domain.NilRepoErrPtr)
+	suite.bookRepo.
+		On("Update", &book, map[string]interface{}{"stock": 7}).
+		Return(domain.NilRepoErrPtr)
+
+	movement, err := suite.service.WithActor("admin").RecordMovement(10, 0, domain.MovementPurchase, 5, "new delivery")
+	a.Nil(err)
+	a.Equal("new delivery", movement.Reason)
+	suite.bookRepo.AssertExpectations(suite.T())
+	suite.branchRepo.AssertCalled(suite.T(), "SaveStock", &domain.BranchStock{BookID: 10, BranchID: 1, Stock: 7})
+}
+
+func (suite *InventoryServiceUnitTestSuite) TestRecordMovement_WithWriteOffAboveStock_ExpectNotEnoughBooks() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "test", Content: "test", Stock: 2}
+
+	suite.bookRepo.
+		On("GetByIDForUpdate", 10).
+		Return(&book, domain.NilRepoErrPtr)
+	suite.branchRepo.
+		On("GetStock", mock.Anything, 1).
+		Return(&domain.BranchStock{Stock: 2}, domain.NilRepoErrPtr)
+
+	_, err := suite.service.RecordMovement(10, 0, domain.MovementWriteOff, -3, "flood")
+	a.NotNil(err)
+	a.Equal(service.NotEnoughBooksOnStock, err.(*service.ServiceError).Type)
+	suite.movementRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *InventoryServiceUnitTestSuite) TestRecordMovement_WithInvalidMovement_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	invalid := []struct {
+		kind     domain.MovementKind
+		quantity int
+		reason   string
+	}{
+		{domain.MovementRentOut, -1, "manual rent"},
+		{domain.MovementPurchase, -1, "negative purchase"},
+		{domain.MovementWriteOff, 1, "positive write-off"},
+		{domain.MovementCorrection, 0, "empty correction"},
+		{domain.MovementCorrection, 2, ""}}
+	for _, movement := range invalid {
+		_, err := suite.service.RecordMovement(10, 0, movement.kind, movement.quantity, movement.reason)
+		a.NotNil(err)
+		a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+	}
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByIDForUpdate", mock.Anything)
+}
+
+func (suite *InventoryServiceUnitTestSuite) TestGetMovements_WithValidBook_ExpectHistory() {
+	a := assert.New(suite.T())
+	movements := []domain.StockMovement{
+		{BookID: 10, Kind: domain.MovementPurchase, Quantity: 3},
+		{BookID: 10, Kind: domain.MovementRentOut, Quantity: -1, RentID: 1}}
+
+	suite.movementRepo.
+		On("GetByBook", 10).
+		Return(movements, domain.NilRepoErrPtr)
+
+	history, err := suite.service.GetMovements(10)
+	a.Nil(err)
+	a.Equal(movements, history)
+}
@@ -0,0 +1,201 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/logging"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LoggingUnitTestSuite struct {
+	suite.Suite
+	dir string
+	now time.Time
+}
+
+func TestLoggingUnitTestSuite(t *testing.T) {
+	suite.Run(t, &LoggingUnitTestSuite{})
+}
+
+func (suite *LoggingUnitTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "logging")
+	suite.Require().Nil(err)
+	suite.dir = dir
+	suite.now = time.Now()
+}
+
+func (suite *LoggingUnitTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.dir)
+}
+
+func (suite *LoggingUnitTestSuite) openFile(maxSize int64, interval time.Duration, maxAge time.Duration, maxBackups int) *logging.RotatingFile {
+	file, err := logging.OpenRotatingFile(filepath.Join(suite.dir, "app.log"), maxSize, interval, maxAge, maxBackups)
+	suite.Require().Nil(err)
+	file.Now = func() time.Time {
+		return suite.now
+	}
+	return file
+}
+
+func (suite *LoggingUnitTestSuite) write(file *logging.RotatingFile, line string) {
+	_, err := file.Write([]byte(line))
+	suite.Require().Nil(err)
+	suite.now = suite.now.Add(time.Second)
+}
+
+func (suite *LoggingUnitTestSuite) TestOpenRotatingFile_WithExistingFile_ExpectAppended() {
+	a := assert.New(suite.T())
+	path := filepath.Join(suite.dir, "app.log")
+	suite.Require().Nil(ioutil.WriteFile(path, []byte("old\n"), 0644))
+
+	file := suite.openFile(0, 0, 0, 0)
+	suite.write(file, "new\n")
+	suite.Require().Nil(file.Close())
+
+	content, err := ioutil.ReadFile(path)
+	suite.Require().Nil(err)
+	a.Equal("old\nnew\n", string(content))
+}
+
+func (suite *LoggingUnitTestSuite) TestWrite_OverMaxSize_ExpectRotated() {
+	a := assert.New(suite.T())
+	file := suite.openFile(10, 0, 0, 0)
+	defer file.Close()
+
+	suite.write(file, "12345678\n")
+	suite.write(file, "abcdefgh\n")
+
+	backups, err := file.Backups()
+	suite.Require().Nil(err)
+	suite.Require().Len(backups, 1)
+	backup, _ := ioutil.ReadFile(backups[0])
+	current, _ := ioutil.ReadFile(file.Path)
+	a.Equal("12345678\n", string(backup))
+	a.Equal("abcdefgh\n", string(current))
+}
+
+func (suite *LoggingUnitTestSuite) TestWrite_AfterInterval_ExpectRotated() {
+	a := assert.New(suite.T())
+	file := suite.openFile(0, time.Hour, 0, 0)
+	defer file.Close()
+
+	suite.write(file, "first\n")
+	suite.write(file, "same period\n")
+	suite.now = suite.now.Add(time.Hour)
+	suite.write(file, "next period\n")
+
+	backups, err := file.Backups()
+	suite.Require().Nil(err)
+	a.Len(backups, 1)
+	current, _ := ioutil.ReadFile(file.Path)
+	a.Equal("next period\n", string(current))
+}
+
+func (suite *LoggingUnitTestSuite) TestRotate_OverMaxBackups_ExpectOldestRemoved() {
+	a := assert.New(suite.T())
+	file := suite.openFile(0, 0, 0, 2)
+	defer file.Close()
+
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		suite.write(file, line)
+		suite.Require().Nil(file.Rotate())
+	}
+
+	backups, err := file.Backups()
+	suite.Require().Nil(err)
+	suite.Require().Len(backups, 2)
+	oldest, _ := ioutil.ReadFile(backups[0])
+	a.Equal("3\n", string(oldest))
+}
+
+func (suite *LoggingUnitTestSuite) TestRotate_WithExpiredBackup_ExpectRemoved() {
+	a := assert.New(suite.T())
+	file := suite.openFile(0, 0, 24*time.Hour, 0)
+	defer file.Close()
+
+	suite.write(file, "old\n")
+	suite.Require().Nil(file.Rotate())
+	suite.now = suite.now.Add(48 * time.Hour)
+	suite.write(file, "recent\n")
+	suite.Require().Nil(file.Rotate())
+
+	backups, err := file.Backups()
+	suite.Require().Nil(err)
+	suite.Require().Len(backups, 1)
+	content, _ := ioutil.ReadFile(backups[0])
+	a.Equal("recent\n", string(content))
+}
+
+func (suite *LoggingUnitTestSuite) TestConfigure_WithMissingFile_ExpectCreated() {
+	a := assert.New(suite.T())
+	path := filepath.Join(suite.dir, "missing.log")
+	logger := log.New()
+
+	closer, err := logging.Configure(logger, config.LoggingConfig{
+		Level:      "warn",
+		Format:     "text",
+		OutputType: "file",
+		FilePath:   path})
+	suite.Require().Nil(err)
+	logger.Info("dropped")
+	logger.Warn("kept")
+	suite.Require().Nil(closer.Close())
+
+	content, err := ioutil.ReadFile(path)
+	suite.Require().Nil(err)
+	a.NotContains(string(content), "dropped")
+	a.Contains(string(content), "level=warning msg=kept")
+}
+
+func (suite *LoggingUnitTestSuite) TestConfigure_WithInvalidOutput_ExpectError() {
+	a := assert.New(suite.T())
+
+	_, err := logging.Configure(log.New(), config.LoggingConfig{OutputType: "syslog"})
+	a.NotNil(err)
+	a.Contains(err.Error(), "logging.outputType")
+}
+
+func (suite *LoggingUnitTestSuite) TestWithFields_ExpectFieldsCarriedByContext() {
+	a := assert.New(suite.T())
+	var out bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(&log.JSONFormatter{})
+
+	ctx := logging.NewContext(context.Background(), log.NewEntry(logger))
+	ctx = logging.WithRentID(logging.WithUserID(logging.WithRequestID(ctx, "req-1"), 7), 9)
+	logging.FromContext(ctx).Info("rented")
+
+	a.Contains(out.String(), `"request_id":"req-1"`)
+	a.Contains(out.String(), `"user_id":7`)
+	a.Contains(out.String(), `"rent_id":9`)
+}
+
+func (suite *LoggingUnitTestSuite) TestMiddleware_WithoutRequestID_ExpectGenerated() {
+	a := assert.New(suite.T())
+	var requestID interface{}
+	handler := logging.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = logging.FromContext(r.Context()).Data[logging.RequestIDField]
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.NotEmpty(requestID)
+	a.Equal(requestID, recorder.Header().Get(logging.RequestIDHeader))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(logging.RequestIDHeader, "given")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	a.Equal("given", requestID)
+}
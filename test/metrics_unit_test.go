@@ -0,0 +1,195 @@
+package test
+
+import (
+	"context"
+	"github.com/idj1997/book-rent-core/cache"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/metrics"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// histogramCount returns number of observations of label values of vec
+func histogramCount(vec *prometheus.HistogramVec, labelValues ...string) uint64 {
+	var metric dto.Metric
+	_ = vec.WithLabelValues(labelValues...).(prometheus.Metric).Write(&metric)
+	return metric.GetHistogram().GetSampleCount()
+}
+
+type MetricsUnitTestSuite struct {
+	suite.Suite
+	Registry *prometheus.Registry
+	Services *metrics.ServiceMetrics
+}
+
+func TestMetricsUnitTestSuite(t *testing.T) {
+	suite.Run(t, &MetricsUnitTestSuite{})
+}
+
+func (suite *MetricsUnitTestSuite) SetupTest() {
+	suite.Registry = metrics.NewRegistry()
+	services, err := metrics.NewServiceMetrics(suite.Registry)
+	suite.Require().Nil(err)
+	suite.Services = services
+	service.SetObserver(suite.Services)
+}
+
+func (suite *MetricsUnitTestSuite) TearDownTest() {
+	service.SetObserver(nil)
+}
+
+// scrape returns registry as served on metrics endpoint
+func (suite *MetricsUnitTestSuite) scrape() *httptest.ResponseRecorder {
+	server := metrics.NewServer(config.MetricsConfig{Address: ":0"}, suite.Registry, nil)
+	suite.Require().NotNil(server)
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, metrics.Path, nil))
+	return recorder
+}
+
+func (suite *MetricsUnitTestSuite) TestNewServiceMetrics_WithRegisteredRegistry_ExpectError() {
+	_, err := metrics.NewServiceMetrics(suite.Registry)
+
+	suite.NotNil(err)
+}
+
+func (suite *MetricsUnitTestSuite) TestServiceMetrics_WithServiceCall_ExpectHistogramExposed() {
+	a := assert.New(suite.T())
+
+	suite.Services.ObserveCall("BookService", "GetByID", "ok", 300*time.Millisecond)
+
+	text := suite.scrape().Body.String()
+	a.Contains(text, "# TYPE bookrent_service_call_duration_seconds histogram")
+	a.Contains(text, `bookrent_service_call_duration_seconds_bucket{method="GetByID",outcome="ok",service="BookService",le="0.25"} 0`)
+	a.Contains(text, `bookrent_service_call_duration_seconds_bucket{method="GetByID",outcome="ok",service="BookService",le="0.5"} 1`)
+	a.Contains(text, `bookrent_service_call_duration_seconds_count{method="GetByID",outcome="ok",service="BookService"} 1`)
+}
+
+func (suite *MetricsUnitTestSuite) TestServiceCall_WithNotFound_ExpectCountedByOutcome() {
+	a := assert.New(suite.T())
+	repo := &repo_mocks.MockedUserRepository{}
+	repo.On("GetByID", 1).Return(domain.NilUserPtr, &domain.RepoError{Type: domain.NotFound})
+	repo.On("GetByID", 2).Return(&domain.User{}, domain.NilRepoErrPtr)
+	users := &service.UserService{Repo: repo}
+
+	_, _ = users.GetByID(1)
+	_, _ = users.GetByID(2)
+	_, _ = users.GetByID(2)
+
+	a.Equal(float64(1), testutil.ToFloat64(suite.Services.Calls.WithLabelValues("UserService", "GetByID", "not_found")))
+	a.Equal(float64(2), testutil.ToFloat64(suite.Services.Calls.WithLabelValues("UserService", "GetByID", "ok")))
+	a.Equal(uint64(2), histogramCount(suite.Services.Durations, "UserService", "GetByID", "ok"))
+}
+
+func (suite *MetricsUnitTestSuite) TestUpdateToLost_WithoutOverdueRents_ExpectJobRunCounted() {
+	a := assert.New(suite.T())
+	rentRepo := &repo_mocks.MockedRentDetailsRepository{}
+	rentRepo.
+		On("GetByStatusAndDeadline", domain.EXPIRED, time.Time{}, mock.Anything).
+		Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+	rents := &service.RentDetailsService{RentRepo: rentRepo}
+
+	a.Nil(rents.UpdateToLost(time.Hour))
+	a.Equal(float64(1), testutil.ToFloat64(suite.Services.JobRuns.WithLabelValues(service.DeclareLostJob, "ok")))
+	a.Equal(float64(0), testutil.ToFloat64(suite.Services.JobItems.WithLabelValues(service.DeclareLostJob)))
+	a.Contains(suite.scrape().Body.String(), `bookrent_service_calls_total{method="UpdateToLost",outcome="ok",service="RentDetailsService"} 1`)
+}
+
+func (suite *MetricsUnitTestSuite) TestStatsCollector_ExpectActiveRentsAndLowStockGauges() {
+	a := assert.New(suite.T())
+	repo := &repo_mocks.MockedStatsRepository{}
+	repo.
+		On("RentsByStatus", metrics.ActiveRentStatuses).
+		Return([]domain.RentStatusCount{{TenantID: "default", Status: domain.EXPIRED, Rents: 3}}, domain.NilRepoErrPtr)
+	repo.
+		On("LowStock", 2).
+		Return([]domain.LowStockCount{{TenantID: "default", BranchID: 1, Books: 4}}, domain.NilRepoErrPtr)
+	suite.Require().Nil(suite.Registry.Register(&metrics.StatsCollector{Repo: repo, LowStockThreshold: 2}))
+
+	text := suite.scrape().Body.String()
+	a.Contains(text, `bookrent_active_rents{status="EXPIRED",tenant="default"} 3`)
+	a.Contains(text, `bookrent_low_stock_books{branch="1",tenant="default"} 4`)
+	a.Contains(text, "bookrent_low_stock_threshold 2")
+}
+
+func (suite *MetricsUnitTestSuite) TestStatsCollector_WithFailedQueries_ExpectThresholdStillCollected() {
+	a := assert.New(suite.T())
+	repo := &repo_mocks.MockedStatsRepository{}
+	repo.
+		On("RentsByStatus", metrics.ActiveRentStatuses).
+		Return([]domain.RentStatusCount(nil), &domain.RepoError{Type: domain.Unknown})
+	repo.
+		On("LowStock", 2).
+		Return([]domain.LowStockCount(nil), &domain.RepoError{Type: domain.Unknown})
+
+	a.Equal(1, testutil.CollectAndCount(&metrics.StatsCollector{Repo: repo, LowStockThreshold: 2}))
+}
+
+func (suite *MetricsUnitTestSuite) TestStatsCollector_WithCaches_ExpectHitsAndMissesByTenant() {
+	a := assert.New(suite.T())
+	repo := &repo_mocks.MockedStatsRepository{}
+	repo.
+		On("RentsByStatus", metrics.ActiveRentStatuses).
+		Return([]domain.RentStatusCount(nil), domain.NilRepoErrPtr)
+	repo.
+		On("LowStock", 2).
+		Return([]domain.LowStockCount(nil), domain.NilRepoErrPtr)
+	book := &domain.Book{Title: "title"}
+	book.ID = 10
+	bookRepo := &repo_mocks.MockedBookRepository{}
+	bookRepo.On("GetByID", 10).Return(book, domain.NilRepoErrPtr).Once()
+	caches := cache.NewTenants(cache.NewLRU(10, time.Minute))
+	books := cache.NewBookRepository(bookRepo, caches.ForContext(domain.WithTenant(context.Background(), "acme")))
+	_, _ = books.GetByID(10)
+	_, _ = books.GetByID(10)
+	suite.Require().Nil(suite.Registry.Register(&metrics.StatsCollector{Repo: repo, LowStockThreshold: 2, Caches: caches}))
+
+	text := suite.scrape().Body.String()
+	a.Contains(text, `bookrent_cache_hits_total{entity="book",tenant="acme"} 1`)
+	a.Contains(text, `bookrent_cache_misses_total{entity="book",tenant="acme"} 1`)
+	a.Contains(text, `bookrent_cache_misses_total{entity="user",tenant="acme"} 0`)
+}
+
+func (suite *MetricsUnitTestSuite) TestNewServer_ExpectMetricsServedOnConfiguredAddress() {
+	a := assert.New(suite.T())
+	suite.Services.ObserveJob(service.ExpireRentsJob, "ok", 1)
+
+	a.Nil(metrics.NewServer(config.MetricsConfig{}, suite.Registry, nil))
+
+	recorder := suite.scrape()
+	a.Equal(http.StatusOK, recorder.Code)
+	a.True(strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+	a.Contains(recorder.Body.String(), "# TYPE bookrent_job_runs_total counter")
+}
+
+func (suite *MetricsUnitTestSuite) TestNewServer_WithHealthChecker_ExpectProbesServed() {
+	a := assert.New(suite.T())
+	db, err := gorm.Open(
+		postgres.Open("host=127.0.0.1 port=1 user=postgres dbname=none sslmode=disable connect_timeout=1"),
+		&gorm.Config{DisableAutomaticPing: true})
+	suite.Require().Nil(err)
+	server := metrics.NewServer(config.MetricsConfig{Address: ":0"}, suite.Registry, config.NewHealthChecker(db))
+	suite.Require().NotNil(server)
+
+	for _, path := range []string{metrics.LivenessPath, metrics.ReadinessPath} {
+		recorder := httptest.NewRecorder()
+		server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		a.Equal(http.StatusServiceUnavailable, recorder.Code, path)
+		a.Contains(recorder.Body.String(), `"status":"down"`, path)
+	}
+}
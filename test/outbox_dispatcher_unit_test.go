@@ -0,0 +1,170 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/outbox"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutboxDispatcherUnitTestSuite struct {
+	suite.Suite
+	repo       *repo_mocks.MockedOutboxRepository
+	handlers   *outbox.HandlerSink
+	dispatcher *outbox.Dispatcher
+}
+
+func TestOutboxDispatcherUnitTestSuite(t *testing.T) {
+	suite.Run(t, &OutboxDispatcherUnitTestSuite{})
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) SetupTest() {
+	suite.repo = &repo_mocks.MockedOutboxRepository{}
+	suite.handlers = outbox.NewHandlerSink()
+	suite.dispatcher = &outbox.Dispatcher{
+		Repo:        suite.repo,
+		Sinks:       []outbox.Sink{suite.handlers},
+		MaxAttempts: 3}
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) TestDispatch_WithAcceptingSink_ExpectDispatched() {
+	a := assert.New(suite.T())
+	events := []domain.OutboxEvent{{ID: 1, Type: domain.BookRented, Payload: json.RawMessage(`{"rentId":1}`)}}
+	var received []domain.OutboxEvent
+	suite.handlers.Subscribe(domain.BookRented, func(event domain.OutboxEvent) error {
+		received = append(received, event)
+		return nil
+	})
+
+	suite.repo.
+		On("GetPending", mock.Anything, 100).
+		Return(events, domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("MarkDispatched", uint(1), mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	delivered, err := suite.dispatcher.Dispatch()
+	a.Nil(err)
+	a.Equal(1, delivered)
+	a.Equal(events, received)
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) TestDispatch_WithFailingSink_ExpectRetryScheduled() {
+	a := assert.New(suite.T())
+	events := []domain.OutboxEvent{{ID: 2, Type: domain.BookReturned, Attempts: 0}}
+	suite.handlers.Subscribe(domain.BookReturned, func(event domain.OutboxEvent) error {
+		return errors.New("consumer down")
+	})
+
+	suite.repo.
+		On("GetPending", mock.Anything, 100).
+		Return(events, domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("MarkFailed", uint(2), 1, mock.MatchedBy(func(next time.Time) bool {
+			return next.After(time.Now())
+		}), "consumer down").
+		Return(domain.NilRepoErrPtr)
+
+	delivered, err := suite.dispatcher.Dispatch()
+	a.Nil(err)
+	a.Zero(delivered)
+	suite.repo.AssertExpectations(suite.T())
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) TestDispatch_WithExhaustedAttempts_ExpectDeadLettered() {
+	a := assert.New(suite.T())
+	events := []domain.OutboxEvent{{ID: 3, Type: domain.RentExpired, Attempts: 2}}
+	suite.handlers.Subscribe(domain.RentExpired, func(event domain.OutboxEvent) error {
+		return errors.New("consumer down")
+	})
+
+	suite.repo.
+		On("GetPending", mock.Anything, 100).
+		Return(events, domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("MarkDeadLettered", uint(3), mock.Anything, "consumer down").
+		Return(domain.NilRepoErrPtr)
+
+	_, err := suite.dispatcher.Dispatch()
+	a.Nil(err)
+	suite.repo.AssertExpectations(suite.T())
+	suite.repo.AssertNotCalled(suite.T(), "MarkFailed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) TestExponentialBackoff_ExpectDoubledAndCapped() {
+	a := assert.New(suite.T())
+
+	a.Equal(time.Second, outbox.ExponentialBackoff(1))
+	a.Equal(4*time.Second, outbox.ExponentialBackoff(3))
+	a.Equal(time.Hour, outbox.ExponentialBackoff(100))
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) TestFileSink_WithEvents_ExpectJsonLines() {
+	a := assert.New(suite.T())
+	dir, _ := ioutil.TempDir("", "outbox")
+	defer os.RemoveAll(dir)
+	sink := outbox.NewFileSink(filepath.Join(dir, "events.jsonl"))
+
+	a.Nil(sink.Deliver(domain.OutboxEvent{ID: 1, Type: domain.BookCreated, Payload: json.RawMessage(`{"bookId":1}`)}))
+	a.Nil(sink.Deliver(domain.OutboxEvent{ID: 2, Type: domain.UserCreated, Payload: json.RawMessage(`{"userId":1}`)}))
+
+	f, err := os.Open(sink.Path)
+	a.Nil(err)
+	defer f.Close()
+
+	var lines []domain.OutboxEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event domain.OutboxEvent
+		a.Nil(json.Unmarshal(scanner.Bytes(), &event))
+		lines = append(lines, event)
+	}
+	a.Equal(2, len(lines))
+	a.Equal(domain.UserCreated, lines[1].Type)
+	a.JSONEq(`{"userId":1}`, string(lines[1].Payload))
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) TestHTTPSink_WithErrorStatus_ExpectError() {
+	a := assert.New(suite.T())
+	var idempotencyKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := outbox.NewHTTPSink(server.URL).Deliver(domain.OutboxEvent{ID: 7, Type: domain.BookCreated, Payload: json.RawMessage(`{}`)})
+	a.Error(err)
+	a.Equal("7", idempotencyKey)
+}
+
+func (suite *OutboxDispatcherUnitTestSuite) TestHTTPSink_WithAcceptingEndpoint_ExpectDelivered() {
+	a := assert.New(suite.T())
+	var received domain.OutboxEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	err := outbox.NewHTTPSink(server.URL).Deliver(domain.OutboxEvent{ID: 8, Type: domain.StockChanged, Payload: json.RawMessage(`{"bookId":1}`)})
+	a.Nil(err)
+	a.Equal(uint(8), received.ID)
+	a.Equal(domain.StockChanged, received.Type)
+}
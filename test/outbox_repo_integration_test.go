@@ -0,0 +1,102 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type OutboxRepoIntegrationTestSuite struct {
+	suite.Suite
+	Repo *repository.GormOutboxRepository
+	Db   *gorm.DB
+}
+
+func TestOutboxRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &OutboxRepoIntegrationTestSuite{})
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) SetupTest() {
+	tx := suite.Db.Begin()
+	suite.Repo = repository.NewGormOutboxRepository(tx)
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) TearDownTest() {
+	suite.Repo.Db.Rollback()
+	suite.Repo.Db = nil
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) createEvent(nextAttemptAt time.Time) *domain.OutboxEvent {
+	event := domain.OutboxEvent{
+		Type:          domain.BookCreated,
+		Payload:       json.RawMessage(`{"bookId":10000}`),
+		NextAttemptAt: nextAttemptAt}
+	_ = suite.Repo.Create(&event)
+	return &event
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) TestGetPending_WithDueAndDelayedEvents_ExpectOnlyDue() {
+	a := assert.New(suite.T())
+	due := suite.createEvent(time.Now().Add(-time.Minute))
+	suite.createEvent(time.Now().Add(time.Hour))
+
+	events, err := suite.Repo.GetPending(time.Now(), 10)
+	a.Nil(err)
+	a.Equal(1, len(events))
+	a.Equal(due.ID, events[0].ID)
+	a.JSONEq(`{"bookId":10000}`, string(events[0].Payload))
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) TestMarkDispatched_ExpectNotPending() {
+	a := assert.New(suite.T())
+	event := suite.createEvent(time.Now().Add(-time.Minute))
+
+	a.Nil(suite.Repo.MarkDispatched(event.ID, time.Now()))
+
+	events, err := suite.Repo.GetPending(time.Now(), 10)
+	a.Nil(err)
+	a.Empty(events)
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) TestMarkDeadLettered_ThenRequeue_ExpectPendingAgain() {
+	a := assert.New(suite.T())
+	event := suite.createEvent(time.Now().Add(-time.Minute))
+
+	a.Nil(suite.Repo.MarkDeadLettered(event.ID, time.Now(), "consumer down"))
+	deadLettered, err := suite.Repo.GetDeadLettered()
+	a.Nil(err)
+	a.Equal(1, len(deadLettered))
+	a.Equal("consumer down", deadLettered[0].LastError)
+
+	a.Nil(suite.Repo.Requeue(event.ID))
+	events, _ := suite.Repo.GetPending(time.Now().Add(time.Second), 10)
+	a.Equal(1, len(events))
+	a.Zero(events[0].Attempts)
+}
+
+func (suite *OutboxRepoIntegrationTestSuite) TestMarkFailed_WithInvalidID_ExpectNotFound() {
+	a := assert.New(suite.T())
+
+	err := suite.Repo.MarkFailed(123456, 1, time.Now(), "error")
+	a.Error(err)
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ReminderRepoIntegrationTestSuite struct {
+	suite.Suite
+	Repo *repository.GormReminderRepository
+	Db   *gorm.DB
+}
+
+func TestReminderRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &ReminderRepoIntegrationTestSuite{})
+}
+
+func (suite *ReminderRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+}
+
+func (suite *ReminderRepoIntegrationTestSuite) SetupTest() {
+	tx := suite.Db.Begin()
+	suite.Repo = repository.NewGormReminderRepository(tx)
+}
+
+func (suite *ReminderRepoIntegrationTestSuite) TearDownTest() {
+	suite.Repo.Db.Rollback()
+	suite.Repo.Db = nil
+}
+
+func (suite *ReminderRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *ReminderRepoIntegrationTestSuite) TestCreate_WithSameReminderTwice_ExpectUniqueConstraint() {
+	a := assert.New(suite.T())
+	first := domain.SentReminder{RentID: 10000, Kind: domain.DueReminder, DaysBefore: 7, SentAt: time.Now()}
+	second := domain.SentReminder{RentID: 10000, Kind: domain.DueReminder, DaysBefore: 7, SentAt: time.Now()}
+
+	a.Nil(suite.Repo.Create(&first))
+	err := suite.Repo.Create(&second)
+	a.Error(err)
+	a.Equal(domain.UniqueConstraint, err.(*domain.RepoError).Type)
+}
+
+func (suite *ReminderRepoIntegrationTestSuite) TestDelete_WithClaimedReminder_ExpectReclaimable() {
+	a := assert.New(suite.T())
+	reminder := domain.SentReminder{RentID: 10000, Kind: domain.OverdueNotice, SentAt: time.Now()}
+	a.Nil(suite.Repo.Create(&reminder))
+
+	a.Nil(suite.Repo.Delete(reminder.ID))
+
+	reminders, err := suite.Repo.GetByRent(10000)
+	a.Nil(err)
+	a.Empty(reminders)
+	a.Nil(suite.Repo.Create(&domain.SentReminder{RentID: 10000, Kind: domain.OverdueNotice, SentAt: time.Now()}))
+}
@@ -0,0 +1,202 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/notify"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ReminderServiceUnitTestSuite struct {
+	suite.Suite
+	service      *service.ReminderService
+	rentRepo     *repo_mocks.MockedRentDetailsRepository
+	reminderRepo *repo_mocks.MockedReminderRepository
+	dir          string
+}
+
+func TestReminderServiceUnitTestSuite(t *testing.T) {
+	suite.Run(t, &ReminderServiceUnitTestSuite{})
+}
+
+func (suite *ReminderServiceUnitTestSuite) SetupTest() {
+	suite.dir, _ = ioutil.TempDir("", "reminders")
+	suite.rentRepo = &repo_mocks.MockedRentDetailsRepository{}
+	suite.reminderRepo = &repo_mocks.MockedReminderRepository{}
+	suite.service = &service.ReminderService{
+		RentRepo:     suite.rentRepo,
+		ReminderRepo: suite.reminderRepo,
+		Notifier:     notify.NewFileNotifier(filepath.Join(suite.dir, "notifications.jsonl")),
+		DaysAhead:    []int{7, 1}}
+}
+
+func (suite *ReminderServiceUnitTestSuite) TearDownTest() {
+	_ = os.RemoveAll(suite.dir)
+}
+
+func (suite *ReminderServiceUnitTestSuite) sentNotifications() []domain.Notification {
+	notifications := make([]domain.Notification, 0)
+	f, err := os.Open(filepath.Join(suite.dir, "notifications.jsonl"))
+	if err != nil {
+		return notifications
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var notification domain.Notification
+		_ = json.Unmarshal(scanner.Bytes(), &notification)
+		notifications = append(notifications, notification)
+	}
+	return notifications
+}
+
+func reminderRent(id uint, deadline time.Time, status domain.RentDetailsStatus) domain.RentDetails {
+	rent := domain.RentDetails{
+		Status:         status,
+		ReturnDeadline: deadline,
+		User:           domain.User{Firstname: "john", Lastname: "doe", Email: "johndoe@gmail.com"},
+		Book:           domain.Book{Title: "title1"}}
+	rent.Model = gorm.Model{ID: id}
+	return rent
+}
+
+func (suite *ReminderServiceUnitTestSuite) TestSendReminders_WithRentInBothWindows_ExpectOnlyClosestReminder() {
+	a := assert.New(suite.T())
+	rent := reminderRent(1, time.Now().Add(12*time.Hour), domain.RENTED)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.RENTED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{rent}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.EXPIRED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+
+	suite.reminderRepo.
+		On("Create", mock.MatchedBy(func(reminder *domain.SentReminder) bool {
+			return reminder.RentID == 1 && reminder.Kind == domain.DueReminder && reminder.DaysBefore == 1
+		})).
+		Return(domain.NilRepoErrPtr).
+		Once()
+
+	sent, err := suite.service.SendReminders()
+	a.Nil(err)
+	a.Equal(1, sent)
+	suite.reminderRepo.AssertExpectations(suite.T())
+
+	notifications := suite.sentNotifications()
+	a.Equal(1, len(notifications))
+	a.Equal("johndoe@gmail.com", notifications[0].To)
+	a.Equal(`Reminder: "title1" is due in 1 day(s)`, notifications[0].Subject)
+}
+
+func (suite *ReminderServiceUnitTestSuite) TestSendReminders_WithAlreadySentReminder_ExpectNothingSent() {
+	a := assert.New(suite.T())
+	rent := reminderRent(2, time.Now().Add(-time.Hour), domain.EXPIRED)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.RENTED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.EXPIRED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{rent}, domain.NilRepoErrPtr)
+
+	suite.reminderRepo.
+		On("Create", mock.Anything).
+		Return(&domain.RepoError{Type: domain.UniqueConstraint})
+
+	sent, err := suite.service.SendReminders()
+	a.Nil(err)
+	a.Zero(sent)
+	a.Empty(suite.sentNotifications())
+}
+
+func (suite *ReminderServiceUnitTestSuite) TestSendReminders_WithFailingNotifier_ExpectClaimReleased() {
+	a := assert.New(suite.T())
+	rent := reminderRent(3, time.Now().Add(-time.Hour), domain.EXPIRED)
+	suite.service.Notifier = notify.NewFileNotifier(filepath.Join(suite.dir, "missing", "notifications.jsonl"))
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.RENTED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.EXPIRED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{rent}, domain.NilRepoErrPtr)
+
+	suite.reminderRepo.
+		On("Create", mock.Anything).
+		Run(func(args mock.Arguments) { args.Get(0).(*domain.SentReminder).ID = 42 }).
+		Return(domain.NilRepoErrPtr)
+
+	suite.reminderRepo.
+		On("Delete", uint(42)).
+		Return(domain.NilRepoErrPtr)
+
+	sent, err := suite.service.SendReminders()
+	a.Nil(err)
+	a.Zero(sent)
+	suite.reminderRepo.AssertCalled(suite.T(), "Delete", uint(42))
+}
+
+func (suite *ReminderServiceUnitTestSuite) TestSendReminders_WithExpiredRent_ExpectOverdueNotice() {
+	a := assert.New(suite.T())
+	rent := reminderRent(4, time.Now().Add(-48*time.Hour), domain.EXPIRED)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.RENTED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.EXPIRED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{rent}, domain.NilRepoErrPtr)
+
+	suite.reminderRepo.
+		On("Create", mock.MatchedBy(func(reminder *domain.SentReminder) bool {
+			return reminder.Kind == domain.OverdueNotice
+		})).
+		Return(domain.NilRepoErrPtr)
+
+	sent, err := suite.service.SendReminders()
+	a.Nil(err)
+	a.Equal(1, sent)
+	a.Equal(`Overdue: "title1"`, suite.sentNotifications()[0].Subject)
+}
+
+func (suite *ReminderServiceUnitTestSuite) TestSendReminders_WithTemplatesMissingKind_ExpectDefaultTemplate() {
+	a := assert.New(suite.T())
+	rent := reminderRent(5, time.Now().Add(-48*time.Hour), domain.EXPIRED)
+	suite.service.Templates = map[domain.ReminderKind]service.ReminderTemplate{
+		domain.DueReminder: service.DefaultReminderTemplates[domain.DueReminder]}
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.RENTED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByStatusAndDeadline", domain.EXPIRED, mock.Anything, mock.Anything).
+		Return([]domain.RentDetails{rent}, domain.NilRepoErrPtr)
+
+	suite.reminderRepo.
+		On("Create", mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	sent, err := suite.service.SendReminders()
+	a.Nil(err)
+	a.Equal(1, sent)
+	a.Equal(`Overdue: "title1"`, suite.sentNotifications()[0].Subject)
+}
@@ -1,10 +1,12 @@
 package test
 
 import (
+	"context"
 	"github.com/idj1997/book-rent-core/config"
 	"github.com/idj1997/book-rent-core/domain"
 	"github.com/idj1997/book-rent-core/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -23,8 +25,11 @@ func TestRentDetailsIntegrationTestSuite(t *testing.T) {
 }
 
 func (suite *RentDetailsIntegrationTestSuite) SetupSuite() {
-	config.InitConfig("test", "../config.yml")
-	suite.Db = config.OpenPostgresDB()
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
 }
 
 func (suite *RentDetailsIntegrationTestSuite) SetupTest() {
@@ -38,7 +43,7 @@ func (suite *RentDetailsIntegrationTestSuite) TearDownTest() {
 }
 
 func (suite *RentDetailsIntegrationTestSuite) TearDownSuite() {
-	config.ClosePostgresDB(suite.Db)
+	suite.Nil(config.ClosePostgresDB(suite.Db))
 }
 
 func (suite *RentDetailsIntegrationTestSuite) TestGetByID_WithValidID_ExpectOk() {
@@ -122,6 +127,62 @@ func (suite *RentDetailsIntegrationTestSuite) TestUpdate_WithValidUpdates_Expect
 	a.Equal(newStatus, rent.Status)
 }
 
+func (suite *RentDetailsIntegrationTestSuite) TestUpdate_WithReturnedToRented_ExpectInvalidTransition() {
+	a := assert.New(suite.T())
+	rentId := 10000
+	rent, _ := suite.Repo.GetByID(rentId)
+
+	repoErr := suite.Repo.Update(rent, map[string]interface{}{"status": domain.RETURNED})
+	a.Nil(repoErr)
+
+	repoErr = suite.Repo.Update(rent, map[string]interface{}{"status": domain.RENTED})
+	a.Error(repoErr)
+	a.Equal(domain.InvalidTransition, repoErr.(*domain.RepoError).Type)
+	a.Equal(domain.RETURNED, rent.Status)
+}
+
+func (suite *RentDetailsIntegrationTestSuite) TestUpdate_WithStaleRent_ExpectInvalidTransitionAndStoredStatusKept() {
+	a := assert.New(suite.T())
+	rentId := 10000
+	rent, _ := suite.Repo.GetByID(rentId)
+	stale := *rent
+
+	repoErr := suite.Repo.Update(rent, map[string]interface{}{"status": domain.RETURNED})
+	a.Nil(repoErr)
+
+	repoErr = suite.Repo.Update(&stale, map[string]interface{}{"status": domain.EXPIRED})
+	a.Error(repoErr)
+	a.Equal(domain.InvalidTransition, repoErr.(*domain.RepoError).Type)
+	a.Equal(domain.RENTED, stale.Status)
+
+	repoErr = suite.Repo.UpdateAssociations(&stale, map[string]interface{}{"status": domain.RETURNED})
+	a.Error(repoErr)
+	a.Equal(domain.InvalidTransition, repoErr.(*domain.RepoError).Type)
+
+	stored, _ := suite.Repo.GetByID(rentId)
+	a.Equal(domain.RETURNED, stored.Status)
+}
+
+func (suite *RentDetailsIntegrationTestSuite) TestUpdate_WithRentedToDamaged_ExpectFeeAndNoteStored() {
+	a := assert.New(suite.T())
+	rentId := 10000
+	rent, _ := suite.Repo.GetByID(rentId)
+
+	updates, err := rent.Transition(domain.DAMAGED, time.Now())
+	a.Nil(err)
+	updates["fee"] = rent.Book.Price
+	updates["note"] = "water damage"
+
+	repoErr := suite.Repo.Update(rent, updates)
+	a.Nil(repoErr)
+
+	stored, _ := suite.Repo.GetByID(rentId)
+	a.Equal(domain.DAMAGED, stored.Status)
+	a.Equal(1999, stored.Fee)
+	a.Equal("water damage", stored.Note)
+	a.False(stored.DamagedAt.IsZero())
+}
+
 func (suite *RentDetailsIntegrationTestSuite) TestUpdate_WithValidAssocUpdates_ExpectOk() {
 	a := assert.New(suite.T())
 	rentId := 10000
@@ -199,11 +260,44 @@ func (suite *RentDetailsIntegrationTestSuite) TestRentedAndExpiredProducer_Expec
 	a := assert.New(suite.T())
 	rents := make([]domain.RentDetails, 0)
 	stream := make(chan domain.RentDetails)
+	iterated := make(chan error, 1)
 
-	go suite.Repo.RentDetailsIterator(stream)
+	go func() {
+		iterated <- suite.Repo.RentDetailsIterator(context.Background(), stream)
+	}()
 	for rent := range stream {
 		rents = append(rents, rent)
 	}
 
 	a.NotEmpty(rents)
+	a.Equal(domain.NilRepoErrPtr, <-iterated)
+}
+
+func (suite *RentDetailsIntegrationTestSuite) TestRentDetailsIterator_WithCanceledContext_ExpectStoppedWithError() {
+	a := assert.New(suite.T())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream := make(chan domain.RentDetails)
+
+	err := suite.Repo.RentDetailsIterator(ctx, stream)
+
+	a.NotEqual(domain.NilRepoErrPtr, err)
+	_, open := <-stream
+	a.False(open)
+}
+
+func (suite *RentDetailsIntegrationTestSuite) TestGetByStatusAndDeadline_WithinWindow_ExpectWithUser() {
+	a := assert.New(suite.T())
+	now := time.Now()
+	due := domain.RentDetails{UserID: 10001, BookID: 10001, Status: domain.RENTED, ReturnDeadline: now.Add(24 * time.Hour)}
+	later := domain.RentDetails{UserID: 10001, BookID: 10001, Status: domain.RENTED, ReturnDeadline: now.Add(10 * 24 * time.Hour)}
+	a.Nil(suite.Repo.Create(&due))
+	a.Nil(suite.Repo.Create(&later))
+
+	rents, err := suite.Repo.GetByStatusAndDeadline(domain.RENTED, now, now.Add(7*24*time.Hour))
+	a.Nil(err)
+	a.Equal(1, len(rents))
+	a.Equal(due.ID, rents[0].ID)
+	a.Equal("markparker@gmail.com", rents[0].User.Email)
+	a.Equal("title2", rents[0].Book.Title)
 }
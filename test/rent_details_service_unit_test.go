@@ -1,10 +1,13 @@
 package test
 
 import (
+	"context"
+	"errors"
 	"github.com/idj1997/book-rent-core/domain"
 	"github.com/idj1997/book-rent-core/service"
 	"github.com/idj1997/book-rent-core/test/repo_mocks"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -17,6 +20,10 @@ type RentDetailsUnitTestSuite struct {
 	RentService domain.RentDetailsService
 	RentRepo    *repo_mocks.MockedRentDetailsRepository
 	BookRepo    *repo_mocks.MockedBookRepository
+	AuditRepo   *repo_mocks.MockedAuditRepository
+	OutboxRepo  *repo_mocks.MockedOutboxRepository
+	Movements   *repo_mocks.MockedStockMovementRepository
+	Branches    *repo_mocks.MockedBranchRepository
 }
 
 func TestRentDetailsUnitTestSuite(t *testing.T) {
@@ -26,9 +33,29 @@ func TestRentDetailsUnitTestSuite(t *testing.T) {
 func (suite *RentDetailsUnitTestSuite) SetupTest() {
 	suite.RentRepo = &repo_mocks.MockedRentDetailsRepository{}
 	suite.BookRepo = &repo_mocks.MockedBookRepository{}
+	suite.AuditRepo = &repo_mocks.MockedAuditRepository{}
+	suite.AuditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.OutboxRepo = &repo_mocks.MockedOutboxRepository{}
+	suite.Movements = &repo_mocks.MockedStockMovementRepository{}
+	suite.Movements.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.Branches = repo_mocks.NewMockedBranchRepository()
 	suite.RentService = &service.RentDetailsService{
 		RentRepo: suite.RentRepo,
-		BookRepo: suite.BookRepo}
+		BookRepo: suite.BookRepo,
+		Tx: &repo_mocks.MockedTransactor{Repos: domain.Repositories{
+			Rents:     suite.RentRepo,
+			Books:     suite.BookRepo,
+			Audit:     suite.AuditRepo,
+			Outbox:    suite.OutboxRepo,
+			Movements: suite.Movements,
+			Branches:  suite.Branches}}}
+}
+
+// stockAtBranch sets stock of every book at every branch
+func (suite *RentDetailsUnitTestSuite) stockAtBranch(stock int) {
+	suite.Branches.
+		On("GetStock", mock.Anything, mock.Anything).
+		Return(&domain.BranchStock{Stock: stock}, domain.NilRepoErrPtr)
 }
 
 func (suite *RentDetailsUnitTestSuite) TestGetByID_WithInvalidRentID_ExpectNotFound() {
@@ -81,6 +108,7 @@ func (suite *RentDetailsUnitTestSuite) TestRentBook_WithInvalidBookID_ExpectNotF
 
 func (suite *RentDetailsUnitTestSuite) TestRentBook_WithEmptyBookStock_ExpectBookNotAvailable() {
 	a := assert.New(suite.T())
+	suite.stockAtBranch(0)
 
 	book := domain.Book{
 		Title:   "test",
@@ -104,6 +132,7 @@ func (suite *RentDetailsUnitTestSuite) TestRentBook_WithEmptyBookStock_ExpectBoo
 
 func (suite *RentDetailsUnitTestSuite) TestRentBook_WithInvalidUserID_ExpectNotFound() {
 	a := assert.New(suite.T())
+	suite.stockAtBranch(10)
 
 	book := domain.Book{
 		Title:   "test",
@@ -130,12 +159,14 @@ func (suite *RentDetailsUnitTestSuite) TestRentBook_WithInvalidUserID_ExpectNotF
 
 func (suite *RentDetailsUnitTestSuite) TestRentBook_WithValidObj_ExpectCreated() {
 	a := assert.New(suite.T())
+	suite.stockAtBranch(10)
 
 	book := domain.Book{
 		Title:   "test",
 		Content: "test",
 		Stock:   10, // available
 	}
+	book.ID = 10000
 
 	rent := domain.RentDetails{
 		UserID: 10000, // valid id
@@ -149,6 +180,10 @@ func (suite *RentDetailsUnitTestSuite) TestRentBook_WithValidObj_ExpectCreated()
 		On("GetByID", rent.BookID).
 		Return(&book, domain.NilRepoErrPtr)
 
+	suite.BookRepo.
+		On("GetByIDForUpdate", rent.BookID).
+		Return(&book, domain.NilRepoErrPtr)
+
 	suite.RentRepo.
 		On("Create", &rent).
 		Return(domain.NilRepoErrPtr)
@@ -157,9 +192,56 @@ func (suite *RentDetailsUnitTestSuite) TestRentBook_WithValidObj_ExpectCreated()
 		On("Update", &book, updates).
 		Return(domain.NilRepoErrPtr)
 
+	suite.OutboxRepo.
+		On("Create", mock.MatchedBy(func(event *domain.OutboxEvent) bool {
+			return event.Type == domain.StockChanged
+		})).
+		Return(domain.NilRepoErrPtr).
+		Once()
+
+	suite.OutboxRepo.
+		On("Create", mock.MatchedBy(func(event *domain.OutboxEvent) bool {
+			return event.Type == domain.BookRented
+		})).
+		Return(domain.NilRepoErrPtr).
+		Once()
+
 	err := suite.RentService.RentBook(&rent)
 	a.Nil(err)
 	a.True(rent.ReturnDeadline.After(rent.CreatedAt))
+	suite.OutboxRepo.AssertExpectations(suite.T())
+}
+
+func (suite *RentDetailsUnitTestSuite) TestRentBook_WithFailedOutboxWrite_ExpectError() {
+	a := assert.New(suite.T())
+	suite.stockAtBranch(10)
+	book := domain.Book{Title: "test", Content: "test", Stock: 10}
+	book.ID = 10000
+	rent := domain.RentDetails{UserID: 10000, BookID: 10000}
+
+	suite.BookRepo.
+		On("GetByID", rent.BookID).
+		Return(&book, domain.NilRepoErrPtr)
+
+	suite.BookRepo.
+		On("GetByIDForUpdate", rent.BookID).
+		Return(&book, domain.NilRepoErrPtr)
+
+	suite.RentRepo.
+		On("Create", &rent).
+		Return(domain.NilRepoErrPtr)
+
+	suite.BookRepo.
+		On("Update", &book, mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	suite.OutboxRepo.
+		On("Create", mock.Anything).
+		Return(&domain.RepoError{Type: domain.Unknown})
+
+	err := suite.RentService.RentBook(&rent)
+	a.Error(err)
+	a.Equal(service.Unknown, err.(*service.ServiceError).Type)
 }
 
 func (suite *RentDetailsUnitTestSuite) TestReturnBook_WithInvalidID_ExpectNotFound() {
@@ -171,7 +253,7 @@ func (suite *RentDetailsUnitTestSuite) TestReturnBook_WithInvalidID_ExpectNotFou
 		On("GetByID", id).
 		Return(domain.NilRentPtr, &err)
 
-	serviceErr := suite.RentService.ReturnBook(id)
+	serviceErr := suite.RentService.ReturnBook(id, 0)
 	a.NotNil(serviceErr)
 	a.Equal(service.NotFound, serviceErr.(*service.ServiceError).Type)
 }
@@ -188,19 +270,21 @@ func (suite *RentDetailsUnitTestSuite) TestReturnBook_WithReturnedBook_ExpectInv
 		On("GetByID", id).
 		Return(&rent, domain.NilRepoErrPtr)
 
-	err := suite.RentService.ReturnBook(id)
+	err := suite.RentService.ReturnBook(id, 0)
 	a.NotNil(err)
 	a.Equal(service.BookAlreadyReturned, err.(*service.ServiceError).Type)
 }
 
 func (suite *RentDetailsUnitTestSuite) TestReturnBook_WithValidID_ExpectOK() {
 	a := assert.New(suite.T())
+	suite.stockAtBranch(10)
 	id := 10000
 
 	book := domain.Book{
 		Title:   "test",
 		Content: "test",
 		Stock:   10}
+	book.ID = 100
 
 	rent := domain.RentDetails{
 		UserID: 100,
@@ -212,10 +296,15 @@ func (suite *RentDetailsUnitTestSuite) TestReturnBook_WithValidID_ExpectOK() {
 		On("GetByID", id).
 		Return(&rent, domain.NilRepoErrPtr)
 
-	rentUpdates := make(map[string]interface{})
-	rentUpdates["status"] = domain.RETURNED
+	suite.BookRepo.
+		On("GetByIDForUpdate", 100).
+		Return(&book, domain.NilRepoErrPtr)
+
 	suite.RentRepo.
-		On("Update", &rent, rentUpdates).
+		On("Update", &rent, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			_, returnedAtSet := updates["returned_at"]
+			return updates["status"] == domain.RETURNED && returnedAtSet
+		})).
 		Return(domain.NilRepoErrPtr)
 
 	bookUpdates := make(map[string]interface{})
@@ -224,7 +313,11 @@ func (suite *RentDetailsUnitTestSuite) TestReturnBook_WithValidID_ExpectOK() {
 		On("Update", &book, bookUpdates).
 		Return(domain.NilRepoErrPtr)
 
-	err := suite.RentService.ReturnBook(id)
+	suite.OutboxRepo.
+		On("Create", mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.RentService.ReturnBook(id, 0)
 	a.Nil(err)
 }
 
@@ -319,6 +412,282 @@ func (suite *RentDetailsUnitTestSuite) TestUpdateToExpired_ExpectMany() {
 		On("Update", mock.Anything, mock.Anything).
 		Return(domain.NilRepoErrPtr)
 
+	suite.OutboxRepo.
+		On("Create", mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.RentService.UpdateToExpired()
+	a.Nil(err)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestUpdateToExpired_WithRentChangedSinceStreamed_ExpectSkipped() {
+	a := assert.New(suite.T())
+
+	suite.RentRepo.
+		On("Update", mock.Anything, mock.Anything).
+		Return(&domain.RepoError{Type: domain.InvalidTransition})
+
+	err := suite.RentService.UpdateToExpired()
+	a.Nil(err)
+	suite.OutboxRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestUpdateToExpired_WithFailedIterator_ExpectError() {
+	a := assert.New(suite.T())
+	suite.RentRepo.IteratorError = &domain.RepoError{Type: domain.Unknown}
+	suite.RentRepo.
+		On("Update", mock.Anything, mock.Anything).
+		Return(domain.NilRepoErrPtr)
+	suite.OutboxRepo.
+		On("Create", mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
 	err := suite.RentService.UpdateToExpired()
+	suite.Require().NotNil(err)
+	a.Equal(service.Unknown, err.(*service.ServiceError).Type)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestStreamActiveRents_WithFailingFn_ExpectStoppedWithItsError() {
+	a := assert.New(suite.T())
+	sendErr := errors.New("send failed")
+	calls := 0
+
+	err := suite.RentService.StreamActiveRents(func(rent domain.RentDetails) error {
+		calls++
+		return sendErr
+	})
+
+	a.Equal(sendErr, err)
+	a.Equal(1, calls)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestStreamActiveRents_WithCanceledContext_ExpectContextError() {
+	a := assert.New(suite.T())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := suite.RentService.(*service.RentDetailsService).WithContext(ctx).StreamActiveRents(func(rent domain.RentDetails) error {
+		return nil
+	})
+
+	a.Equal(context.Canceled, err)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestStreamActiveRents_WithFailedIterator_ExpectUnknown() {
+	a := assert.New(suite.T())
+	suite.RentRepo.IteratorError = &domain.RepoError{Type: domain.Unknown}
+	var streamed []domain.RentDetails
+
+	err := suite.RentService.StreamActiveRents(func(rent domain.RentDetails) error {
+		streamed = append(streamed, rent)
+		return nil
+	})
+
+	suite.Require().NotNil(err)
+	a.Equal(service.Unknown, err.(*service.ServiceError).Type)
+	a.Len(streamed, 2)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestReturnBook_WithLostBook_ExpectInvalidStatusTransition() {
+	a := assert.New(suite.T())
+	id := 10000
+	rent := domain.RentDetails{
+		UserID: 100,
+		BookID: 100,
+		Status: domain.LOST}
+
+	suite.RentRepo.
+		On("GetByID", id).
+		Return(&rent, domain.NilRepoErrPtr)
+
+	err := suite.RentService.ReturnBook(id, 0)
+	a.NotNil(err)
+	a.Equal(service.InvalidStatusTransition, err.(*service.ServiceError).Type)
+	suite.RentRepo.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestTransition_WithDeadlineNotPassed_ExpectExpireRejected() {
+	a := assert.New(suite.T())
+	now := time.Now()
+	rent := domain.RentDetails{
+		Status:         domain.RENTED,
+		ReturnDeadline: now.Add(24 * time.Hour)}
+
+	_, err := rent.Transition(domain.EXPIRED, now)
+	a.Error(err)
+	a.IsType(&domain.TransitionError{}, err)
+
+	rent.ReturnDeadline = now.Add(-time.Hour)
+	updates, err := rent.Transition(domain.EXPIRED, now)
+	a.Nil(err)
+	a.Equal(domain.EXPIRED, updates["status"])
+	a.Equal(now, updates["expired_at"])
+}
+
+func (suite *RentDetailsUnitTestSuite) TestTransition_FromFinalStatus_ExpectRejected() {
+	a := assert.New(suite.T())
+
+	a.True(domain.EXPIRED.CanTransitionTo(domain.RETURNED))
+	a.False(domain.RETURNED.CanTransitionTo(domain.RENTED))
+	a.False(domain.LOST.CanTransitionTo(domain.RETURNED))
+	a.False(domain.RENTED.CanTransitionTo(domain.RENTED))
+}
+
+func (suite *RentDetailsUnitTestSuite) TestDeclareLost_WithFee_ExpectChargedAndNotRestocked() {
+	a := assert.New(suite.T())
+	id := 10000
+	rent := domain.RentDetails{
+		UserID: 100,
+		BookID: 100,
+		Status: domain.EXPIRED,
+		Book:   domain.Book{Stock: 3, Price: 1999}}
+
+	suite.RentRepo.
+		On("GetByID", id).
+		Return(&rent, domain.NilRepoErrPtr)
+	suite.RentRepo.
+		On("Update", &rent, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			_, lostAtSet := updates["lost_at"]
+			return updates["status"] == domain.LOST && lostAtSet &&
+				updates["fee"] == 1999 && updates["note"] == "left on train"
+		})).
+		Return(domain.NilRepoErrPtr)
+	suite.OutboxRepo.
+		On("Create", mock.MatchedBy(func(event *domain.OutboxEvent) bool {
+			return event.Type == domain.RentLost
+		})).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.RentService.DeclareLost(id, domain.RentOutcome{ChargeFee: true, Note: "left on train"})
+	a.Nil(err)
+	suite.BookRepo.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestDeclareDamaged_WithRestock_ExpectRestockedWithoutFee() {
+	a := assert.New(suite.T())
+	suite.stockAtBranch(3)
+	id := 10000
+	rent := domain.RentDetails{
+		UserID: 100,
+		BookID: 100,
+		Status: domain.RENTED,
+		Book:   domain.Book{Stock: 3, Price: 1999}}
+	rent.Book.ID = 100
+
+	suite.RentRepo.
+		On("GetByID", id).
+		Return(&rent, domain.NilRepoErrPtr)
+	suite.BookRepo.
+		On("GetByIDForUpdate", 100).
+		Return(&rent.Book, domain.NilRepoErrPtr)
+	suite.RentRepo.
+		On("Update", &rent, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			_, feeSet := updates["fee"]
+			return updates["status"] == domain.DAMAGED && !feeSet
+		})).
+		Return(domain.NilRepoErrPtr)
+	suite.BookRepo.
+		On("Update", &rent.Book, map[string]interface{}{"stock": 4}).
+		Return(domain.NilRepoErrPtr)
+	suite.OutboxRepo.
+		On("Create", mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.RentService.DeclareDamaged(id, domain.RentOutcome{Restock: true})
+	a.Nil(err)
+	suite.BookRepo.AssertExpectations(suite.T())
+}
+
+func (suite *RentDetailsUnitTestSuite) TestDeclareDamaged_WithReturnedBook_ExpectInvalidStatusTransition() {
+	a := assert.New(suite.T())
+	id := 10000
+	rent := domain.RentDetails{
+		UserID: 100,
+		BookID: 100,
+		Status: domain.RETURNED}
+
+	suite.RentRepo.
+		On("GetByID", id).
+		Return(&rent, domain.NilRepoErrPtr)
+
+	err := suite.RentService.DeclareDamaged(id, domain.RentOutcome{ChargeFee: true})
+	a.NotNil(err)
+	a.Equal(service.InvalidStatusTransition, err.(*service.ServiceError).Type)
+	suite.RentRepo.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestUpdateToLost_WithLongOverdueRent_ExpectLostWithFee() {
+	a := assert.New(suite.T())
+	rents := []domain.RentDetails{{
+		UserID:         100,
+		BookID:         100,
+		Status:         domain.EXPIRED,
+		ReturnDeadline: time.Now().Add(-60 * 24 * time.Hour),
+		Book:           domain.Book{Stock: 3, Price: 500}}}
+
+	suite.RentRepo.
+		On("GetByStatusAndDeadline", domain.EXPIRED, time.Time{}, mock.Anything).
+		Return(rents, domain.NilRepoErrPtr)
+	suite.RentRepo.
+		On("Update", mock.Anything, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			return updates["status"] == domain.LOST && updates["fee"] == 500
+		})).
+		Return(domain.NilRepoErrPtr)
+	suite.OutboxRepo.
+		On("Create", mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.RentService.UpdateToLost(30 * 24 * time.Hour)
+	a.Nil(err)
+	suite.RentRepo.AssertNumberOfCalls(suite.T(), "Update", 1)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestUpdateToLost_WithInvalidPeriod_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	err := suite.RentService.UpdateToLost(0)
+	a.NotNil(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+}
+
+func (suite *RentDetailsUnitTestSuite) TestReturnBook_AtOtherBranch_ExpectRestockedThere() {
+	a := assert.New(suite.T())
+	id := 10000
+	east := &domain.Branch{Name: "east"}
+	east.ID = 2
+	rent := domain.RentDetails{
+		UserID:   100,
+		BookID:   100,
+		BranchID: 1,
+		Status:   domain.RENTED,
+		Book:     domain.Book{Stock: 4}}
+	rent.Book.ID = 100
+
+	suite.RentRepo.
+		On("GetByID", id).
+		Return(&rent, domain.NilRepoErrPtr)
+	suite.Branches.
+		On("GetByID", 2).
+		Return(east, domain.NilRepoErrPtr)
+	suite.Branches.
+		On("GetStock", 100, 2).
+		Return(&domain.BranchStock{BookID: 100, BranchID: 2, Stock: 0}, domain.NilRepoErrPtr)
+	suite.BookRepo.
+		On("GetByIDForUpdate", 100).
+		Return(&rent.Book, domain.NilRepoErrPtr)
+	suite.RentRepo.
+		On("Update", &rent, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			return updates["status"] == domain.RETURNED && updates["return_branch_id"] == uint(2)
+		})).
+		Return(domain.NilRepoErrPtr)
+	suite.BookRepo.
+		On("Update", &rent.Book, map[string]interface{}{"stock": 5}).
+		Return(domain.NilRepoErrPtr)
+	suite.OutboxRepo.
+		On("Create", mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	err := suite.RentService.ReturnBook(id, 2)
 	a.Nil(err)
+	suite.Branches.AssertCalled(suite.T(), "SaveStock", &domain.BranchStock{BookID: 100, BranchID: 2, Stock: 1})
 }
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const replicaDBName = "books_test_replica"
+
+// ReplicasIntegrationTestSuite routes reads to second database standing in for replica,
+// which never receives writes of primary
+type ReplicasIntegrationTestSuite struct {
+	suite.Suite
+	Db      *gorm.DB
+	Replica *gorm.DB
+	created []uint
+}
+
+func TestReplicasIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &ReplicasIntegrationTestSuite{})
+}
+
+func (suite *ReplicasIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+
+	var exists int64
+	err = suite.Db.Raw("SELECT count(*) FROM pg_database WHERE datname = ?", replicaDBName).Scan(&exists).Error
+	suite.Require().Nil(err)
+	if exists == 0 {
+		suite.Require().Nil(suite.Db.Exec("CREATE DATABASE " + replicaDBName).Error)
+	}
+
+	replicaConfig := cfg.Database.ConnectionConfig
+	replicaConfig.DBName = replicaDBName
+	suite.Replica, err = gorm.Open(postgres.Open(replicaConfig.DSN()), config.GetGormConfig(cfg.Database))
+	suite.Require().Nil(err)
+	suite.Require().Nil(suite.Replica.Migrator().DropTable(&domain.Book{}))
+	suite.Require().Nil(suite.Replica.AutoMigrate(&domain.Book{}))
+	suite.Require().Nil(suite.Replica.Exec(
+		"INSERT INTO books (id, title, content, stock) VALUES (20000, 'replica', 'replica', 1)").Error)
+
+	pool, err := suite.Replica.DB()
+	suite.Require().Nil(err)
+	suite.Require().Nil(suite.Db.Use(&repository.Replicas{Pools: []gorm.ConnPool{pool}}))
+}
+
+func (suite *ReplicasIntegrationTestSuite) TearDownTest() {
+	for _, id := range suite.created {
+		suite.Db.Unscoped().Delete(&domain.Book{}, id)
+	}
+	suite.created = nil
+}
+
+func (suite *ReplicasIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *ReplicasIntegrationTestSuite) TestGetByID_WithReplicaOnlyBook_ExpectReadFromReplica() {
+	a := assert.New(suite.T())
+
+	book, err := repository.NewGormBookRepository(suite.Db).GetByID(20000)
+	a.Nil(err)
+	a.Equal("replica", book.Title)
+
+	_, err = repository.NewGormBookRepository(repository.ReadPrimary(suite.Db)).GetByID(20000)
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+}
+
+func (suite *ReplicasIntegrationTestSuite) TestCreate_ThenGetByID_ExpectOwnWriteOnlyOnPrimary() {
+	a := assert.New(suite.T())
+	repo := repository.NewGormBookRepository(suite.Db)
+
+	id, err := repo.Create(&domain.Book{Title: "primary", Content: "primary", Stock: 1})
+	a.Nil(err)
+	suite.created = append(suite.created, id)
+
+	_, err = repo.GetByID(int(id))
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+
+	book, err := repository.NewGormBookRepository(repository.ReadPrimary(suite.Db)).GetByID(int(id))
+	a.Nil(err)
+	a.Equal("primary", book.Title)
+}
+
+func (suite *ReplicasIntegrationTestSuite) TestTransaction_WithRead_ExpectReadFromPrimary() {
+	a := assert.New(suite.T())
+
+	err := repository.NewGormTransactor(suite.Db).Transaction(func(repos domain.Repositories) error {
+		book, getErr := repos.Books.GetByID(10000)
+		a.Equal(domain.NilRepoErrPtr, getErr)
+		a.Equal("title1", book.Title)
+
+		_, getErr = repos.Books.GetByID(20000)
+		a.Equal(domain.NotFound, getErr.(*domain.RepoError).Type)
+		return nil
+	})
+	a.Nil(err)
+}
+
+func (suite *ReplicasIntegrationTestSuite) TestUpdate_AfterRead_ExpectWrittenToPrimary() {
+	a := assert.New(suite.T())
+	repo := repository.NewGormBookRepository(suite.Db)
+
+	book, err := repository.NewGormBookRepository(repository.ReadPrimary(suite.Db)).GetByID(10001)
+	a.Nil(err)
+	err = repo.Update(book, map[string]interface{}{"content": "updated"})
+	a.Nil(err)
+
+	var content string
+	a.Nil(repository.ReadPrimary(suite.Db).Model(&domain.Book{}).Select("content").Where("id = ?", 10001).Scan(&content).Error)
+	a.Equal("updated", content)
+	a.Nil(repo.Update(book, map[string]interface{}{"content": "content2"}))
+}
@@ -0,0 +1,20 @@
+package repo_mocks
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockedAuditRepository) Create(record *domain.AuditRecord) error {
+	args := m.Called(record)
+	return args.Error(0)
+}
+
+func (m *MockedAuditRepository) Find(filter domain.AuditFilter) ([]domain.AuditRecord, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]domain.AuditRecord), args.Error(1)
+}
@@ -1,6 +1,8 @@
 package repo_mocks
 
 import (
+	"time"
+
 	"github.com/idj1997/book-rent-core/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -14,11 +16,31 @@ func (m *MockedBookRepository) GetByID(id int) (*domain.Book, error) {
 	return args.Get(0).(*domain.Book), args.Error(1)
 }
 
+func (m *MockedBookRepository) GetByIDForUpdate(id int) (*domain.Book, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Book), args.Error(1)
+}
+
+func (m *MockedBookRepository) GetByIDs(ids []int) ([]domain.Book, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]domain.Book), args.Error(1)
+}
+
 func (m *MockedBookRepository) GetByTitle(title string) ([]domain.Book, error) {
 	args := m.Called(title)
 	return args.Get(0).([]domain.Book), args.Error(1)
 }
 
+func (m *MockedBookRepository) GetAll() ([]domain.Book, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.Book), args.Error(1)
+}
+
+func (m *MockedBookRepository) GetByNaturalKey(isbn string, title string) (*domain.Book, error) {
+	args := m.Called(isbn, title)
+	return args.Get(0).(*domain.Book), args.Error(1)
+}
+
 func (m *MockedBookRepository) Create(book *domain.Book) (uint, error) {
 	args := m.Called(book)
 	return args.Get(0).(uint), args.Error(1)
@@ -33,3 +55,18 @@ func (m *MockedBookRepository) Delete(id int) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MockedBookRepository) GetDeleted() ([]domain.Book, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.Book), args.Error(1)
+}
+
+func (m *MockedBookRepository) Restore(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockedBookRepository) Purge(deletedBefore time.Time) ([]uint, error) {
+	args := m.Called(deletedBefore)
+	return args.Get(0).([]uint), args.Error(1)
+}
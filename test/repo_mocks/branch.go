@@ -0,0 +1,66 @@
+package repo_mocks
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedBranchRepository struct {
+	mock.Mock
+}
+
+// NewMockedBranchRepository returns mock resolving default branch, by default or by its id,
+// to DefaultBranch and accepting stock writes, other branches and stock reads are left to tests
+func NewMockedBranchRepository() *MockedBranchRepository {
+	m := &MockedBranchRepository{}
+	m.On("GetDefault").Return(DefaultBranch, domain.NilRepoErrPtr)
+	m.On("GetByID", int(DefaultBranch.ID)).Return(DefaultBranch, domain.NilRepoErrPtr)
+	m.On("SaveStock", mock.Anything).Return(domain.NilRepoErrPtr)
+	return m
+}
+
+var DefaultBranch = &domain.Branch{Name: "main", IsDefault: true}
+
+func init() {
+	DefaultBranch.ID = 1
+}
+
+func (m *MockedBranchRepository) GetByID(id int) (*domain.Branch, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Branch), args.Error(1)
+}
+
+func (m *MockedBranchRepository) GetAll() ([]domain.Branch, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.Branch), args.Error(1)
+}
+
+func (m *MockedBranchRepository) GetDefault() (*domain.Branch, error) {
+	args := m.Called()
+	return args.Get(0).(*domain.Branch), args.Error(1)
+}
+
+func (m *MockedBranchRepository) Create(branch *domain.Branch) error {
+	args := m.Called(branch)
+	return args.Error(0)
+}
+
+func (m *MockedBranchRepository) GetStock(bookID int, branchID int) (*domain.BranchStock, error) {
+	args := m.Called(bookID, branchID)
+	return args.Get(0).(*domain.BranchStock), args.Error(1)
+}
+
+func (m *MockedBranchRepository) SaveStock(stock *domain.BranchStock) error {
+	args := m.Called(stock)
+	return args.Error(0)
+}
+
+func (m *MockedBranchRepository) GetAvailability(bookID int) ([]domain.BranchStock, error) {
+	args := m.Called(bookID)
+	return args.Get(0).([]domain.BranchStock), args.Error(1)
+}
+
+func (m *MockedBranchRepository) GetAvailableBooks(branchID int) ([]domain.BranchStock, error) {
+	args := m.Called(branchID)
+	return args.Get(0).([]domain.BranchStock), args.Error(1)
+}
@@ -0,0 +1,47 @@
+package repo_mocks
+
+import (
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockedOutboxRepository) Create(event *domain.OutboxEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockedOutboxRepository) GetPending(now time.Time, limit int) ([]domain.OutboxEvent, error) {
+	args := m.Called(now, limit)
+	return args.Get(0).([]domain.OutboxEvent), args.Error(1)
+}
+
+func (m *MockedOutboxRepository) GetDeadLettered() ([]domain.OutboxEvent, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.OutboxEvent), args.Error(1)
+}
+
+func (m *MockedOutboxRepository) MarkDispatched(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockedOutboxRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(id, attempts, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockedOutboxRepository) MarkDeadLettered(id uint, at time.Time, lastError string) error {
+	args := m.Called(id, at, lastError)
+	return args.Error(0)
+}
+
+func (m *MockedOutboxRepository) Requeue(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
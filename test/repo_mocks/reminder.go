@@ -0,0 +1,25 @@
+package repo_mocks
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedReminderRepository struct {
+	mock.Mock
+}
+
+func (m *MockedReminderRepository) Create(reminder *domain.SentReminder) error {
+	args := m.Called(reminder)
+	return args.Error(0)
+}
+
+func (m *MockedReminderRepository) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockedReminderRepository) GetByRent(rentID uint) ([]domain.SentReminder, error) {
+	args := m.Called(rentID)
+	return args.Get(0).([]domain.SentReminder), args.Error(1)
+}
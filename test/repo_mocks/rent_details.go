@@ -1,12 +1,17 @@
 package repo_mocks
 
 import (
+	"context"
+	"time"
+
 	"github.com/idj1997/book-rent-core/domain"
 	"github.com/stretchr/testify/mock"
 )
 
 type MockedRentDetailsRepository struct {
 	mock.Mock
+	// IteratorError is returned by RentDetailsIterator once it sent every rent
+	IteratorError *domain.RepoError
 }
 
 func (m *MockedRentDetailsRepository) GetByID(id int) (*domain.RentDetails, error) {
@@ -44,7 +49,12 @@ func (m *MockedRentDetailsRepository) GetByStatus(status domain.RentDetailsStatu
 	return args.Get(0).([]domain.RentDetails), args.Error(1)
 }
 
-func (m *MockedRentDetailsRepository) RentDetailsIterator(stream chan domain.RentDetails) {
+func (m *MockedRentDetailsRepository) GetByStatusAndDeadline(status domain.RentDetailsStatus, from time.Time, to time.Time) ([]domain.RentDetails, error) {
+	args := m.Called(status, from, to)
+	return args.Get(0).([]domain.RentDetails), args.Error(1)
+}
+
+func (m *MockedRentDetailsRepository) RentDetailsIterator(ctx context.Context, stream chan<- domain.RentDetails) error {
 	defer close(stream)
 
 	rents := make([]domain.RentDetails, 3)
@@ -53,6 +63,11 @@ func (m *MockedRentDetailsRepository) RentDetailsIterator(stream chan domain.Ren
 	rents[2].Status = 2
 
 	for _, rent := range rents {
-		stream <- rent
+		select {
+		case stream <- rent:
+		case <-ctx.Done():
+			return &domain.RepoError{Type: domain.Unknown, Message: ctx.Err().Error()}
+		}
 	}
+	return m.IteratorError
 }
@@ -0,0 +1,30 @@
+package repo_mocks
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedReportRepository struct {
+	mock.Mock
+}
+
+func (m *MockedReportRepository) MostRentedBooks(query domain.ReportQuery) ([]domain.BookRentCount, error) {
+	args := m.Called(query)
+	return args.Get(0).([]domain.BookRentCount), args.Error(1)
+}
+
+func (m *MockedReportRepository) OverdueRates(query domain.ReportQuery) ([]domain.OverdueRate, error) {
+	args := m.Called(query)
+	return args.Get(0).([]domain.OverdueRate), args.Error(1)
+}
+
+func (m *MockedReportRepository) LoanDurations(query domain.ReportQuery) ([]domain.LoanDuration, error) {
+	args := m.Called(query)
+	return args.Get(0).([]domain.LoanDuration), args.Error(1)
+}
+
+func (m *MockedReportRepository) ActiveBorrowers(query domain.ReportQuery) ([]domain.ActiveBorrowers, error) {
+	args := m.Called(query)
+	return args.Get(0).([]domain.ActiveBorrowers), args.Error(1)
+}
@@ -0,0 +1,25 @@
+package repo_mocks
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedStatsRepository struct {
+	mock.Mock
+}
+
+func (m *MockedStatsRepository) RentsByStatus(statuses []domain.RentDetailsStatus) ([]domain.RentStatusCount, error) {
+	args := m.Called(statuses)
+	return args.Get(0).([]domain.RentStatusCount), args.Error(1)
+}
+
+func (m *MockedStatsRepository) LowStock(threshold int) ([]domain.LowStockCount, error) {
+	args := m.Called(threshold)
+	return args.Get(0).([]domain.LowStockCount), args.Error(1)
+}
+
+func (m *MockedStatsRepository) Tenants() ([]string, error) {
+	args := m.Called()
+	return args.Get(0).([]string), args.Error(1)
+}
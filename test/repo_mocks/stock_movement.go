@@ -0,0 +1,20 @@
+package repo_mocks
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedStockMovementRepository struct {
+	mock.Mock
+}
+
+func (m *MockedStockMovementRepository) Create(movement *domain.StockMovement) error {
+	args := m.Called(movement)
+	return args.Error(0)
+}
+
+func (m *MockedStockMovementRepository) GetByBook(bookID int) ([]domain.StockMovement, error) {
+	args := m.Called(bookID)
+	return args.Get(0).([]domain.StockMovement), args.Error(1)
+}
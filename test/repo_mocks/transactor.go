@@ -0,0 +1,12 @@
+package repo_mocks
+
+import "github.com/idj1997/book-rent-core/domain"
+
+// MockedTransactor runs transaction functions directly against mocked repositories
+type MockedTransactor struct {
+	Repos domain.Repositories
+}
+
+func (m *MockedTransactor) Transaction(fn func(repos domain.Repositories) error) error {
+	return fn(m.Repos)
+}
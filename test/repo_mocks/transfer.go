@@ -0,0 +1,35 @@
+package repo_mocks
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedTransferRepository struct {
+	mock.Mock
+}
+
+func (m *MockedTransferRepository) GetByID(id int) (*domain.Transfer, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Transfer), args.Error(1)
+}
+
+func (m *MockedTransferRepository) GetByIDForUpdate(id int) (*domain.Transfer, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Transfer), args.Error(1)
+}
+
+func (m *MockedTransferRepository) GetByBranch(branchID int) ([]domain.Transfer, error) {
+	args := m.Called(branchID)
+	return args.Get(0).([]domain.Transfer), args.Error(1)
+}
+
+func (m *MockedTransferRepository) Create(transfer *domain.Transfer) error {
+	args := m.Called(transfer)
+	return args.Error(0)
+}
+
+func (m *MockedTransferRepository) Update(transfer *domain.Transfer, updates map[string]interface{}) error {
+	args := m.Called(transfer, updates)
+	return args.Error(0)
+}
@@ -1,6 +1,8 @@
 package repo_mocks
 
 import (
+	"time"
+
 	"github.com/idj1997/book-rent-core/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -14,6 +16,11 @@ func (m *MockedUserRepository) GetByID(id int) (*domain.User, error) {
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockedUserRepository) GetByIDs(ids []int) ([]domain.User, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
 func (m *MockedUserRepository) GetByEmail(email string) (*domain.User, error) {
 	args := m.Called(email)
 	return args.Get(0).(*domain.User), args.Error(1)
@@ -25,6 +32,11 @@ func (m *MockedUserRepository) GetByFirstnameAndLastname(firstname string, lastn
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
+func (m *MockedUserRepository) Search(query string) ([]domain.User, error) {
+	args := m.Called(query)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
 func (m *MockedUserRepository) Create(user *domain.User) error {
 	args := m.Called(user)
 	return args.Error(0)
@@ -39,3 +51,18 @@ func (m *MockedUserRepository) Delete(id int) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MockedUserRepository) GetDeleted() ([]domain.User, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockedUserRepository) Restore(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockedUserRepository) Purge(deletedBefore time.Time) ([]uint, error) {
+	args := m.Called(deletedBefore)
+	return args.Get(0).([]uint), args.Error(1)
+}
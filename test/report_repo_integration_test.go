@@ -0,0 +1,116 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// ReportRepoIntegrationTestSuite runs against seeded rents, all started on 1 Jan 2020
+type ReportRepoIntegrationTestSuite struct {
+	suite.Suite
+	Repo  *repository.GormReportRepository
+	Db    *gorm.DB
+	Tx    *gorm.DB
+	Query domain.ReportQuery
+}
+
+func TestReportRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &ReportRepoIntegrationTestSuite{})
+}
+
+func (suite *ReportRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+}
+
+func (suite *ReportRepoIntegrationTestSuite) SetupTest() {
+	suite.Tx = suite.Db.Begin()
+	suite.Repo = repository.NewGormReportRepository(suite.Tx)
+	suite.Query = domain.ReportQuery{
+		From:    time.Date(2019, 12, 1, 0, 0, 0, 0, time.Local),
+		To:      time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local),
+		GroupBy: domain.GroupByMonth,
+		Limit:   10}
+}
+
+func (suite *ReportRepoIntegrationTestSuite) TearDownTest() {
+	suite.Tx.Rollback()
+	suite.Repo = nil
+}
+
+func (suite *ReportRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *ReportRepoIntegrationTestSuite) TestMostRentedBooks_WithSeededRents_ExpectRankedPerPeriod() {
+	a := assert.New(suite.T())
+
+	counts, err := suite.Repo.MostRentedBooks(suite.Query)
+	a.Nil(err)
+	a.Equal(2, len(counts))
+	a.Equal(time.January, counts[0].Period.Month())
+	a.Equal(uint(10000), counts[0].BookID)
+	a.Equal("title1", counts[0].Title)
+	a.Equal(int64(2), counts[0].Rents)
+}
+
+func (suite *ReportRepoIntegrationTestSuite) TestMostRentedBooks_WithLimit_ExpectTopOnly() {
+	a := assert.New(suite.T())
+	suite.Query.Limit = 1
+
+	counts, err := suite.Repo.MostRentedBooks(suite.Query)
+	a.Nil(err)
+	a.Equal(1, len(counts))
+}
+
+func (suite *ReportRepoIntegrationTestSuite) TestOverdueRates_WithSeededRents_ExpectExpiredCounted() {
+	a := assert.New(suite.T())
+
+	rates, err := suite.Repo.OverdueRates(suite.Query)
+	a.Nil(err)
+	a.Equal(1, len(rates))
+	a.Equal(int64(4), rates[0].Rents)
+	a.Equal(int64(1), rates[0].Overdue)
+}
+
+func (suite *ReportRepoIntegrationTestSuite) TestActiveBorrowers_ByDay_ExpectDistinctUsers() {
+	a := assert.New(suite.T())
+	suite.Query.GroupBy = domain.GroupByDay
+
+	borrowers, err := suite.Repo.ActiveBorrowers(suite.Query)
+	a.Nil(err)
+	a.Equal(1, len(borrowers))
+	a.Equal(1, borrowers[0].Period.Day())
+	a.Equal(int64(2), borrowers[0].Borrowers)
+	a.Equal(int64(4), borrowers[0].Rents)
+}
+
+func (suite *ReportRepoIntegrationTestSuite) TestLoanDurations_WithReturnedRent_ExpectAverageDays() {
+	a := assert.New(suite.T())
+	rentedAt := time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local)
+	rent := domain.RentDetails{
+		UserID:         10000,
+		BookID:         10001,
+		Status:         domain.RETURNED,
+		ReturnDeadline: rentedAt.AddDate(0, 0, 30),
+		ReturnedAt:     rentedAt.AddDate(0, 0, 10)}
+	rent.CreatedAt = rentedAt
+	a.Nil(suite.Tx.Create(&rent).Error)
+
+	durations, err := suite.Repo.LoanDurations(suite.Query)
+	a.Nil(err)
+	a.Equal(1, len(durations))
+	a.Equal(time.February, durations[0].Period.Month())
+	a.Equal(int64(1), durations[0].Returned)
+	a.InDelta(10, durations[0].AverageDays, 0.01)
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReportServiceUnitTestSuite struct {
+	suite.Suite
+	Service domain.ReportService
+	Repo    *repo_mocks.MockedReportRepository
+	From    time.Time
+	To      time.Time
+}
+
+func TestReportServiceUnitTestSuite(t *testing.T) {
+	suite.Run(t, &ReportServiceUnitTestSuite{})
+}
+
+func (suite *ReportServiceUnitTestSuite) SetupTest() {
+	suite.Repo = &repo_mocks.MockedReportRepository{}
+	suite.Service = &service.ReportService{Repo: suite.Repo}
+	suite.From = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.To = time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *ReportServiceUnitTestSuite) TestMostRentedBooks_WithInvertedRange_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	_, err := suite.Service.MostRentedBooks(domain.ReportQuery{From: suite.To, To: suite.From})
+	a.NotNil(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+	suite.Repo.AssertNotCalled(suite.T(), "MostRentedBooks")
+}
+
+func (suite *ReportServiceUnitTestSuite) TestActiveBorrowers_WithUnknownGrouping_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	_, err := suite.Service.ActiveBorrowers(domain.ReportQuery{From: suite.From, To: suite.To, GroupBy: "year"})
+	a.NotNil(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+}
+
+func (suite *ReportServiceUnitTestSuite) TestMostRentedBooks_WithoutGroupingAndLimit_ExpectDefaults() {
+	a := assert.New(suite.T())
+	expected := domain.ReportQuery{
+		From:    suite.From,
+		To:      suite.To,
+		GroupBy: domain.GroupByDay,
+		Limit:   service.DefaultReportLimit}
+	counts := []domain.BookRentCount{{Period: suite.From, BookID: 1, Title: "title", Rents: 3}}
+
+	suite.Repo.
+		On("MostRentedBooks", expected).
+		Return(counts, domain.NilRepoErrPtr)
+
+	result, err := suite.Service.MostRentedBooks(domain.ReportQuery{From: suite.From, To: suite.To})
+	a.Nil(err)
+	a.Equal(counts, result)
+}
+
+func (suite *ReportServiceUnitTestSuite) TestOverdueRates_WithRents_ExpectRateComputed() {
+	a := assert.New(suite.T())
+	query := domain.ReportQuery{From: suite.From, To: suite.To, GroupBy: domain.GroupByWeek, Limit: 5}
+	rates := []domain.OverdueRate{
+		{Period: suite.From, Rents: 4, Overdue: 1},
+		{Period: suite.From.AddDate(0, 0, 7), Rents: 0, Overdue: 0}}
+
+	suite.Repo.
+		On("OverdueRates", query).
+		Return(rates, domain.NilRepoErrPtr)
+
+	result, err := suite.Service.OverdueRates(query)
+	a.Nil(err)
+	a.Equal(0.25, result[0].Rate)
+	a.Zero(result[1].Rate)
+}
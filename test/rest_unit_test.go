@@ -0,0 +1,360 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/idj1997/book-rent-core/auth"
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/logging"
+	"github.com/idj1997/book-rent-core/restapi"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type RESTUnitTestSuite struct {
+	suite.Suite
+	bookRepo  *repo_mocks.MockedBookRepository
+	userRepo  *repo_mocks.MockedUserRepository
+	rentRepo  *repo_mocks.MockedRentDetailsRepository
+	auditRepo *repo_mocks.MockedAuditRepository
+	spec      *openapi3.T
+	router    *mux.Router
+	// scoped is context services of last request were scoped to
+	scoped context.Context
+}
+
+const restTestToken = "rest-test-token"
+
+func TestRESTUnitTestSuite(t *testing.T) {
+	suite.Run(t, &RESTUnitTestSuite{})
+}
+
+func (suite *RESTUnitTestSuite) SetupTest() {
+	suite.scoped = nil
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.userRepo = &repo_mocks.MockedUserRepository{}
+	suite.rentRepo = &repo_mocks.MockedRentDetailsRepository{}
+	movements := &repo_mocks.MockedStockMovementRepository{}
+	movements.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	outboxRepo := &repo_mocks.MockedOutboxRepository{}
+	outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.auditRepo = &repo_mocks.MockedAuditRepository{}
+	suite.auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	branches := repo_mocks.NewMockedBranchRepository()
+	branches.On("GetStock", mock.Anything, mock.Anything).Return(&domain.BranchStock{Stock: 0}, domain.NilRepoErrPtr)
+	tx := &repo_mocks.MockedTransactor{Repos: domain.Repositories{
+		Books:     suite.bookRepo,
+		Users:     suite.userRepo,
+		Rents:     suite.rentRepo,
+		Audit:     suite.auditRepo,
+		Outbox:    outboxRepo,
+		Movements: movements,
+		Branches:  branches}}
+	scope := func(ctx context.Context) restapi.Services {
+		suite.scoped = ctx
+		return restapi.Services{
+			Books: service.NewBookService(suite.bookRepo, tx).WithLogger(logging.FromContext(ctx)),
+			Users: (&service.UserService{Repo: suite.userRepo, Tx: tx}).WithLogger(logging.FromContext(ctx)),
+			Rents: &service.RentDetailsService{RentRepo: suite.rentRepo, BookRepo: suite.bookRepo, Tx: tx}}
+	}
+
+	spec, err := restapi.LoadSpec()
+	suite.Require().Nil(err)
+	suite.spec = spec
+	suite.router = restapi.NewRouter(scope, map[string]config.ActorConfig{"tester": {Token: restTestToken, Tenant: "acme"}})
+}
+
+// do serves request of test actor and requires response to conform to its operation in spec
+func (suite *RESTUnitTestSuite) do(method string, path string, body string) *httptest.ResponseRecorder {
+	return suite.doWithToken(restTestToken, method, path, body)
+}
+
+// doWithToken is do presenting token, none when it is empty
+func (suite *RESTUnitTestSuite) doWithToken(token string, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set(auth.Header, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	suite.router.ServeHTTP(rec, req)
+
+	var match mux.RouteMatch
+	suite.Require().True(suite.router.Match(req, &match), "route of %s %s", method, path)
+	template, err := match.Route.GetPathTemplate()
+	suite.Require().Nil(err)
+	route := restapi.Route(suite.spec, template, method)
+	suite.Require().NotNil(route, "route %s %s is not described by spec", method, template)
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: req, PathParams: match.Vars, Route: route},
+		Status:                 rec.Code,
+		Header:                 rec.Header(),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true}}
+	input.SetBodyBytes(rec.Body.Bytes())
+	suite.Require().Nil(openapi3filter.ValidateResponse(context.Background(), input), "response of %s %s: %s", method, path, rec.Body.String())
+	return rec
+}
+
+func (suite *RESTUnitTestSuite) errorCode(rec *httptest.ResponseRecorder) string {
+	var resp restapi.ErrorResponse
+	suite.Require().Nil(json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Error.Code
+}
+
+func (suite *RESTUnitTestSuite) TestRoutes_ExpectEveryRouteDescribedBySpecAndEveryOperationRegistered() {
+	a := assert.New(suite.T())
+
+	var registered []string
+	err := suite.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		suite.Require().Nil(err)
+		methods, err := route.GetMethods()
+		suite.Require().Nil(err)
+		for _, method := range methods {
+			pathItem := suite.spec.Paths.Find(template)
+			if a.NotNil(pathItem, "path %s", template) {
+				a.NotNil(pathItem.GetOperation(method), "operation %s %s", method, template)
+			}
+			registered = append(registered, method+" "+template)
+		}
+		return nil
+	})
+	suite.Require().Nil(err)
+
+	var described []string
+	for path, pathItem := range suite.spec.Paths {
+		for method := range pathItem.Operations() {
+			described = append(described, method+" "+path)
+		}
+	}
+	sort.Strings(registered)
+	sort.Strings(described)
+	a.Equal(described, registered)
+}
+
+func (suite *RESTUnitTestSuite) TestGetSpec_ExpectOpenAPIDocument() {
+	a := assert.New(suite.T())
+
+	rec := suite.doWithToken("", http.MethodGet, "/openapi.json", "")
+
+	a.Equal(http.StatusOK, rec.Code)
+	spec, err := openapi3.NewLoader().LoadFromData(rec.Body.Bytes())
+	suite.Require().Nil(err)
+	a.Equal(len(suite.spec.Paths), len(spec.Paths))
+}
+
+func (suite *RESTUnitTestSuite) TestGetBook_ExpectBookMapped() {
+	a := assert.New(suite.T())
+	book := &domain.Book{Title: "title", Content: "content", Stock: 3, Price: 1500, ISBN: "isbn"}
+	book.ID = 7
+	suite.bookRepo.On("GetByID", 7).Return(book, domain.NilRepoErrPtr)
+
+	rec := suite.do(http.MethodGet, "/books/7", "")
+
+	a.Equal(http.StatusOK, rec.Code)
+	a.JSONEq(`{"id": 7, "title": "title", "content": "content", "stock": 3, "price": 1500, "isbn": "isbn"}`, rec.Body.String())
+}
+
+func (suite *RESTUnitTestSuite) TestGetBook_WithoutValidToken_ExpectUnauthenticatedEnvelope() {
+	a := assert.New(suite.T())
+
+	for _, token := range []string{"", "wrong-token"} {
+		rec := suite.doWithToken(token, http.MethodGet, "/books/7", "")
+
+		a.Equal(http.StatusUnauthorized, rec.Code, token)
+		a.Equal(restapi.UnauthenticatedCode, suite.errorCode(rec), token)
+		a.Equal("Bearer", rec.Header().Get("WWW-Authenticate"))
+	}
+	a.Nil(suite.scoped)
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByID", mock.Anything)
+}
+
+func (suite *RESTUnitTestSuite) TestGetBook_WithToken_ExpectScopedToActorAndItsTenant() {
+	a := assert.New(suite.T())
+	suite.bookRepo.On("GetByID", 7).Return(domain.NilBookPtr, &domain.RepoError{Type: domain.NotFound})
+
+	suite.do(http.MethodGet, "/books/7", "")
+
+	suite.Require().NotNil(suite.scoped)
+	a.Equal("tester", auth.ActorFromContext(suite.scoped))
+	a.Equal("acme", domain.TenantFromContext(suite.scoped))
+}
+
+func (suite *RESTUnitTestSuite) TestGetBook_WithNotFound_ExpectNotFoundEnvelope() {
+	a := assert.New(suite.T())
+	suite.bookRepo.On("GetByID", 1).Return(domain.NilBookPtr, &domain.RepoError{Type: domain.NotFound})
+
+	rec := suite.do(http.MethodGet, "/books/1", "")
+
+	a.Equal(http.StatusNotFound, rec.Code)
+	a.Equal("NOT_FOUND", suite.errorCode(rec))
+}
+
+func (suite *RESTUnitTestSuite) TestGetBook_WithInvalidID_ExpectRejectedBeforeService() {
+	a := assert.New(suite.T())
+
+	for _, path := range []string{"/books/abc", "/books/0"} {
+		rec := suite.do(http.MethodGet, path, "")
+
+		a.Equal(http.StatusBadRequest, rec.Code, path)
+		a.Equal("INVALID_ARGUMENTS", suite.errorCode(rec), path)
+	}
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByID", mock.Anything)
+}
+
+func (suite *RESTUnitTestSuite) TestSearchBooks_WithoutTitle_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	rec := suite.do(http.MethodGet, "/books", "")
+
+	a.Equal(http.StatusBadRequest, rec.Code)
+	a.Contains(rec.Body.String(), "title")
+	suite.bookRepo.AssertNotCalled(suite.T(), "GetByTitle", mock.Anything)
+}
+
+func (suite *RESTUnitTestSuite) TestCreateBook_WithInvalidBody_ExpectRejectedBeforeService() {
+	a := assert.New(suite.T())
+
+	bodies := []string{
+		`{"content": "no title"}`,
+		`{"title": "title", "stock": -1}`,
+		`{"title": "title", "author": "unknown field"}`,
+		`not json`}
+	for _, body := range bodies {
+		rec := suite.do(http.MethodPost, "/books", body)
+
+		a.Equal(http.StatusBadRequest, rec.Code, body)
+		a.Equal("INVALID_ARGUMENTS", suite.errorCode(rec), body)
+	}
+	suite.bookRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *RESTUnitTestSuite) TestCreateUser_ExpectCreatedWithoutPassword() {
+	a := assert.New(suite.T())
+	suite.userRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+
+	rec := suite.do(http.MethodPost, "/users",
+		`{"firstname": "first", "email": "first@mail.com", "password": "password", "type": "ADMIN"}`)
+
+	a.Equal(http.StatusCreated, rec.Code)
+	a.NotContains(rec.Body.String(), "password")
+	created := suite.userRepo.Calls[0].Arguments.Get(0).(*domain.User)
+	a.Nil(bcrypt.CompareHashAndPassword([]byte(created.Password), []byte("password")))
+	a.Equal(domain.ADMIN, created.Type)
+	record := suite.auditRepo.Calls[0].Arguments.Get(0).(*domain.AuditRecord)
+	a.Equal("tester", record.Actor)
+}
+
+func (suite *RESTUnitTestSuite) TestDeleteUser_ExpectNoContent() {
+	a := assert.New(suite.T())
+	user := &domain.User{Email: "first@mail.com"}
+	user.ID = 3
+	suite.userRepo.On("GetByID", 3).Return(user, domain.NilRepoErrPtr)
+	suite.rentRepo.On("GetByUser", 3).Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+	suite.userRepo.On("Delete", 3).Return(domain.NilRepoErrPtr)
+
+	rec := suite.do(http.MethodDelete, "/users/3", "")
+
+	a.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (suite *RESTUnitTestSuite) TestListUserRents_ExpectReturnedAtOnlyOnReturnedRents() {
+	a := assert.New(suite.T())
+	now := time.Now()
+	rents := []domain.RentDetails{
+		{UserID: 5, BookID: 1, ReturnDeadline: now.Add(time.Hour)},
+		{UserID: 5, BookID: 2, Status: domain.RETURNED, ReturnDeadline: now, ReturnedAt: now}}
+	for i := range rents {
+		rents[i].ID = uint(i + 1)
+		rents[i].CreatedAt = now
+	}
+	suite.rentRepo.On("GetByUser", 5).Return(rents, domain.NilRepoErrPtr)
+
+	rec := suite.do(http.MethodGet, "/users/5/rents", "")
+
+	a.Equal(http.StatusOK, rec.Code)
+	var resp []restapi.Rent
+	suite.Require().Nil(json.Unmarshal(rec.Body.Bytes(), &resp))
+	suite.Require().Len(resp, 2)
+	a.Nil(resp[0].ReturnedAt)
+	a.Equal("RETURNED", resp[1].Status)
+	a.NotNil(resp[1].ReturnedAt)
+}
+
+func (suite *RESTUnitTestSuite) TestRentBook_WithEmptyStock_ExpectConflict() {
+	a := assert.New(suite.T())
+	book := domain.Book{Title: "title"}
+	book.ID = 3
+	suite.bookRepo.On("GetByID", 3).Return(&book, domain.NilRepoErrPtr)
+
+	rec := suite.do(http.MethodPost, "/rents", `{"userId": 5, "bookId": 3}`)
+
+	a.Equal(http.StatusConflict, rec.Code)
+	a.Equal("NOT_ENOUGH_BOOKS_ON_STOCK", suite.errorCode(rec))
+	suite.rentRepo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *RESTUnitTestSuite) TestReturnBook_WithoutBody_ExpectReturnedRent() {
+	a := assert.New(suite.T())
+	now := time.Now()
+	book := domain.Book{Title: "title", Stock: 1}
+	book.ID = 3
+	rent := domain.RentDetails{UserID: 5, BookID: 3, Book: book, ReturnDeadline: now}
+	rent.ID = 7
+	rent.CreatedAt = now
+	returned := rent
+	returned.Status = domain.RETURNED
+	returned.ReturnedAt = now
+	suite.rentRepo.On("GetByID", 7).Return(&rent, domain.NilRepoErrPtr).Once()
+	suite.rentRepo.On("GetByID", 7).Return(&returned, domain.NilRepoErrPtr).Once()
+	suite.rentRepo.On("Update", &rent, mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.bookRepo.On("GetByIDForUpdate", 3).Return(&book, domain.NilRepoErrPtr)
+	suite.bookRepo.On("Update", mock.Anything, mock.Anything).Return(domain.NilRepoErrPtr)
+
+	rec := suite.do(http.MethodPost, "/rents/7/return", "")
+
+	a.Equal(http.StatusOK, rec.Code)
+	var resp restapi.Rent
+	suite.Require().Nil(json.Unmarshal(rec.Body.Bytes(), &resp))
+	a.Equal("RETURNED", resp.Status)
+}
+
+func (suite *RESTUnitTestSuite) TestUnknownRoute_ExpectNotFoundEnvelope() {
+	a := assert.New(suite.T())
+	rec := httptest.NewRecorder()
+
+	suite.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	a.Equal(http.StatusNotFound, rec.Code)
+	a.Equal("NOT_FOUND", suite.errorCode(rec))
+}
+
+func (suite *RESTUnitTestSuite) TestStatusCode_ExpectEveryServiceErrorTypeMapped() {
+	a := assert.New(suite.T())
+
+	a.Equal(http.StatusNotFound, restapi.StatusCode(service.NotFound))
+	a.Equal(http.StatusConflict, restapi.StatusCode(service.AlreadyExist))
+	a.Equal(http.StatusBadRequest, restapi.StatusCode(service.InvalidArguments))
+	a.Equal(http.StatusConflict, restapi.StatusCode(service.NotEnoughBooksOnStock))
+	a.Equal(http.StatusConflict, restapi.StatusCode(service.BookAlreadyReturned))
+	a.Equal(http.StatusConflict, restapi.StatusCode(service.ActiveBookRents))
+	a.Equal(http.StatusConflict, restapi.StatusCode(service.InvalidStatusTransition))
+	a.Equal(http.StatusInternalServerError, restapi.StatusCode(service.Unknown))
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/notify"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SMTPNotifierUnitTestSuite struct {
+	suite.Suite
+	notifier *notify.SMTPNotifier
+}
+
+func TestSMTPNotifierUnitTestSuite(t *testing.T) {
+	suite.Run(t, &SMTPNotifierUnitTestSuite{})
+}
+
+func (suite *SMTPNotifierUnitTestSuite) SetupTest() {
+	suite.notifier = &notify.SMTPNotifier{Host: "localhost", Port: 25, From: "library@mail.com"}
+}
+
+func (suite *SMTPNotifierUnitTestSuite) TestMessage_WithNonASCIISubject_ExpectEncodedSubject() {
+	a := assert.New(suite.T())
+
+	message, err := suite.notifier.Message(domain.Notification{To: "johndoe@gmail.com", Subject: `Overdue: "Zoë"`, Body: "body"})
+
+	suite.Require().Nil(err)
+	a.Contains(string(message), "\r\nSubject: =?utf-8?q?Overdue:_\"Zo=C3=AB\"?=\r\n")
+}
+
+func (suite *SMTPNotifierUnitTestSuite) TestMessage_WithLineBreakInSubject_ExpectNoInjectedHeader() {
+	a := assert.New(suite.T())
+
+	message, err := suite.notifier.Message(domain.Notification{
+		To:      "johndoe@gmail.com",
+		Subject: "Overdue\r\nBcc: attacker@mail.com",
+		Body:    "body"})
+
+	suite.Require().Nil(err)
+	headers := strings.SplitN(string(message), "\r\n\r\n", 2)[0]
+	for _, header := range strings.Split(headers, "\r\n") {
+		a.False(strings.HasPrefix(header, "Bcc:"), header)
+	}
+	a.Contains(headers, "Subject: Overdue Bcc: attacker@mail.com")
+}
+
+func (suite *SMTPNotifierUnitTestSuite) TestMessage_WithLineBreakInRecipient_ExpectError() {
+	a := assert.New(suite.T())
+
+	_, err := suite.notifier.Message(domain.Notification{To: "johndoe@gmail.com\r\nBcc: attacker@mail.com", Subject: "subject"})
+
+	a.Error(err)
+}
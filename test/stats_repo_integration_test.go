@@ -0,0 +1,93 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/metrics"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type StatsRepoIntegrationTestSuite struct {
+	suite.Suite
+	Db      *gorm.DB
+	Tx      *gorm.DB
+	Repo    *repository.GormStatsRepository
+	Queries *metrics.QueryMetrics
+}
+
+func TestStatsRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &StatsRepoIntegrationTestSuite{})
+}
+
+func (suite *StatsRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+
+	suite.Queries, err = metrics.NewQueryMetrics(metrics.NewRegistry())
+	suite.Require().Nil(err)
+	suite.Require().Nil(suite.Db.Use(suite.Queries))
+}
+
+func (suite *StatsRepoIntegrationTestSuite) SetupTest() {
+	suite.Tx = suite.Db.Begin()
+	suite.Repo = repository.NewGormStatsRepository(suite.Tx)
+}
+
+func (suite *StatsRepoIntegrationTestSuite) TearDownTest() {
+	suite.Tx.Rollback()
+}
+
+func (suite *StatsRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *StatsRepoIntegrationTestSuite) TestRentsByStatus_ExpectOnlyRequestedStatuses() {
+	a := assert.New(suite.T())
+
+	counts, err := suite.Repo.RentsByStatus([]domain.RentDetailsStatus{domain.RENTED})
+	a.Nil(err)
+	for _, count := range counts {
+		a.Equal(domain.RENTED, count.Status)
+		a.NotEmpty(count.TenantID)
+		a.True(count.Rents > 0)
+	}
+}
+
+func (suite *StatsRepoIntegrationTestSuite) TestLowStock_WithEmptiedStock_ExpectBookCounted() {
+	a := assert.New(suite.T())
+	before, err := suite.Repo.LowStock(0)
+	a.Nil(err)
+
+	result := suite.Tx.Exec("UPDATE branch_stocks SET stock = 0 WHERE book_id = (SELECT min(book_id) FROM branch_stocks WHERE stock > 0) AND stock > 0")
+	suite.Require().Nil(result.Error)
+	suite.Require().True(result.RowsAffected > 0)
+
+	after, err := suite.Repo.LowStock(0)
+	a.Nil(err)
+	a.Equal(totalBooks(before)+result.RowsAffected, totalBooks(after))
+}
+
+func (suite *StatsRepoIntegrationTestSuite) TestQueryMetrics_ExpectQueriesTimedByTable() {
+	a := assert.New(suite.T())
+	before := histogramCount(suite.Queries.Durations, "books", "query", "ok")
+
+	var books []domain.Book
+	suite.Require().Nil(suite.Db.Limit(1).Find(&books).Error)
+	a.Equal(before+1, histogramCount(suite.Queries.Durations, "books", "query", "ok"))
+}
+
+func totalBooks(counts []domain.LowStockCount) int64 {
+	var total int64
+	for _, count := range counts {
+		total += count.Books
+	}
+	return total
+}
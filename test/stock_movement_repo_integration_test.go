@@ -0,0 +1,123 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"github.com/idj1997/book-rent-core/service"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type StockMovementRepoIntegrationTestSuite struct {
+	suite.Suite
+	Repo *repository.GormStockMovementRepository
+	Db   *gorm.DB
+}
+
+func TestStockMovementRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &StockMovementRepoIntegrationTestSuite{})
+}
+
+func (suite *StockMovementRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+}
+
+func (suite *StockMovementRepoIntegrationTestSuite) SetupTest() {
+	tx := suite.Db.Begin()
+	suite.Repo = repository.NewGormStockMovementRepository(tx)
+}
+
+func (suite *StockMovementRepoIntegrationTestSuite) TearDownTest() {
+	suite.Repo.Db.Rollback()
+	suite.Repo.Db = nil
+}
+
+func (suite *StockMovementRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *StockMovementRepoIntegrationTestSuite) TestGetByBook_WithSeededBook_ExpectOpeningBalance() {
+	a := assert.New(suite.T())
+
+	movements, err := suite.Repo.GetByBook(10000)
+	a.Nil(err)
+	a.Equal(1, len(movements))
+	a.Equal(domain.MovementCorrection, movements[0].Kind)
+	a.Equal(5, movements[0].Quantity)
+}
+
+func (suite *StockMovementRepoIntegrationTestSuite) TestCreate_WithMovements_ExpectLedgerSumEqualsStock() {
+	a := assert.New(suite.T())
+	now := time.Now()
+	movements := []domain.StockMovement{
+		{BookID: 10001, Kind: domain.MovementPurchase, Quantity: 4, Reason: "delivery", Actor: "admin", CreatedAt: now},
+		{BookID: 10001, Kind: domain.MovementRentOut, Quantity: -1, Actor: "system", RentID: 10003, CreatedAt: now.Add(time.Second)}}
+	for i := range movements {
+		a.Nil(suite.Repo.Create(&movements[i]))
+	}
+
+	ledger, err := suite.Repo.GetByBook(10001)
+	a.Nil(err)
+	a.Equal(3, len(ledger))
+	a.Equal(domain.MovementRentOut, ledger[2].Kind)
+
+	sum := 0
+	for _, movement := range ledger {
+		sum += movement.Quantity
+	}
+	a.Equal(15+4-1, sum)
+}
+
+func (suite *StockMovementRepoIntegrationTestSuite) TestRecordMovement_Concurrently_ExpectNoLostUpdates() {
+	a := assert.New(suite.T())
+	// movements commit, so they run against own book removed afterwards
+	book := domain.Book{Title: "concurrent", Content: "concurrent"}
+	suite.Require().Nil(suite.Db.Create(&book).Error)
+	defer func() {
+		suite.Db.Where("book_id = ?", book.ID).Delete(&domain.StockMovement{})
+		suite.Db.Where("book_id = ?", book.ID).Delete(&domain.BranchStock{})
+		suite.Db.Where("entity = ? AND entity_id = ?", "Book", book.ID).Delete(&domain.AuditRecord{})
+		suite.Db.Where("type = ? AND (payload->>'bookId')::int = ?", domain.StockChanged, book.ID).Delete(&domain.OutboxEvent{})
+		suite.Db.Unscoped().Delete(&book)
+	}()
+
+	inventory := service.NewInventoryService(
+		repository.NewGormStockMovementRepository(suite.Db),
+		repository.NewGormTransactor(suite.Db))
+	const movements = 10
+	errs := make(chan error, movements)
+	var wg sync.WaitGroup
+	for i := 0; i < movements; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := inventory.RecordMovement(int(book.ID), 0, domain.MovementPurchase, 1, "delivery")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		a.Nil(err)
+	}
+
+	var stored domain.Book
+	suite.Require().Nil(suite.Db.First(&stored, book.ID).Error)
+	a.Equal(movements, stored.Stock)
+	var branchStock domain.BranchStock
+	suite.Require().Nil(suite.Db.Where("book_id = ?", book.ID).First(&branchStock).Error)
+	a.Equal(movements, branchStock.Stock)
+	ledger, err := repository.NewGormStockMovementRepository(suite.Db).GetByBook(int(book.ID))
+	a.Nil(err)
+	a.Equal(movements, len(ledger))
+}
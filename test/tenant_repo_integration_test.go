@@ -0,0 +1,306 @@
+package test
+
+import (
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// TenantRepoIntegrationTestSuite stores records of tenant acme next to seeded records of default tenant
+type TenantRepoIntegrationTestSuite struct {
+	suite.Suite
+	Db     *gorm.DB
+	Tx     *gorm.DB
+	Acme   *gorm.DB
+	book   domain.Book
+	user   domain.User
+	rent   domain.RentDetails
+	branch *domain.Branch
+}
+
+func TestTenantRepoIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &TenantRepoIntegrationTestSuite{})
+}
+
+func (suite *TenantRepoIntegrationTestSuite) SetupSuite() {
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
+}
+
+func (suite *TenantRepoIntegrationTestSuite) SetupTest() {
+	suite.Tx = suite.Db.Begin()
+	suite.Acme = repository.ForTenant(suite.Tx, "acme")
+
+	suite.book = domain.Book{Title: "title1", Content: "acme content", Stock: 2, ISBN: "978-0000000001"}
+	_, err := repository.NewGormBookRepository(suite.Acme).Create(&suite.book)
+	suite.Require().Nil(err)
+
+	// same email as seeded user of default tenant
+	suite.user = domain.User{Firstname: "john", Lastname: "doe", Email: "johndoe@gmail.com", Password: "secret"}
+	err = repository.NewGormUserRepository(suite.Acme).Create(&suite.user)
+	suite.Require().Nil(err)
+
+	suite.rent = domain.RentDetails{
+		UserID:         int(suite.user.ID),
+		BookID:         int(suite.book.ID),
+		ReturnDeadline: time.Now().Add(-time.Hour)}
+	err = (&repository.GormRentDetailsRepository{Db: suite.Acme}).Create(&suite.rent)
+	suite.Require().Nil(err)
+
+	branches := repository.NewGormBranchRepository(suite.Acme)
+	suite.branch, err = branches.GetDefault()
+	suite.Require().Nil(err)
+	err = branches.SaveStock(&domain.BranchStock{BookID: suite.book.ID, BranchID: suite.branch.ID, Stock: 2})
+	suite.Require().Nil(err)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TearDownTest() {
+	suite.Tx.Rollback()
+	suite.Tx, suite.Acme = nil, nil
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TearDownSuite() {
+	suite.Nil(config.ClosePostgresDB(suite.Db))
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestCreate_WithTenantContext_ExpectRecordsStamped() {
+	a := assert.New(suite.T())
+
+	a.Equal("acme", suite.book.TenantID)
+	a.Equal("acme", suite.user.TenantID)
+	a.Equal("acme", suite.rent.TenantID)
+
+	seeded, err := repository.NewGormBookRepository(suite.Tx).GetByID(10000)
+	a.Nil(err)
+	a.Equal(domain.DefaultTenant, seeded.TenantID)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestBookRepository_AcrossTenants_ExpectNoLeakage() {
+	a := assert.New(suite.T())
+	defaultBooks := repository.NewGormBookRepository(suite.Tx)
+	acmeBooks := repository.NewGormBookRepository(suite.Acme)
+
+	_, err := defaultBooks.GetByID(int(suite.book.ID))
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+	_, err = acmeBooks.GetByID(10000)
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+
+	books, err := defaultBooks.GetByTitle("title1")
+	a.Nil(err)
+	a.Equal(1, len(books))
+	a.Equal(uint(10000), books[0].ID)
+
+	books, err = acmeBooks.GetAll()
+	a.Nil(err)
+	a.Equal(1, len(books))
+	a.Equal(suite.book.ID, books[0].ID)
+
+	_, err = defaultBooks.GetByNaturalKey(suite.book.ISBN, "")
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+
+	// writes through other tenant touch nothing
+	seeded := domain.Book{}
+	seeded.ID = 10000
+	err = acmeBooks.Update(&seeded, map[string]interface{}{"stock": 0})
+	a.Nil(err)
+	err = acmeBooks.Delete(10000)
+	a.Nil(err)
+	book, err := defaultBooks.GetByID(10000)
+	a.Nil(err)
+	a.Equal(5, book.Stock)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestBookRepository_WithSameISBNInOtherTenant_ExpectCreated() {
+	a := assert.New(suite.T())
+
+	_, err := repository.NewGormBookRepository(suite.Tx).Create(&domain.Book{Title: "t", Content: "c", ISBN: suite.book.ISBN})
+	a.Nil(err)
+
+	_, err = repository.NewGormBookRepository(suite.Acme).Create(&domain.Book{Title: "t", Content: "c", ISBN: suite.book.ISBN})
+	a.Equal(domain.UniqueConstraint, err.(*domain.RepoError).Type)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestUserRepository_AcrossTenants_ExpectNoLeakage() {
+	a := assert.New(suite.T())
+	defaultUsers := repository.NewGormUserRepository(suite.Tx)
+	acmeUsers := repository.NewGormUserRepository(suite.Acme)
+
+	user, err := defaultUsers.GetByEmail("johndoe@gmail.com")
+	a.Nil(err)
+	a.Equal(uint(10000), user.ID)
+
+	user, err = acmeUsers.GetByEmail("johndoe@gmail.com")
+	a.Nil(err)
+	a.Equal(suite.user.ID, user.ID)
+
+	users, err := acmeUsers.Search("doe")
+	a.Nil(err)
+	a.Equal(1, len(users))
+
+	err = acmeUsers.Create(&domain.User{Firstname: "jane", Lastname: "doe", Email: "johndoe@gmail.com", Password: "secret"})
+	a.Equal(domain.UniqueConstraint, err.(*domain.RepoError).Type)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestRentRepository_AcrossTenants_ExpectNoLeakage() {
+	a := assert.New(suite.T())
+	defaultRents := &repository.GormRentDetailsRepository{Db: suite.Tx}
+	acmeRents := &repository.GormRentDetailsRepository{Db: suite.Acme}
+
+	_, err := defaultRents.GetByID(int(suite.rent.ID))
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+
+	rent, err := acmeRents.GetByID(int(suite.rent.ID))
+	a.Nil(err)
+	a.Equal(suite.book.ID, rent.Book.ID)
+	a.Equal(suite.user.ID, rent.User.ID)
+
+	rents, err := acmeRents.GetByStatus(domain.RENTED)
+	a.Nil(err)
+	a.Equal(1, len(rents))
+
+	rents, err = defaultRents.GetByStatusAndDeadline(domain.RENTED, time.Time{}, time.Now())
+	a.Nil(err)
+	for _, r := range rents {
+		a.NotEqual(suite.rent.ID, r.ID)
+	}
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestBranchRepository_WithOtherTenantBook_ExpectNotListed() {
+	a := assert.New(suite.T())
+
+	stocks, err := repository.NewGormBranchRepository(suite.Tx).GetAvailableBooks(int(suite.branch.ID))
+	a.Nil(err)
+	for _, stock := range stocks {
+		a.NotEqual(suite.book.ID, stock.BookID)
+	}
+
+	stocks, err = repository.NewGormBranchRepository(suite.Acme).GetAvailableBooks(int(suite.branch.ID))
+	a.Nil(err)
+	a.Equal(1, len(stocks))
+	a.Equal(suite.book.ID, stocks[0].BookID)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestBranchRepository_AcrossTenants_ExpectOwnBranchesOnly() {
+	a := assert.New(suite.T())
+	defaultBranches := repository.NewGormBranchRepository(suite.Tx)
+
+	branch, err := defaultBranches.GetDefault()
+	a.Nil(err)
+	a.NotEqual(suite.branch.ID, branch.ID)
+	a.Equal("acme", suite.branch.TenantID)
+	_, err = defaultBranches.GetByID(int(suite.branch.ID))
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+
+	// same name as default branch of default tenant
+	named := domain.Branch{Name: branch.Name + " east"}
+	a.Nil(defaultBranches.Create(&named))
+	acmeNamed := domain.Branch{Name: named.Name}
+	a.Nil(repository.NewGormBranchRepository(suite.Acme).Create(&acmeNamed))
+	branches, err := defaultBranches.GetAll()
+	a.Nil(err)
+	for _, b := range branches {
+		a.NotEqual(acmeNamed.ID, b.ID)
+	}
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestBranchRepository_AcrossTenants_ExpectOwnStockAndTransfersOnly() {
+	a := assert.New(suite.T())
+	transfer := domain.Transfer{BookID: suite.book.ID, FromBranchID: suite.branch.ID, ToBranchID: suite.branch.ID, Quantity: 1}
+	suite.Require().Nil(repository.NewGormTransferRepository(suite.Acme).Create(&transfer))
+	a.Equal("acme", transfer.TenantID)
+
+	stocks, err := repository.NewGormBranchRepository(suite.Tx).GetAvailability(int(suite.book.ID))
+	a.Nil(err)
+	a.Equal(0, len(stocks))
+	stocks, err = repository.NewGormBranchRepository(suite.Acme).GetAvailability(int(suite.book.ID))
+	a.Nil(err)
+	a.Equal(1, len(stocks))
+	a.Equal("acme", stocks[0].TenantID)
+
+	transfers, err := repository.NewGormTransferRepository(suite.Tx).GetByBranch(int(suite.branch.ID))
+	a.Nil(err)
+	for _, t := range transfers {
+		a.NotEqual(transfer.ID, t.ID)
+	}
+	_, err = repository.NewGormTransferRepository(suite.Tx).GetByID(int(transfer.ID))
+	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestStockMovementRepository_AcrossTenants_ExpectOwnLedgerOnly() {
+	a := assert.New(suite.T())
+	movement := domain.StockMovement{BookID: suite.book.ID, BranchID: suite.branch.ID, Kind: domain.MovementPurchase,
+		Quantity: 2, Actor: "admin", CreatedAt: time.Now()}
+	suite.Require().Nil(repository.NewGormStockMovementRepository(suite.Acme).Create(&movement))
+	a.Equal("acme", movement.TenantID)
+
+	movements, err := repository.NewGormStockMovementRepository(suite.Tx).GetByBook(int(suite.book.ID))
+	a.Nil(err)
+	a.Equal(0, len(movements))
+	movements, err = repository.NewGormStockMovementRepository(suite.Acme).GetByBook(int(suite.book.ID))
+	a.Nil(err)
+	a.Equal(1, len(movements))
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestAuditRepository_AcrossTenants_ExpectOwnRecordsOnly() {
+	a := assert.New(suite.T())
+	record := domain.AuditRecord{Actor: "admin", Entity: "Book", EntityID: suite.book.ID, Action: domain.AuditUpdate,
+		CreatedAt: time.Now()}
+	suite.Require().Nil(repository.NewGormAuditRepository(suite.Acme).Create(&record))
+	a.Equal("acme", record.TenantID)
+
+	filter := domain.AuditFilter{Entity: "Book", EntityID: suite.book.ID}
+	records, err := repository.NewGormAuditRepository(suite.Tx).Find(filter)
+	a.Nil(err)
+	a.Equal(0, len(records))
+	records, err = repository.NewGormAuditRepository(suite.Acme).Find(filter)
+	a.Nil(err)
+	a.Equal(1, len(records))
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestOutboxRepository_AcrossTenants_ExpectDispatchedForAllTenants() {
+	a := assert.New(suite.T())
+	event := domain.OutboxEvent{Type: domain.StockChanged, Payload: []byte(`{}`), CreatedAt: time.Now(), NextAttemptAt: time.Now()}
+	suite.Require().Nil(repository.NewGormOutboxRepository(suite.Acme).Create(&event))
+	a.Equal("acme", event.TenantID)
+
+	events, err := repository.NewGormOutboxRepository(suite.Tx).GetPending(time.Now(), 100)
+	a.Nil(err)
+	for _, e := range events {
+		a.NotEqual(event.ID, e.ID)
+	}
+
+	events, err = repository.NewGormOutboxRepository(repository.AllTenants(suite.Tx)).GetPending(time.Now(), 100)
+	a.Nil(err)
+	found := false
+	for _, e := range events {
+		found = found || e.ID == event.ID
+	}
+	a.True(found)
+}
+
+func (suite *TenantRepoIntegrationTestSuite) TestReportRepository_AcrossTenants_ExpectOwnRentsOnly() {
+	a := assert.New(suite.T())
+	query := domain.ReportQuery{
+		From:    time.Now().Add(-time.Hour),
+		To:      time.Now().Add(time.Hour),
+		GroupBy: domain.GroupByDay,
+		Limit:   10}
+
+	counts, err := repository.NewGormReportRepository(suite.Acme).MostRentedBooks(query)
+	a.Nil(err)
+	a.Equal(1, len(counts))
+	a.Equal(int(suite.book.ID), counts[0].BookID)
+
+	rates, err := repository.NewGormReportRepository(suite.Tx).OverdueRates(query)
+	a.Nil(err)
+	a.Equal(0, len(rates))
+}
@@ -0,0 +1,242 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/idj1997/book-rent-core/config"
+	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/service"
+	"github.com/idj1997/book-rent-core/test/repo_mocks"
+	"github.com/idj1997/book-rent-core/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// remoteTraceparent is traceparent header of sampled remote caller
+const remoteTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordSpans sets global provider recording ended spans until returned reset is called
+func recordSpans() (*tracetest.SpanRecorder, func()) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder, func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	}
+}
+
+// spansByName returns spans ended in recorder by name
+func spansByName(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+type TracingUnitTestSuite struct {
+	suite.Suite
+	Recorder *tracetest.SpanRecorder
+	reset    func()
+}
+
+func TestTracingUnitTestSuite(t *testing.T) {
+	suite.Run(t, &TracingUnitTestSuite{})
+}
+
+func (suite *TracingUnitTestSuite) SetupTest() {
+	suite.Recorder, suite.reset = recordSpans()
+}
+
+func (suite *TracingUnitTestSuite) TearDownTest() {
+	suite.reset()
+}
+
+func (suite *TracingUnitTestSuite) spans() map[string]sdktrace.ReadOnlySpan {
+	return spansByName(suite.Recorder)
+}
+
+func (suite *TracingUnitTestSuite) TestServiceCall_Concurrent_ExpectRepositorySpansUnderOwnServiceSpan() {
+	a := assert.New(suite.T())
+	ctx, root := tracing.Start(context.Background(), "request")
+	// both calls are in repository at once, like parallel resolvers of one request
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	repo := &repo_mocks.MockedUserRepository{}
+	repo.
+		On("GetByID", mock.Anything).
+		Run(func(args mock.Arguments) {
+			arrived.Done()
+			arrived.Wait()
+		}).
+		Return(&domain.User{}, domain.NilRepoErrPtr)
+	users := (&service.UserService{Repo: tracing.NewUserRepository(ctx, repo)}).WithContext(ctx)
+
+	var done sync.WaitGroup
+	for id := 1; id <= 2; id++ {
+		done.Add(1)
+		go func(id int) {
+			defer done.Done()
+			_, _ = users.GetByID(id)
+		}(id)
+	}
+	done.Wait()
+	root.End()
+
+	serviceSpans := make(map[trace.SpanID]bool)
+	var repoParents []trace.SpanID
+	for _, span := range suite.Recorder.Ended() {
+		switch span.Name() {
+		case "UserService.GetByID":
+			serviceSpans[span.SpanContext().SpanID()] = true
+			a.Equal(root.SpanContext().SpanID(), span.Parent().SpanID())
+		case "UserRepository.GetByID":
+			repoParents = append(repoParents, span.Parent().SpanID())
+		}
+	}
+	suite.Require().Len(repoParents, 2)
+	a.NotEqual(repoParents[0], repoParents[1])
+	for _, parent := range repoParents {
+		a.True(serviceSpans[parent])
+	}
+}
+
+func (suite *TracingUnitTestSuite) TestServiceCall_ExpectRepositorySpanChildOfServiceSpan() {
+	a := assert.New(suite.T())
+	ctx, root := tracing.Start(context.Background(), "request")
+	repo := &repo_mocks.MockedUserRepository{}
+	repo.On("GetByID", 1).Return(&domain.User{}, domain.NilRepoErrPtr)
+	users := (&service.UserService{Repo: tracing.NewUserRepository(ctx, repo)}).WithContext(ctx)
+
+	_, err := users.GetByID(1)
+	root.End()
+
+	a.Nil(err)
+	spans := suite.spans()
+	a.Equal(root.SpanContext().SpanID(), spans["UserService.GetByID"].Parent().SpanID())
+	a.Equal(spans["UserService.GetByID"].SpanContext().SpanID(), spans["UserRepository.GetByID"].Parent().SpanID())
+	a.Equal(root.SpanContext().TraceID(), spans["UserRepository.GetByID"].SpanContext().TraceID())
+	a.Equal(codes.Unset, spans["UserRepository.GetByID"].Status().Code)
+}
+
+func (suite *TracingUnitTestSuite) TestServiceCall_WithNotFound_ExpectErrorRecorded() {
+	a := assert.New(suite.T())
+	ctx := context.Background()
+	repo := &repo_mocks.MockedUserRepository{}
+	repo.On("GetByID", 1).Return(domain.NilUserPtr, &domain.RepoError{Type: domain.NotFound})
+	users := (&service.UserService{Repo: tracing.NewUserRepository(ctx, repo)}).WithContext(ctx)
+
+	_, err := users.GetByID(1)
+
+	a.NotNil(err)
+	spans := suite.spans()
+	a.Equal(codes.Error, spans["UserService.GetByID"].Status().Code)
+	a.Equal(codes.Error, spans["UserRepository.GetByID"].Status().Code)
+	a.Equal("exception", spans["UserService.GetByID"].Events()[0].Name)
+	a.Contains(spans["UserService.GetByID"].Attributes(), attribute.String("outcome", "not_found"))
+}
+
+func (suite *TracingUnitTestSuite) TestTransactor_ExpectRepositorySpansChildrenOfTransaction() {
+	a := assert.New(suite.T())
+	ctx := context.Background()
+	repo := &repo_mocks.MockedUserRepository{}
+	repo.On("GetByID", 1).Return(&domain.User{}, domain.NilRepoErrPtr)
+	tx := tracing.NewTransactor(ctx, &repo_mocks.MockedTransactor{Repos: domain.Repositories{Users: repo}})
+
+	err := tx.Transaction(func(repos domain.Repositories) error {
+		_, err := repos.Users.GetByID(1)
+		return err
+	})
+
+	a.Equal(domain.NilRepoErrPtr, err)
+	spans := suite.spans()
+	a.Equal(spans["transaction"].SpanContext().SpanID(), spans["UserRepository.GetByID"].Parent().SpanID())
+	a.Equal(codes.Unset, spans["transaction"].Status().Code)
+}
+
+func (suite *TracingUnitTestSuite) TestStart_WithoutTracerProvider_ExpectSpanNotRecording() {
+	a := assert.New(suite.T())
+	suite.reset()
+
+	_, span := tracing.Start(context.Background(), "disabled")
+	span.SetAttributes(attribute.Int64("n", 1))
+	tracing.RecordError(span, &domain.RepoError{Type: domain.NotFound})
+	span.End()
+
+	a.False(span.IsRecording())
+	a.Empty(suite.Recorder.Ended())
+	provider, err := tracing.NewTracerProvider(config.TracingConfig{Exporter: "none"})
+	a.Nil(err)
+	a.Nil(provider)
+}
+
+func (suite *TracingUnitTestSuite) TestNewTracerProvider_WithOTLPExporter_ExpectSpansPostedToCollector() {
+	a := assert.New(suite.T())
+	requests := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer collector.Close()
+
+	provider, err := tracing.NewTracerProvider(config.TracingConfig{
+		Exporter:    "otlp",
+		Endpoint:    collector.URL + "/v1/traces",
+		ServiceName: "book-rent-test"})
+	suite.Require().Nil(err)
+	_, span := provider.Tracer("test").Start(context.Background(), "otlp")
+	span.End()
+	suite.Require().Nil(provider.ForceFlush(context.Background()))
+	suite.Require().Nil(provider.Shutdown(context.Background()))
+
+	request := <-requests
+	a.Equal(http.MethodPost, request.Method)
+	a.Equal("/v1/traces", request.URL.Path)
+	a.Equal("application/x-protobuf", request.Header.Get("Content-Type"))
+}
+
+func (suite *TracingUnitTestSuite) TestMiddleware_WithTraceparent_ExpectRequestSpanContinuesRemoteTrace() {
+	a := assert.New(suite.T())
+	handler := tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "handler")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	request := httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	request.Header.Set("traceparent", remoteTraceparent)
+
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := suite.spans()
+	server := spans["HTTP GET"]
+	suite.Require().NotNil(server)
+	a.Equal("4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	a.Equal("00f067aa0ba902b7", server.Parent().SpanID().String())
+	a.True(server.Parent().IsRemote())
+	a.Equal(trace.SpanKindServer, server.SpanKind())
+	a.Equal(codes.Error, server.Status().Code)
+	a.Contains(server.Attributes(), attribute.Int("http.status_code", http.StatusInternalServerError))
+	a.Equal(server.SpanContext().SpanID(), spans["handler"].Parent().SpanID())
+}
+
+func (suite *TracingUnitTestSuite) TestMiddleware_WithoutTraceparent_ExpectNewTrace() {
+	a := assert.New(suite.T())
+	handler := tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", nil))
+
+	server := suite.spans()["HTTP POST"]
+	suite.Require().NotNil(server)
+	a.False(server.Parent().IsValid())
+	a.Equal(codes.Unset, server.Status().Code)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/idj1997/book-rent-core/domain"
 	"github.com/idj1997/book-rent-core/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -22,8 +23,11 @@ func TestRunUserRepoIntegrationTestSuite(t *testing.T) {
 }
 
 func (suite *UserRepoIntegrationTestSuite) SetupSuite() {
-	config.InitConfig("test", "../config.yml")
-	suite.Db = config.OpenPostgresDB()
+	cfg, err := config.Load("test", "../config.yml")
+	suite.Require().Nil(err)
+	db, err := config.OpenPostgresDB(cfg.Database)
+	suite.Require().Nil(err)
+	suite.Db = db
 }
 
 func (suite *UserRepoIntegrationTestSuite) SetupTest() {
@@ -40,7 +44,7 @@ func (suite *UserRepoIntegrationTestSuite) TearDownTest() {
 }
 
 func (suite *UserRepoIntegrationTestSuite) TearDownSuite() {
-	config.ClosePostgresDB(suite.Db)
+	suite.Nil(config.ClosePostgresDB(suite.Db))
 }
 
 func (suite *UserRepoIntegrationTestSuite) TestGetByID_WithInvalidID_ExpectNotFound() {
@@ -117,6 +121,59 @@ func (suite *UserRepoIntegrationTestSuite) TestGetByFirstnameAndLastname_WithExa
 	a.Nil(err)
 }
 
+func (suite *UserRepoIntegrationTestSuite) TestGetByFirstnameAndLastname_WithSharedLastname_ExpectOnlyExact() {
+	a := assert.New(suite.T())
+
+	users, err := suite.Repo.GetByFirstnameAndLastname("john", "doe")
+	a.Nil(err)
+	a.Equal(1, len(users))
+	a.Equal(uint(10000), users[0].ID)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestGetByFirstnameAndLastname_WithoutAccentsAndCase_ExpectFound() {
+	a := assert.New(suite.T())
+
+	users, err := suite.Repo.GetByFirstnameAndLastname("ZOE", "doe")
+	a.Nil(err)
+	a.Equal(1, len(users))
+	a.Equal(uint(10002), users[0].ID)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestGetByFirstnameAndLastname_WithTypo_ExpectFound() {
+	a := assert.New(suite.T())
+
+	users, err := suite.Repo.GetByFirstnameAndLastname("johnn", "doe")
+	a.Nil(err)
+	a.NotEmpty(users)
+	a.Equal(uint(10000), users[0].ID)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestSearch_WithFullName_ExpectBestMatchFirst() {
+	a := assert.New(suite.T())
+
+	users, err := suite.Repo.Search("john doe")
+	a.Nil(err)
+	a.NotEmpty(users)
+	a.Equal(uint(10000), users[0].ID)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestSearch_WithEmail_ExpectFound() {
+	a := assert.New(suite.T())
+
+	users, err := suite.Repo.Search("markparker@")
+	a.Nil(err)
+	a.NotEmpty(users)
+	a.Equal(uint(10001), users[0].ID)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestSearch_WithInvalidQuery_ExpectEmpty() {
+	a := assert.New(suite.T())
+
+	users, err := suite.Repo.Search("qwxzvk")
+	a.Nil(err)
+	a.Empty(users)
+}
+
 func (suite *UserRepoIntegrationTestSuite) TestCreate_WithValidObject_ExpectOK() {
 	a := assert.New(suite.T())
 	user := domain.User{
@@ -230,3 +287,78 @@ func (suite *UserRepoIntegrationTestSuite) TestDelete_WithValidID_ExpectDeleted(
 	a.NotNil(err)
 	a.Equal(domain.NotFound, err.(*domain.RepoError).Type)
 }
+
+func (suite *UserRepoIntegrationTestSuite) TestGetDeleted_WithDeletedUser_ExpectListed() {
+	a := assert.New(suite.T())
+	const ID int = 10001
+
+	a.Nil(suite.Repo.Delete(ID))
+
+	users, err := suite.Repo.GetDeleted()
+	a.Nil(err)
+	a.Equal(1, len(users))
+	a.Equal(uint(ID), users[0].ID)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestRestore_WithDeletedUser_ExpectRestored() {
+	a := assert.New(suite.T())
+	const ID int = 10001
+
+	a.Nil(suite.Repo.Delete(ID))
+
+	err := suite.Repo.Restore(ID)
+	a.Nil(err)
+
+	user, err := suite.Repo.GetByID(ID)
+	a.Nil(err)
+	a.Equal(uint(ID), user.ID)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestRestore_WithReusedEmail_ExpectUniqueConstraint() {
+	a := assert.New(suite.T())
+	const ID int = 10001
+	deleted, _ := suite.Repo.GetByID(ID)
+	a.Nil(suite.Repo.Delete(ID))
+
+	// email of deleted user is free for new users
+	user := domain.User{
+		Firstname: "new",
+		Lastname:  "owner",
+		Email:     deleted.Email,
+		Password:  "$2y$12$Z51tvYyB2xEUejQydGcaiuCs1i3xqgHMvHwlzVLLQCk/7KVzahP9W",
+		Type:      domain.CUSTOMER}
+	a.Nil(suite.Repo.Create(&user))
+
+	err := suite.Repo.Restore(ID)
+	a.Error(err)
+	a.Equal(domain.UniqueConstraint, err.(*domain.RepoError).Type)
+}
+
+func (suite *UserRepoIntegrationTestSuite) TestPurge_WithDeletedUsers_ExpectRentHistoryAnonymised() {
+	a := assert.New(suite.T())
+	user := domain.User{
+		Firstname: "purged",
+		Lastname:  "purged",
+		Email:     "purged@test.com",
+		Password:  "$2y$12$Z51tvYyB2xEUejQydGcaiuCs1i3xqgHMvHwlzVLLQCk/7KVzahP9W",
+		Type:      domain.CUSTOMER}
+	a.Nil(suite.Repo.Create(&user))
+
+	a.Nil(suite.Repo.Delete(int(user.ID)))
+	a.Nil(suite.Repo.Delete(10000)) // has rent history
+
+	purged, err := suite.Repo.Purge(time.Now().Add(time.Hour))
+	a.Nil(err)
+	a.Equal([]uint{user.ID, 10000}, purged)
+
+	users, _ := suite.Repo.GetDeleted()
+	a.Equal(1, len(users))
+	a.Equal(uint(10000), users[0].ID)
+	a.Empty(users[0].Firstname)
+	a.Empty(users[0].Email)
+	a.Empty(users[0].Password)
+
+	purged, err = suite.Repo.Purge(time.Now().Add(time.Hour))
+	a.Nil(err)
+	a.Empty(purged)
+}
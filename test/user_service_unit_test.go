@@ -1,21 +1,32 @@
 package test
 
 import (
+	"context"
 	"github.com/idj1997/book-rent-core/domain"
+	"github.com/idj1997/book-rent-core/logging"
 	"github.com/idj1997/book-rent-core/service"
 	"github.com/idj1997/book-rent-core/test/repo_mocks"
 	"testing"
 	"time"
 
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserServiceUnitTestSuite struct {
 	suite.Suite
-	service *service.UserService
-	repo    *repo_mocks.MockedUserRepository
+	service  *service.UserService
+	repo     *repo_mocks.MockedUserRepository
+	rentRepo *repo_mocks.MockedRentDetailsRepository
+	bookRepo  *repo_mocks.MockedBookRepository
+	auditRepo  *repo_mocks.MockedAuditRepository
+	outboxRepo *repo_mocks.MockedOutboxRepository
+	movementRepo *repo_mocks.MockedStockMovementRepository
 }
 
 func TestUserServiceUnitTestSuite(t *testing.T) {
@@ -24,7 +35,26 @@ func TestUserServiceUnitTestSuite(t *testing.T) {
 
 func (suite *UserServiceUnitTestSuite) SetupTest() {
 	suite.repo = &repo_mocks.MockedUserRepository{}
-	suite.service = &service.UserService{Repo: suite.repo}
+	suite.rentRepo = &repo_mocks.MockedRentDetailsRepository{}
+	suite.bookRepo = &repo_mocks.MockedBookRepository{}
+	suite.auditRepo = &repo_mocks.MockedAuditRepository{}
+	suite.auditRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.outboxRepo = &repo_mocks.MockedOutboxRepository{}
+	suite.outboxRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	suite.movementRepo = &repo_mocks.MockedStockMovementRepository{}
+	suite.movementRepo.On("Create", mock.Anything).Return(domain.NilRepoErrPtr)
+	branchRepo := repo_mocks.NewMockedBranchRepository()
+	branchRepo.On("GetStock", mock.Anything, mock.Anything).Return(&domain.BranchStock{Stock: 100}, domain.NilRepoErrPtr)
+	suite.service = &service.UserService{
+		Repo: suite.repo,
+		Tx: &repo_mocks.MockedTransactor{Repos: domain.Repositories{
+			Users:     suite.repo,
+			Rents:     suite.rentRepo,
+			Books:     suite.bookRepo,
+			Audit:     suite.auditRepo,
+			Outbox:    suite.outboxRepo,
+			Movements: suite.movementRepo,
+			Branches:  branchRepo}}}
 }
 
 func (suite *UserServiceUnitTestSuite) TestGetByID_WithInvalidID_ExpectNotFound() {
@@ -94,6 +124,49 @@ func (suite *UserServiceUnitTestSuite) TestGetByEmail_WithValidEmail_ExpectFound
 	a.Equal(user.Email, returnedUser.Firstname)
 }
 
+func (suite *UserServiceUnitTestSuite) TestGetByFirstnameAndLastname_WithEmptyArgs_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	_, err := suite.service.GetByFirstnameAndLastname(" ", "")
+	a.Error(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+	suite.repo.AssertNotCalled(suite.T(), "GetByFirstnameAndLastname", " ", "")
+}
+
+func (suite *UserServiceUnitTestSuite) TestGetByFirstnameAndLastname_WithValidArgs_ExpectFound() {
+	a := assert.New(suite.T())
+	users := []domain.User{{Firstname: "john", Lastname: "doe"}}
+
+	suite.repo.
+		On("GetByFirstnameAndLastname", "john", "doe").
+		Return(users, domain.NilRepoErrPtr)
+
+	returnedUsers, err := suite.service.GetByFirstnameAndLastname("john", "doe")
+	a.Nil(err)
+	a.Equal(users, returnedUsers)
+}
+
+func (suite *UserServiceUnitTestSuite) TestSearch_WithEmptyQuery_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+
+	_, err := suite.service.Search("  ")
+	a.Error(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+}
+
+func (suite *UserServiceUnitTestSuite) TestSearch_WithValidQuery_ExpectFound() {
+	a := assert.New(suite.T())
+	users := []domain.User{{Firstname: "john", Lastname: "doe", Email: "johndoe@gmail.com"}}
+
+	suite.repo.
+		On("Search", "john").
+		Return(users, domain.NilRepoErrPtr)
+
+	returnedUsers, err := suite.service.Search("john")
+	a.Nil(err)
+	a.Equal(users, returnedUsers)
+}
+
 func (suite *UserServiceUnitTestSuite) TestCreate_WithUnavailableEmail_ExpectAlreadyExist() {
 	a := assert.New(suite.T())
 	user := domain.User{
@@ -151,6 +224,35 @@ func (suite *UserServiceUnitTestSuite) TestCreate_WithValidUserObj_ExpectCreated
 
 	err := suite.service.Create(&user)
 	a.Nil(err)
+	a.NotEqual("test", user.Password)
+	a.Nil(bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("test")))
+}
+
+func (suite *UserServiceUnitTestSuite) TestCreate_WithEmptyPassword_ExpectInvalidArguments() {
+	a := assert.New(suite.T())
+	user := domain.User{Firstname: "test", Lastname: "test", Email: "available@gmail.com"}
+
+	err := suite.service.Create(&user)
+	a.Error(err)
+	a.Equal(service.InvalidArguments, err.(*service.ServiceError).Type)
+	suite.repo.AssertNotCalled(suite.T(), "Create", mock.Anything)
+}
+
+func (suite *UserServiceUnitTestSuite) TestCreate_WithRequestLogger_ExpectScopedFieldsLogged() {
+	a := assert.New(suite.T())
+	user := domain.User{Model: gorm.Model{ID: 42}, Email: "logged@gmail.com", Password: "test"}
+	suite.repo.
+		On("Create", &user).
+		Return(domain.NilRepoErrPtr)
+	logger, hook := logtest.NewNullLogger()
+	ctx := logging.WithRequestID(logging.NewContext(context.Background(), log.NewEntry(logger)), "req-1")
+
+	err := suite.service.WithLogger(logging.FromContext(ctx)).Create(&user)
+	a.Nil(err)
+	entry := hook.LastEntry()
+	suite.Require().NotNil(entry)
+	a.Equal("req-1", entry.Data[logging.RequestIDField])
+	a.Equal(uint(42), entry.Data[logging.UserIDField])
 }
 
 func (suite *UserServiceUnitTestSuite) TestDelete_WithInvalidID_ExpectNotFound() {
@@ -188,6 +290,10 @@ func (suite *UserServiceUnitTestSuite) TestDelete_WithValidID_ExpectDeleted() {
 		On("GetByID", id).
 		Return(userPtr, domain.NilRepoErrPtr)
 
+	suite.rentRepo.
+		On("GetByUser", id).
+		Return([]domain.RentDetails{}, domain.NilRepoErrPtr)
+
 	suite.repo.
 		On("Delete", id).
 		Return(domain.NilRepoErrPtr)
@@ -195,3 +301,122 @@ func (suite *UserServiceUnitTestSuite) TestDelete_WithValidID_ExpectDeleted() {
 	serviceErr := suite.service.Delete(id)
 	a.Nil(serviceErr)
 }
+
+func (suite *UserServiceUnitTestSuite) TestDelete_WithRentedBooks_ExpectActiveBookRents() {
+	a := assert.New(suite.T())
+	id := 10000
+
+	suite.repo.
+		On("GetByID", id).
+		Return(&domain.User{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByUser", id).
+		Return([]domain.RentDetails{{Status: domain.RENTED}}, domain.NilRepoErrPtr)
+
+	serviceErr := suite.service.Delete(id)
+	a.Error(serviceErr)
+	a.Equal(service.ActiveBookRents, serviceErr.(*service.ServiceError).Type)
+	suite.repo.AssertNotCalled(suite.T(), "Delete", id)
+}
+
+func (suite *UserServiceUnitTestSuite) TestForceDelete_WithRentedBooksAsReturned_ExpectRestocked() {
+	a := assert.New(suite.T())
+	id := 10000
+	book := domain.Book{Title: "test", Content: "test", Stock: 4}
+	book.ID = 10001
+	rents := []domain.RentDetails{{UserID: id, BookID: 10001, Status: domain.RENTED}}
+
+	suite.repo.
+		On("GetByID", id).
+		Return(&domain.User{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByUser", id).
+		Return(rents, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("Update", mock.Anything, mock.Anything).
+		Return(domain.NilRepoErrPtr)
+
+	suite.bookRepo.
+		On("GetByID", 10001).
+		Return(&book, domain.NilRepoErrPtr)
+
+	suite.bookRepo.
+		On("GetByIDForUpdate", 10001).
+		Return(&book, domain.NilRepoErrPtr)
+
+	suite.bookRepo.
+		On("Update", &book, map[string]interface{}{"stock": 5}).
+		Return(domain.NilRepoErrPtr)
+
+	suite.repo.
+		On("Delete", id).
+		Return(domain.NilRepoErrPtr)
+
+	serviceErr := suite.service.ForceDelete(id, domain.RETURNED)
+	a.Nil(serviceErr)
+	suite.bookRepo.AssertExpectations(suite.T())
+	suite.repo.AssertCalled(suite.T(), "Delete", id)
+}
+
+func (suite *UserServiceUnitTestSuite) TestForceDelete_WithFailedRentUpdate_ExpectNotDeleted() {
+	a := assert.New(suite.T())
+	id := 10000
+
+	suite.repo.
+		On("GetByID", id).
+		Return(&domain.User{}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("GetByUser", id).
+		Return([]domain.RentDetails{{Status: domain.EXPIRED}}, domain.NilRepoErrPtr)
+
+	suite.rentRepo.
+		On("Update", mock.Anything, mock.Anything).
+		Return(&domain.RepoError{Type: domain.Unknown})
+
+	serviceErr := suite.service.ForceDelete(id, domain.LOST)
+	a.Error(serviceErr)
+	a.Equal(service.Unknown, serviceErr.(*service.ServiceError).Type)
+	suite.repo.AssertNotCalled(suite.T(), "Delete", id)
+}
+
+func (suite *UserServiceUnitTestSuite) TestRestore_WithTakenEmail_ExpectAlreadyExist() {
+	a := assert.New(suite.T())
+	id := 10001
+
+	suite.repo.
+		On("Restore", id).
+		Return(&domain.RepoError{Type: domain.UniqueConstraint})
+
+	err := suite.service.Restore(id)
+	a.Error(err)
+	a.Equal(service.AlreadyExist, err.(*service.ServiceError).Type)
+}
+
+func (suite *UserServiceUnitTestSuite) TestGetDeleted_ExpectMany() {
+	a := assert.New(suite.T())
+	users := make([]domain.User, 2)
+
+	suite.repo.
+		On("GetDeleted").
+		Return(users, domain.NilRepoErrPtr)
+
+	returnedUsers, err := suite.service.GetDeleted()
+	a.Nil(err)
+	a.Equal(2, len(returnedUsers))
+}
+
+func (suite *UserServiceUnitTestSuite) TestPurgeDeleted_WithRetention_ExpectPurged() {
+	a := assert.New(suite.T())
+
+	suite.repo.
+		On("Purge", mock.AnythingOfType("time.Time")).
+		Return([]uint{10000}, domain.NilRepoErrPtr)
+
+	purged, err := suite.service.PurgeDeleted(time.Hour)
+	a.Nil(err)
+	a.Equal(1, purged)
+}
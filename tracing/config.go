@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/idj1997/book-rent-core/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// NewTracerProvider creates provider batching spans to configured exporter, nil when exporter is none
+func NewTracerProvider(cfg config.TracingConfig) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		exporter, err = newOTLPExporter(cfg.Endpoint)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while creating %s span exporter: %w", cfg.Exporter, err)
+	}
+
+	var batchOptions []sdktrace.BatchSpanProcessorOption
+	if cfg.BatchSize > 0 {
+		batchOptions = append(batchOptions, sdktrace.WithMaxExportBatchSize(cfg.BatchSize))
+	}
+	if cfg.FlushInterval > 0 {
+		batchOptions = append(batchOptions, sdktrace.WithBatchTimeout(cfg.FlushInterval))
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, batchOptions...),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(cfg.ServiceName)))), nil
+}
+
+// newOTLPExporter creates exporter posting spans to traces url of collector
+func newOTLPExporter(endpoint string) (*otlptrace.Exporter, error) {
+	collector, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(collector.Host)}
+	if collector.Path != "" {
+		options = append(options, otlptracehttp.WithURLPath(collector.Path))
+	}
+	if collector.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// SQLPluginName is the name SQLEvents is registered under in gorm plugins
+const SQLPluginName = "book-rent:tracing"
+
+// SQLEvents is gorm plugin adding every executed statement as "sql" event to span carried
+// by statement context, so queries of db.WithContext(ctx) show up in trace of ctx
+type SQLEvents struct{}
+
+func (SQLEvents) Name() string {
+	return SQLPluginName
+}
+
+type registerFunc func(name string, fn func(*gorm.DB)) error
+
+func (SQLEvents) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	operations := []struct {
+		name  string
+		after registerFunc
+	}{
+		{"create", callback.Create().After("gorm:create").Register},
+		{"query", callback.Query().After("gorm:query").Register},
+		{"update", callback.Update().After("gorm:update").Register},
+		{"delete", callback.Delete().After("gorm:delete").Register},
+		{"row", callback.Row().After("gorm:row").Register},
+		{"raw", callback.Raw().After("gorm:raw").Register}}
+
+	for _, operation := range operations {
+		err := operation.after("tracing:sql_"+operation.name, addSQLEvent(operation.name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addSQLEvent(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		span := trace.SpanFromContext(db.Statement.Context)
+		if !span.IsRecording() {
+			return
+		}
+
+		attributes := []attribute.KeyValue{
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected)}
+		if db.Statement.Table != "" {
+			attributes = append(attributes, attribute.String("db.table", db.Statement.Table))
+		}
+		if db.Error != nil {
+			attributes = append(attributes, attribute.String("error", db.Error.Error()))
+		}
+		span.AddEvent("sql", trace.WithAttributes(attributes...))
+	}
+}
@@ -0,0 +1,633 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/idj1997/book-rent-core/domain"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// repository starts spans of decorated repository methods under span carried by ctx
+type repository struct {
+	ctx  context.Context
+	name string
+}
+
+func (r repository) start(method string) trace.Span {
+	_, span := Start(r.ctx, r.name+"."+method)
+	return span
+}
+
+// finish ends span of repository call, deferred with pointer to its error result
+func finish(span trace.Span, err *error) {
+	if *err != nil && *err != error(domain.NilRepoErrPtr) {
+		RecordError(span, *err)
+	}
+	span.End()
+}
+
+type BookRepository struct {
+	repository
+	inner domain.BookRepository
+}
+
+func NewBookRepository(ctx context.Context, inner domain.BookRepository) *BookRepository {
+	return &BookRepository{repository: repository{ctx: ctx, name: "BookRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *BookRepository) WithContext(ctx context.Context) domain.BookRepository {
+	return NewBookRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *BookRepository) under(span trace.Span) domain.BookRepository {
+	return domain.BookRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *BookRepository) GetByID(id int) (_ *domain.Book, err error) {
+	span := r.start("GetByID")
+	defer finish(span, &err)
+	return r.under(span).GetByID(id)
+}
+
+func (r *BookRepository) GetByIDForUpdate(id int) (_ *domain.Book, err error) {
+	span := r.start("GetByIDForUpdate")
+	defer finish(span, &err)
+	return r.under(span).GetByIDForUpdate(id)
+}
+
+func (r *BookRepository) GetByIDs(ids []int) (_ []domain.Book, err error) {
+	span := r.start("GetByIDs")
+	defer finish(span, &err)
+	return r.under(span).GetByIDs(ids)
+}
+
+func (r *BookRepository) GetByTitle(title string) (_ []domain.Book, err error) {
+	span := r.start("GetByTitle")
+	defer finish(span, &err)
+	return r.under(span).GetByTitle(title)
+}
+
+func (r *BookRepository) GetAll() (_ []domain.Book, err error) {
+	span := r.start("GetAll")
+	defer finish(span, &err)
+	return r.under(span).GetAll()
+}
+
+func (r *BookRepository) GetByNaturalKey(isbn string, title string) (_ *domain.Book, err error) {
+	span := r.start("GetByNaturalKey")
+	defer finish(span, &err)
+	return r.under(span).GetByNaturalKey(isbn, title)
+}
+
+func (r *BookRepository) Create(book *domain.Book) (_ uint, err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(book)
+}
+
+func (r *BookRepository) Update(book *domain.Book, updates map[string]interface{}) (err error) {
+	span := r.start("Update")
+	defer finish(span, &err)
+	return r.under(span).Update(book, updates)
+}
+
+func (r *BookRepository) Delete(id int) (err error) {
+	span := r.start("Delete")
+	defer finish(span, &err)
+	return r.under(span).Delete(id)
+}
+
+func (r *BookRepository) GetDeleted() (_ []domain.Book, err error) {
+	span := r.start("GetDeleted")
+	defer finish(span, &err)
+	return r.under(span).GetDeleted()
+}
+
+func (r *BookRepository) Restore(id int) (err error) {
+	span := r.start("Restore")
+	defer finish(span, &err)
+	return r.under(span).Restore(id)
+}
+
+func (r *BookRepository) Purge(deletedBefore time.Time) (_ []uint, err error) {
+	span := r.start("Purge")
+	defer finish(span, &err)
+	return r.under(span).Purge(deletedBefore)
+}
+
+type UserRepository struct {
+	repository
+	inner domain.UserRepository
+}
+
+func NewUserRepository(ctx context.Context, inner domain.UserRepository) *UserRepository {
+	return &UserRepository{repository: repository{ctx: ctx, name: "UserRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *UserRepository) WithContext(ctx context.Context) domain.UserRepository {
+	return NewUserRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *UserRepository) under(span trace.Span) domain.UserRepository {
+	return domain.UserRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *UserRepository) GetByID(id int) (_ *domain.User, err error) {
+	span := r.start("GetByID")
+	defer finish(span, &err)
+	return r.under(span).GetByID(id)
+}
+
+func (r *UserRepository) GetByIDs(ids []int) (_ []domain.User, err error) {
+	span := r.start("GetByIDs")
+	defer finish(span, &err)
+	return r.under(span).GetByIDs(ids)
+}
+
+func (r *UserRepository) GetByEmail(email string) (_ *domain.User, err error) {
+	span := r.start("GetByEmail")
+	defer finish(span, &err)
+	return r.under(span).GetByEmail(email)
+}
+
+func (r *UserRepository) GetByFirstnameAndLastname(firstname string, lastname string) (_ []domain.User, err error) {
+	span := r.start("GetByFirstnameAndLastname")
+	defer finish(span, &err)
+	return r.under(span).GetByFirstnameAndLastname(firstname, lastname)
+}
+
+func (r *UserRepository) Search(query string) (_ []domain.User, err error) {
+	span := r.start("Search")
+	defer finish(span, &err)
+	return r.under(span).Search(query)
+}
+
+func (r *UserRepository) Create(user *domain.User) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(user)
+}
+
+func (r *UserRepository) Update(user *domain.User, updates map[string]interface{}) (err error) {
+	span := r.start("Update")
+	defer finish(span, &err)
+	return r.under(span).Update(user, updates)
+}
+
+func (r *UserRepository) Delete(id int) (err error) {
+	span := r.start("Delete")
+	defer finish(span, &err)
+	return r.under(span).Delete(id)
+}
+
+func (r *UserRepository) GetDeleted() (_ []domain.User, err error) {
+	span := r.start("GetDeleted")
+	defer finish(span, &err)
+	return r.under(span).GetDeleted()
+}
+
+func (r *UserRepository) Restore(id int) (err error) {
+	span := r.start("Restore")
+	defer finish(span, &err)
+	return r.under(span).Restore(id)
+}
+
+func (r *UserRepository) Purge(deletedBefore time.Time) (_ []uint, err error) {
+	span := r.start("Purge")
+	defer finish(span, &err)
+	return r.under(span).Purge(deletedBefore)
+}
+
+type RentDetailsRepository struct {
+	repository
+	inner domain.RentDetailsRepository
+}
+
+func NewRentDetailsRepository(ctx context.Context, inner domain.RentDetailsRepository) *RentDetailsRepository {
+	return &RentDetailsRepository{repository: repository{ctx: ctx, name: "RentDetailsRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *RentDetailsRepository) WithContext(ctx context.Context) domain.RentDetailsRepository {
+	return NewRentDetailsRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *RentDetailsRepository) under(span trace.Span) domain.RentDetailsRepository {
+	return domain.RentDetailsRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *RentDetailsRepository) GetByID(id int) (_ *domain.RentDetails, err error) {
+	span := r.start("GetByID")
+	defer finish(span, &err)
+	return r.under(span).GetByID(id)
+}
+
+func (r *RentDetailsRepository) Create(rent *domain.RentDetails) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(rent)
+}
+
+func (r *RentDetailsRepository) Update(rent *domain.RentDetails, updates map[string]interface{}) (err error) {
+	span := r.start("Update")
+	defer finish(span, &err)
+	return r.under(span).Update(rent, updates)
+}
+
+func (r *RentDetailsRepository) UpdateAssociations(rent *domain.RentDetails, updates map[string]interface{}) (err error) {
+	span := r.start("UpdateAssociations")
+	defer finish(span, &err)
+	return r.under(span).UpdateAssociations(rent, updates)
+}
+
+func (r *RentDetailsRepository) GetByUser(userID int) (_ []domain.RentDetails, err error) {
+	span := r.start("GetByUser")
+	defer finish(span, &err)
+	return r.under(span).GetByUser(userID)
+}
+
+func (r *RentDetailsRepository) GetByBook(bookID int) (_ []domain.RentDetails, err error) {
+	span := r.start("GetByBook")
+	defer finish(span, &err)
+	return r.under(span).GetByBook(bookID)
+}
+
+func (r *RentDetailsRepository) GetByStatus(status domain.RentDetailsStatus) (_ []domain.RentDetails, err error) {
+	span := r.start("GetByStatus")
+	defer finish(span, &err)
+	return r.under(span).GetByStatus(status)
+}
+
+func (r *RentDetailsRepository) GetByStatusAndDeadline(status domain.RentDetailsStatus, from time.Time, to time.Time) (_ []domain.RentDetails, err error) {
+	span := r.start("GetByStatusAndDeadline")
+	defer finish(span, &err)
+	return r.under(span).GetByStatusAndDeadline(status, from, to)
+}
+
+func (r *RentDetailsRepository) RentDetailsIterator(ctx context.Context, rents chan<- domain.RentDetails) (err error) {
+	span := r.start("RentDetailsIterator")
+	defer finish(span, &err)
+	return r.under(span).RentDetailsIterator(ctx, rents)
+}
+
+type AuditRepository struct {
+	repository
+	inner domain.AuditRepository
+}
+
+func NewAuditRepository(ctx context.Context, inner domain.AuditRepository) *AuditRepository {
+	return &AuditRepository{repository: repository{ctx: ctx, name: "AuditRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *AuditRepository) WithContext(ctx context.Context) domain.AuditRepository {
+	return NewAuditRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *AuditRepository) under(span trace.Span) domain.AuditRepository {
+	return domain.AuditRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *AuditRepository) Create(record *domain.AuditRecord) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(record)
+}
+
+func (r *AuditRepository) Find(filter domain.AuditFilter) (_ []domain.AuditRecord, err error) {
+	span := r.start("Find")
+	defer finish(span, &err)
+	return r.under(span).Find(filter)
+}
+
+type OutboxRepository struct {
+	repository
+	inner domain.OutboxRepository
+}
+
+func NewOutboxRepository(ctx context.Context, inner domain.OutboxRepository) *OutboxRepository {
+	return &OutboxRepository{repository: repository{ctx: ctx, name: "OutboxRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *OutboxRepository) WithContext(ctx context.Context) domain.OutboxRepository {
+	return NewOutboxRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *OutboxRepository) under(span trace.Span) domain.OutboxRepository {
+	return domain.OutboxRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *OutboxRepository) Create(event *domain.OutboxEvent) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(event)
+}
+
+func (r *OutboxRepository) GetPending(now time.Time, limit int) (_ []domain.OutboxEvent, err error) {
+	span := r.start("GetPending")
+	defer finish(span, &err)
+	return r.under(span).GetPending(now, limit)
+}
+
+func (r *OutboxRepository) GetDeadLettered() (_ []domain.OutboxEvent, err error) {
+	span := r.start("GetDeadLettered")
+	defer finish(span, &err)
+	return r.under(span).GetDeadLettered()
+}
+
+func (r *OutboxRepository) MarkDispatched(id uint, at time.Time) (err error) {
+	span := r.start("MarkDispatched")
+	defer finish(span, &err)
+	return r.under(span).MarkDispatched(id, at)
+}
+
+func (r *OutboxRepository) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) (err error) {
+	span := r.start("MarkFailed")
+	defer finish(span, &err)
+	return r.under(span).MarkFailed(id, attempts, nextAttemptAt, lastError)
+}
+
+func (r *OutboxRepository) MarkDeadLettered(id uint, at time.Time, lastError string) (err error) {
+	span := r.start("MarkDeadLettered")
+	defer finish(span, &err)
+	return r.under(span).MarkDeadLettered(id, at, lastError)
+}
+
+func (r *OutboxRepository) Requeue(id uint) (err error) {
+	span := r.start("Requeue")
+	defer finish(span, &err)
+	return r.under(span).Requeue(id)
+}
+
+type StockMovementRepository struct {
+	repository
+	inner domain.StockMovementRepository
+}
+
+func NewStockMovementRepository(ctx context.Context, inner domain.StockMovementRepository) *StockMovementRepository {
+	return &StockMovementRepository{repository: repository{ctx: ctx, name: "StockMovementRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *StockMovementRepository) WithContext(ctx context.Context) domain.StockMovementRepository {
+	return NewStockMovementRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *StockMovementRepository) under(span trace.Span) domain.StockMovementRepository {
+	return domain.StockMovementRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *StockMovementRepository) Create(movement *domain.StockMovement) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(movement)
+}
+
+func (r *StockMovementRepository) GetByBook(bookID int) (_ []domain.StockMovement, err error) {
+	span := r.start("GetByBook")
+	defer finish(span, &err)
+	return r.under(span).GetByBook(bookID)
+}
+
+type BranchRepository struct {
+	repository
+	inner domain.BranchRepository
+}
+
+func NewBranchRepository(ctx context.Context, inner domain.BranchRepository) *BranchRepository {
+	return &BranchRepository{repository: repository{ctx: ctx, name: "BranchRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *BranchRepository) WithContext(ctx context.Context) domain.BranchRepository {
+	return NewBranchRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *BranchRepository) under(span trace.Span) domain.BranchRepository {
+	return domain.BranchRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *BranchRepository) GetByID(id int) (_ *domain.Branch, err error) {
+	span := r.start("GetByID")
+	defer finish(span, &err)
+	return r.under(span).GetByID(id)
+}
+
+func (r *BranchRepository) GetAll() (_ []domain.Branch, err error) {
+	span := r.start("GetAll")
+	defer finish(span, &err)
+	return r.under(span).GetAll()
+}
+
+func (r *BranchRepository) GetDefault() (_ *domain.Branch, err error) {
+	span := r.start("GetDefault")
+	defer finish(span, &err)
+	return r.under(span).GetDefault()
+}
+
+func (r *BranchRepository) Create(branch *domain.Branch) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(branch)
+}
+
+func (r *BranchRepository) GetStock(bookID int, branchID int) (_ *domain.BranchStock, err error) {
+	span := r.start("GetStock")
+	defer finish(span, &err)
+	return r.under(span).GetStock(bookID, branchID)
+}
+
+func (r *BranchRepository) SaveStock(stock *domain.BranchStock) (err error) {
+	span := r.start("SaveStock")
+	defer finish(span, &err)
+	return r.under(span).SaveStock(stock)
+}
+
+func (r *BranchRepository) GetAvailability(bookID int) (_ []domain.BranchStock, err error) {
+	span := r.start("GetAvailability")
+	defer finish(span, &err)
+	return r.under(span).GetAvailability(bookID)
+}
+
+func (r *BranchRepository) GetAvailableBooks(branchID int) (_ []domain.BranchStock, err error) {
+	span := r.start("GetAvailableBooks")
+	defer finish(span, &err)
+	return r.under(span).GetAvailableBooks(branchID)
+}
+
+type TransferRepository struct {
+	repository
+	inner domain.TransferRepository
+}
+
+func NewTransferRepository(ctx context.Context, inner domain.TransferRepository) *TransferRepository {
+	return &TransferRepository{repository: repository{ctx: ctx, name: "TransferRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *TransferRepository) WithContext(ctx context.Context) domain.TransferRepository {
+	return NewTransferRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *TransferRepository) under(span trace.Span) domain.TransferRepository {
+	return domain.TransferRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *TransferRepository) GetByID(id int) (_ *domain.Transfer, err error) {
+	span := r.start("GetByID")
+	defer finish(span, &err)
+	return r.under(span).GetByID(id)
+}
+
+func (r *TransferRepository) GetByIDForUpdate(id int) (_ *domain.Transfer, err error) {
+	span := r.start("GetByIDForUpdate")
+	defer finish(span, &err)
+	return r.under(span).GetByIDForUpdate(id)
+}
+
+func (r *TransferRepository) GetByBranch(branchID int) (_ []domain.Transfer, err error) {
+	span := r.start("GetByBranch")
+	defer finish(span, &err)
+	return r.under(span).GetByBranch(branchID)
+}
+
+func (r *TransferRepository) Create(transfer *domain.Transfer) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(transfer)
+}
+
+func (r *TransferRepository) Update(transfer *domain.Transfer, updates map[string]interface{}) (err error) {
+	span := r.start("Update")
+	defer finish(span, &err)
+	return r.under(span).Update(transfer, updates)
+}
+
+type ReminderRepository struct {
+	repository
+	inner domain.ReminderRepository
+}
+
+func NewReminderRepository(ctx context.Context, inner domain.ReminderRepository) *ReminderRepository {
+	return &ReminderRepository{repository: repository{ctx: ctx, name: "ReminderRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *ReminderRepository) WithContext(ctx context.Context) domain.ReminderRepository {
+	return NewReminderRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *ReminderRepository) under(span trace.Span) domain.ReminderRepository {
+	return domain.ReminderRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *ReminderRepository) Create(reminder *domain.SentReminder) (err error) {
+	span := r.start("Create")
+	defer finish(span, &err)
+	return r.under(span).Create(reminder)
+}
+
+func (r *ReminderRepository) Delete(id uint) (err error) {
+	span := r.start("Delete")
+	defer finish(span, &err)
+	return r.under(span).Delete(id)
+}
+
+func (r *ReminderRepository) GetByRent(rentID uint) (_ []domain.SentReminder, err error) {
+	span := r.start("GetByRent")
+	defer finish(span, &err)
+	return r.under(span).GetByRent(rentID)
+}
+
+type ReportRepository struct {
+	repository
+	inner domain.ReportRepository
+}
+
+func NewReportRepository(ctx context.Context, inner domain.ReportRepository) *ReportRepository {
+	return &ReportRepository{repository: repository{ctx: ctx, name: "ReportRepository"}, inner: inner}
+}
+
+// WithContext returns repository tracing calls under span of ctx
+func (r *ReportRepository) WithContext(ctx context.Context) domain.ReportRepository {
+	return NewReportRepository(ctx, r.inner)
+}
+
+// under returns inner repository bound to span of call
+func (r *ReportRepository) under(span trace.Span) domain.ReportRepository {
+	return domain.ReportRepositoryWithContext(trace.ContextWithSpan(r.ctx, span), r.inner)
+}
+
+func (r *ReportRepository) MostRentedBooks(query domain.ReportQuery) (_ []domain.BookRentCount, err error) {
+	span := r.start("MostRentedBooks")
+	defer finish(span, &err)
+	return r.under(span).MostRentedBooks(query)
+}
+
+func (r *ReportRepository) OverdueRates(query domain.ReportQuery) (_ []domain.OverdueRate, err error) {
+	span := r.start("OverdueRates")
+	defer finish(span, &err)
+	return r.under(span).OverdueRates(query)
+}
+
+func (r *ReportRepository) LoanDurations(query domain.ReportQuery) (_ []domain.LoanDuration, err error) {
+	span := r.start("LoanDurations")
+	defer finish(span, &err)
+	return r.under(span).LoanDurations(query)
+}
+
+func (r *ReportRepository) ActiveBorrowers(query domain.ReportQuery) (_ []domain.ActiveBorrowers, err error) {
+	span := r.start("ActiveBorrowers")
+	defer finish(span, &err)
+	return r.under(span).ActiveBorrowers(query)
+}
+
+// WrapRepositories returns repos tracing calls under span carried by ctx
+func WrapRepositories(ctx context.Context, repos domain.Repositories) domain.Repositories {
+	return domain.Repositories{
+		Books:     NewBookRepository(ctx, repos.Books),
+		Users:     NewUserRepository(ctx, repos.Users),
+		Rents:     NewRentDetailsRepository(ctx, repos.Rents),
+		Audit:     NewAuditRepository(ctx, repos.Audit),
+		Outbox:    NewOutboxRepository(ctx, repos.Outbox),
+		Movements: NewStockMovementRepository(ctx, repos.Movements),
+		Branches:  NewBranchRepository(ctx, repos.Branches),
+		Transfers: NewTransferRepository(ctx, repos.Transfers)}
+}
+
+// Transactor traces every transaction as span, repository calls inside it are its children
+type Transactor struct {
+	ctx   context.Context
+	inner domain.Transactor
+}
+
+func NewTransactor(ctx context.Context, inner domain.Transactor) *Transactor {
+	return &Transactor{ctx: ctx, inner: inner}
+}
+
+// WithContext returns transactor tracing transactions under span of ctx
+func (t *Transactor) WithContext(ctx context.Context) domain.Transactor {
+	return NewTransactor(ctx, t.inner)
+}
+
+func (t *Transactor) Transaction(fn func(repos domain.Repositories) error) (err error) {
+	ctx, span := Start(t.ctx, "transaction")
+	defer finish(span, &err)
+
+	return domain.TransactorWithContext(ctx, t.inner).Transaction(func(repos domain.Repositories) error {
+		return fn(WrapRepositories(ctx, repos))
+	})
+}
@@ -0,0 +1,34 @@
+// Package tracing records spans of service, repository and database calls with OpenTelemetry,
+// exports them to stdout or to OpenTelemetry collector over OTLP and continues traces of
+// requests carrying W3C traceparent header.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is scope name of spans recorded by this package
+const InstrumentationName = "github.com/idj1997/book-rent-core/tracing"
+
+// Start starts span named name with tracer of global provider, child of span carried by ctx or
+// root of new trace, returned context carries the span and must be passed to calls nested in it.
+// Span records nothing when no provider is set.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(InstrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError adds exception event of err to span and marks it failed
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}